@@ -3,83 +3,88 @@ package main
 import (
 	"fmt"
 	"log"
-	"path/filepath"
+	"strings"
 	"transifex"
 	"transifex/cli"
 	"transifex/config"
-	"transifex/format"
 )
 
 func main() {
 	transifexCLI := cli.NewCLI()
-	transifexApi := transifex.NewTransifexAPI(transifexCLI.ProjectSlug(), transifexCLI.Username(), transifexCLI.Password())
 	rootDir := transifexCLI.RootDir()
 
-	transifexApi.Debug = transifexCLI.Debug()
+	conf, err := transifexCLI.ReadConfig()
+	if err != nil {
+		fmt.Println(rootDir)
+		log.Fatalf("Error reading reading language files: \n\n%s", err)
+	}
+
+	client, err := transifex.NewHTTPClient(conf.HTTP.Merge(transifexCLI.HTTPSettings()))
+	if err != nil {
+		log.Fatalf("Error configuring the http client: %s", err)
+	}
+
+	summaries := []string{}
+	for _, project := range conf.Projects {
+		summaries = append(summaries, downloadProject(rootDir, project, transifexCLI.TransifexAPI(project, client)))
+	}
+
+	fmt.Printf("\nDownload Summary:\n  * %s\n", strings.Join(summaries, "\n  * "))
+}
 
+func downloadProject(rootDir string, project config.Project, transifexApi transifex.TransifexAPI) string {
 	var err error
 	if err = transifexApi.ValidateConfiguration(); err != nil {
-		log.Fatalf(err.Error())
+		log.Fatalf("%s: %s", project.Slug, err)
 	}
 
 	var sourceLang string
 	if sourceLang, err = transifexApi.SourceLanguage(); err != nil {
-		log.Fatalf("Error loading the transifext project data.")
-	}
-
-	files, readFilesErr := config.ReadConfig(transifexCLI.ConfigFile(), rootDir, sourceLang)
-	if readFilesErr != nil {
-		fmt.Println(rootDir)
-		log.Fatalf("Error reading reading language files: \n\n%s", readFilesErr)
+		log.Fatalf("Error loading the transifext project data of %s.", project.Slug)
 	}
 
 	existingResources := readExistingResources(transifexApi)
 
-	doneChan := make(chan bool)
+	doneChan := make(chan int)
 	goProcessNum := 0
-	for _, file := range files {
+	for _, file := range project.Files {
 		if _, has := existingResources[file.Slug]; has {
 			goProcessNum++
 			go downloadTranslations(rootDir, doneChan, sourceLang, file, transifexApi)
 		}
 	}
 
+	written := 0
 	for done := 0; done < goProcessNum; {
-		<-doneChan
+		written += <-doneChan
 
 		done++
 	}
+
+	return fmt.Sprintf("%s: %d translation files written for %d resources, %d resources not in transifex", project.Slug, written, goProcessNum, len(project.Files)-goProcessNum)
 }
 
 func readExistingResources(transifexApi transifex.TransifexAPI) map[string]bool {
-	resources, err := transifexApi.ListResources()
-	if err != nil {
-		log.Fatalf("Unable to load resources: %s", err)
-	}
 	existingResources := make(map[string]bool)
-	for _, res := range resources {
+	err := transifexApi.EachResource(func(res transifex.Resource) error {
 		existingResources[res.Slug] = true
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Unable to load resources: %s", err)
 	}
 	return existingResources
 }
 
-func downloadTranslations(rootDir string, doneChan chan bool, sourceLang string, file config.LocalizationFile, transifexApi transifex.TransifexAPI) {
+func downloadTranslations(rootDir string, doneChan chan int, sourceLang string, file config.LocalizationFile, transifexApi transifex.TransifexAPI) {
 	translations, err := transifexApi.DownloadTranslations(file.Slug)
 	if err != nil {
 		log.Fatalf("Failed to download translation files: %s", err)
 	}
-	i18Nformat, ok := format.Formats[file.I18nType]
-	if !ok {
-		log.Fatalf("No registered format %q", file.I18nType)
-	}
-	for _, path := range file.Translations {
-		dir := filepath.Join(rootDir, filepath.Dir(path))
-		for lang, translation := range translations {
-			if err = i18Nformat.Write(dir, lang, sourceLang, file.Fname, translation, file.FileLocator); err != nil {
-				log.Fatalf("Error writing out a translation: %s, %s\nError: %s\n\n Translation Data:\n%s", lang, file, err, translation)
-			}
+	for lang, translation := range translations {
+		if err = file.WriteTranslation(rootDir, lang, sourceLang, translation); err != nil {
+			log.Fatalf("Error writing out a translation: %s, %s\nError: %s\n\n Translation Data:\n%s", lang, file.Slug, err, translation)
 		}
-		doneChan <- true
-		break
 	}
+	doneChan <- len(translations)
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"transifex"
+	"transifex/cli"
+	"transifex/config"
+	"transifex/format"
+)
+
+var out = flag.String("out", "xliff", "The directory the xliff files are written to")
+var version = flag.String("xliff", "1.2", "The version of the xliff files: 1.2 or 2.0")
+var sourceLang = flag.String("source", "", "The source language of the resources.  Defaults to the source language of the transifex project")
+var langs = flag.String("langs", "", "A comma separated list of the languages to export.  Defaults to the translations of each resource")
+
+func main() {
+	transifexCLI := cli.NewCLI()
+
+	newFormat, has := format.Formats["XLIFF"+strings.Replace(*version, ".", "", -1)]
+	if !has {
+		fmt.Printf("The 'xliff' flag must be 1.2 or 2.0.  \n\n")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	xliff := newFormat().(*format.Xliff)
+
+	conf, err := transifexCLI.ReadConfig()
+	if err != nil {
+		log.Fatalf("Error reading reading language files: \n\n%s", err)
+	}
+
+	var client *http.Client
+	if *sourceLang == "" {
+		if client, err = transifex.NewHTTPClient(conf.HTTP.Merge(transifexCLI.HTTPSettings())); err != nil {
+			log.Fatalf("Error configuring the http client: %s", err)
+		}
+	}
+
+	summaries := []string{}
+	for _, project := range conf.Projects {
+		srcLang := *sourceLang
+		if srcLang == "" {
+			if srcLang, err = transifexCLI.TransifexAPI(project, client).SourceLanguage(); err != nil {
+				log.Fatalf("Error loading the transifex project data of %s: %s", project.Slug, err)
+			}
+		}
+		written := 0
+		for _, file := range project.Files {
+			written += exportFile(xliff, project, file, srcLang)
+		}
+		summaries = append(summaries, fmt.Sprintf("%s: %d xliff files written for %d resources", project.Slug, written, len(project.Files)))
+	}
+
+	fmt.Printf("\nExport Summary:\n  * %s\n", strings.Join(summaries, "\n  * "))
+}
+
+// Write a bilingual xliff file of the resource for each language.  Returns the number of files written
+func exportFile(xliff *format.Xliff, project config.Project, file config.LocalizationFile, sourceLang string) int {
+	if _, has := file.Translations[sourceLang]; !has {
+		log.Fatalf("The resource %s has no %s source file", file.Slug, sourceLang)
+	}
+	source, err := file.ReadSourceCatalog(sourceLang)
+	if err != nil {
+		log.Fatalf("Error reading the source file of %s: %s", file.Slug, err)
+	}
+
+	targetLangs := []string{}
+	if *langs != "" {
+		targetLangs = strings.Split(*langs, ",")
+	} else {
+		for lang := range file.Translations {
+			if lang != sourceLang {
+				targetLangs = append(targetLangs, lang)
+			}
+		}
+	}
+
+	for _, lang := range targetLangs {
+		target, err := file.ReadCatalog(strings.TrimSpace(lang))
+		if err != nil {
+			log.Fatalf("Error reading the %s translation of %s: %s", lang, file.Slug, err)
+		}
+		path := filepath.Join(*out, project.Slug, fmt.Sprintf("%s.%s.xlf", file.Slug, target.Lang))
+		fmt.Println("Writing xliff file: " + path)
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			err = ioutil.WriteFile(path, xliff.EncodeBilingual(project.Slug+"/"+file.Slug, source, target), 0644)
+		}
+		if err != nil {
+			log.Fatalf("Error writing %s: %s", path, err)
+		}
+	}
+	return len(targetLangs)
+}
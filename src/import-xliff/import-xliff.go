@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"transifex/cli"
+	"transifex/config"
+	"transifex/format"
+)
+
+var in = flag.String("in", "xliff", "The directory of the translated xliff files.  All .xlf and .xliff files below it are imported")
+
+func main() {
+	transifexCLI := cli.NewCLI()
+	rootDir := transifexCLI.RootDir()
+
+	conf, err := transifexCLI.ReadConfig()
+	if err != nil {
+		log.Fatalf("Error reading reading language files: \n\n%s", err)
+	}
+
+	imported := 0
+	err = filepath.Walk(*in, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if ext := filepath.Ext(path); info.IsDir() || ext != ".xlf" && ext != ".xliff" {
+			return nil
+		}
+		if err := importFile(rootDir, conf, path); err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+		imported++
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Error importing the xliff files: %s", err)
+	}
+
+	fmt.Printf("\nImport Summary:\n  * %d xliff files imported from %s\n", imported, *in)
+}
+
+// Write the translations of the xliff file to the translation file of its resource.  The original of the
+// xliff file is the project/resource written by export-xliff.  The translations of the existing translation file
+// that are not in the xliff file are kept
+func importFile(rootDir string, conf config.Config, path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	original, source, target, err := format.DecodeXliff(content)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return fmt.Errorf("The xliff file has no target language")
+	}
+
+	file, found := findResource(conf, original)
+	if !found {
+		return fmt.Errorf("No resource %q in the configuration", original)
+	}
+
+	translated := format.NewCatalog(target.Lang)
+	for _, e := range target.Entries {
+		if e.Value != "" || len(e.Plurals) > 0 {
+			translated.Add(e)
+		}
+	}
+	if len(translated.Entries) == 0 {
+		fmt.Printf("Skipping %s, it has no translations\n", path)
+		return nil
+	}
+	merged, err := file.MergeCatalog(translated)
+	if err != nil {
+		return err
+	}
+	kept := format.NewCatalog(target.Lang)
+	for _, e := range merged.Entries {
+		if e.Value != "" || len(e.Plurals) > 0 {
+			kept.Add(e)
+		}
+	}
+	translation, err := format.EncodeStructuredJson(kept)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Importing %d translations of %s into the %s translation of %s\n", len(translated.Entries), path, target.Lang, original)
+	return file.WriteTranslation(rootDir, target.Lang, source.Lang, string(translation))
+}
+
+// find the resource by project/slug, or only the slug
+func findResource(conf config.Config, original string) (config.LocalizationFile, bool) {
+	projectSlug, slug := "", original
+	if i := strings.LastIndex(original, "/"); i >= 0 {
+		projectSlug, slug = original[:i], original[i+1:]
+	}
+	for _, project := range conf.Projects {
+		if projectSlug != "" && project.Slug != projectSlug {
+			continue
+		}
+		for _, file := range project.Files {
+			if file.Slug == slug {
+				return file, true
+			}
+		}
+	}
+	return config.LocalizationFile{}, false
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"transifex"
+	"transifex/cli"
+	"transifex/config"
+	"transifex/webhook"
+)
+
+const secretEnvVar = "TRANSIFEX_WEBHOOK_SECRET"
+
+var addr = flag.String("addr", ":8080", "The address to listen on for webhook requests")
+var path = flag.String("path", "/", "The url path the webhook is configured to post to")
+var secret = flag.String("secret", "", "The webhook secret.  Defaults to the "+secretEnvVar+" environment variable")
+
+func main() {
+	transifexCLI := cli.NewCLI()
+	rootDir := transifexCLI.RootDir()
+
+	if *secret == "" {
+		*secret = os.Getenv(secretEnvVar)
+	}
+	if *secret == "" {
+		fmt.Printf("The 'secret' flag or the %s environment variable is required.  \n\n", secretEnvVar)
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	conf, err := transifexCLI.ReadConfig()
+	if err != nil {
+		log.Fatalf("Error reading reading language files: \n\n%s", err)
+	}
+	if len(conf.Projects) != 1 {
+		log.Fatalf("The configuration file declares %d projects, use the 'project' flag to select the project of the webhook", len(conf.Projects))
+	}
+	project := conf.Projects[0]
+
+	client, err := transifex.NewHTTPClient(conf.HTTP.Merge(transifexCLI.HTTPSettings()))
+	if err != nil {
+		log.Fatalf("Error configuring the http client: %s", err)
+	}
+	transifexApi := transifexCLI.TransifexAPI(project, client)
+
+	sourceLang, err := transifexApi.SourceLanguage()
+	if err != nil {
+		log.Fatalf("Error loading the transifext project data: %s", err)
+	}
+
+	handler := webhook.Handler{
+		Secret:     *secret,
+		Project:    project.Slug,
+		SourceLang: sourceLang,
+		Files:      project.Files,
+		Download: func(file config.LocalizationFile, lang string) error {
+			translation, err := transifexApi.DownloadTranslation(file.Slug, lang)
+			if err != nil {
+				return err
+			}
+			return file.WriteTranslation(rootDir, lang, sourceLang, translation)
+		}}
+
+	http.Handle(*path, handler)
+	log.Printf("Listening for transifex webhooks on %s%s", *addr, *path)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -0,0 +1,230 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type RecorderMode int
+
+const (
+	// requests are sent to the real server and the exchanges are saved to the fixture file
+	Record RecorderMode = iota
+	// requests are answered from the fixture file, no network access is required
+	Replay
+)
+
+// environment variable that switches RecordOrReplay to Record mode
+const RecordEnvVar = "TRANSIFEX_RECORD"
+
+const scrubbed = "REDACTED"
+
+// Headers that are never written to a fixture file
+var ScrubbedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// Query parameters whose values are never written to a fixture file, compared ignoring case
+var ScrubbedParams = []string{"api_key", "apikey", "access_token", "token", "password", "secret"}
+
+// A single recorded http request and its response
+type Exchange struct {
+	Method          string      `json:"method"`
+	Url             string      `json:"url"`
+	RequestHeaders  http.Header `json:"request_headers"`
+	RequestBody     string      `json:"request_body"`
+	StatusCode      int         `json:"status_code"`
+	ResponseHeaders http.Header `json:"response_headers"`
+	ResponseBody    string      `json:"response_body"`
+}
+
+// An http.RoundTripper that records exchanges with a server to a fixture file and can later
+// replay them so that tests can run offline and deterministically.
+//
+// In Replay mode each request is matched against the first unused exchange with the same method, url and body.
+type Recorder struct {
+	Mode    RecorderMode
+	Fixture string
+	// the transport used to make the real requests in Record mode.  http.DefaultTransport if nil
+	Transport http.RoundTripper
+
+	mutex     sync.Mutex
+	exchanges []Exchange
+	used      []bool
+}
+
+func NewRecorder(fixture string, mode RecorderMode) (*Recorder, error) {
+	r := &Recorder{Mode: mode, Fixture: fixture}
+	if mode == Replay {
+		data, err := ioutil.ReadFile(fixture)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read fixture %s: %s", fixture, err)
+		}
+		if err = json.Unmarshal(data, &r.exchanges); err != nil {
+			return nil, fmt.Errorf("Fixture %s is not valid: %s", fixture, err)
+		}
+		r.used = make([]bool, len(r.exchanges))
+	}
+	return r, nil
+}
+
+// Creates a Recorder for the fixture.  The recorder replays the fixture unless the TRANSIFEX_RECORD
+// environment variable is set, in which case real requests are made and the fixture is saved when the test completes
+func RecordOrReplay(fixture string, t *testing.T) *Recorder {
+	mode := Replay
+	if os.Getenv(RecordEnvVar) != "" {
+		mode = Record
+	}
+	r, err := NewRecorder(fixture, mode)
+	if err != nil {
+		t.Fatalf("Unable to create recorder: %s", err)
+	}
+	if mode == Record {
+		t.Cleanup(func() {
+			if err := r.Save(); err != nil {
+				t.Errorf("Unable to save fixture %s: %s", fixture, err)
+			}
+		})
+	}
+	return r
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	url := scrubUrl(req)
+
+	if r.Mode == Replay {
+		return r.replay(req, url, body)
+	}
+
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	r.exchanges = append(r.exchanges, Exchange{
+		Method:          req.Method,
+		Url:             url,
+		RequestHeaders:  scrubHeaders(req.Header),
+		RequestBody:     string(body),
+		StatusCode:      resp.StatusCode,
+		ResponseHeaders: scrubHeaders(resp.Header),
+		ResponseBody:    string(respBody)})
+	r.mutex.Unlock()
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+	return resp, nil
+}
+
+// Write all recorded exchanges to the fixture file.  Does nothing in Replay mode
+func (r *Recorder) Save() error {
+	if r.Mode != Record {
+		return nil
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	data, err := json.MarshalIndent(r.exchanges, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(r.Fixture), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.Fixture, data, 0644)
+}
+
+func (r *Recorder) replay(req *http.Request, url string, body []byte) (*http.Response, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, e := range r.exchanges {
+		if r.used[i] || e.Method != req.Method || e.Url != url || e.RequestBody != string(body) {
+			continue
+		}
+		r.used[i] = true
+		header := http.Header{}
+		for k, v := range e.ResponseHeaders {
+			header[k] = v
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+			StatusCode:    e.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(bytes.NewReader([]byte(e.ResponseBody))),
+			ContentLength: int64(len(e.ResponseBody)),
+			Request:       req}, nil
+	}
+	return nil, fmt.Errorf("No recorded exchange in %s matches %s %s", r.Fixture, req.Method, url)
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func scrubUrl(req *http.Request) string {
+	u := *req.URL
+	if u.User != nil {
+		u.User = nil
+	}
+	// the query is only re-encoded when a value is scrubbed so that the other urls are recorded as sent
+	query, scrub := u.Query(), false
+	for param := range query {
+		for _, p := range ScrubbedParams {
+			if strings.EqualFold(param, p) {
+				for i := range query[param] {
+					query[param][i] = scrubbed
+				}
+				scrub = true
+			}
+		}
+	}
+	if scrub {
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+func scrubHeaders(header http.Header) http.Header {
+	clean := http.Header{}
+	for k, v := range header {
+		clean[k] = v
+	}
+	for _, h := range ScrubbedHeaders {
+		if _, has := clean[http.CanonicalHeaderKey(h)]; has {
+			clean.Set(h, scrubbed)
+		}
+	}
+	return clean
+}
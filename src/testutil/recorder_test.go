@@ -0,0 +1,123 @@
+package testutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Recorder_RecordAndReplay(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret-session")
+		body, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, `{"path": %q, "body": %q}`, r.URL.Path, string(body))
+	}))
+
+	dir, _ := ioutil.TempDir("", "fixtures")
+	defer os.RemoveAll(dir)
+	fixture := filepath.Join(dir, "nested", "exchanges.json")
+
+	recorder, err := NewRecorder(fixture, Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: recorder}
+
+	send := func(client *http.Client, method, path, body string) string {
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.SetBasicAuth("user", "secret-password")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request %s %s failed: %s", method, path, err)
+		}
+		defer resp.Body.Close()
+		data, _ := ioutil.ReadAll(resp.Body)
+		return string(data)
+	}
+
+	first := send(client, "GET", "/a", "")
+	second := send(client, "PUT", "/b", "content")
+
+	if err = recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	ts.Close()
+
+	fixtureData, _ := ioutil.ReadFile(fixture)
+	if strings.Contains(string(fixtureData), "secret") {
+		t.Errorf("Credentials were not scrubbed from the fixture:\n%s", fixtureData)
+	}
+	// base64 of user:secret-password
+	if strings.Contains(string(fixtureData), "dXNlcjpzZWNyZXQtcGFzc3dvcmQ=") {
+		t.Errorf("Basic auth header was not scrubbed from the fixture:\n%s", fixtureData)
+	}
+
+	replayer, err := NewRecorder(fixture, Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: replayer}
+
+	AssertEquals("replayed PUT", second, send(client, "PUT", "/b", "content"), t)
+	AssertEquals("replayed GET", first, send(client, "GET", "/a", ""), t)
+
+	req, _ := http.NewRequest("GET", ts.URL+"/a", nil)
+	if _, err := client.Do(req); err == nil {
+		t.Errorf("Expected an error because the recorded exchange was already used")
+	}
+}
+
+func Test_Recorder_ScrubsQuery(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Query().Get("page"))
+	}))
+
+	dir, _ := ioutil.TempDir("", "fixtures")
+	defer os.RemoveAll(dir)
+	fixture := filepath.Join(dir, "exchanges.json")
+
+	recorder, err := NewRecorder(fixture, Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/resources?page=2&API_KEY=secret-key&access_token=secret-token"
+	if _, err = (&http.Client{Transport: recorder}).Get(ts.URL + path); err != nil {
+		t.Fatal(err)
+	}
+	if err = recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	ts.Close()
+
+	fixtureData, _ := ioutil.ReadFile(fixture)
+	if strings.Contains(string(fixtureData), "secret") {
+		t.Errorf("Query parameters were not scrubbed from the fixture:\n%s", fixtureData)
+	}
+	if !strings.Contains(string(fixtureData), `/resources?API_KEY=REDACTED\u0026access_token=REDACTED\u0026page=2`) {
+		t.Errorf("Expected the other query parameters in the fixture:\n%s", fixtureData)
+	}
+
+	// the request is replayed whatever the values of the scrubbed parameters
+	replayer, err := NewRecorder(fixture, Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: replayer}).Get(ts.URL + "/resources?page=2&API_KEY=other&access_token=other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := ioutil.ReadAll(resp.Body)
+	AssertEquals("replayed", "2", string(data), t)
+}
+
+func Test_NewRecorder_MissingFixture(t *testing.T) {
+	if _, err := NewRecorder(filepath.Join(os.TempDir(), "does-not-exist.json"), Replay); err == nil {
+		t.Errorf("Expected an error when replaying a fixture that does not exist")
+	}
+}
@@ -31,7 +31,7 @@ func AssertEquals(msg, expected, actual string, t *testing.T) {
 }
 func AssertEqualsInt(msg string, expected, actual int, t *testing.T) {
 	if actual != expected {
-		t.Errorf("%s: Expected/Actual \n%q\n%q", msg, expected, actual)
+		t.Errorf("%s: Expected/Actual \n%d\n%d", msg, expected, actual)
 	}
 }
 
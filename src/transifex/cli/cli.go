@@ -5,15 +5,19 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"transifex"
+	"transifex/config"
 )
 
 const version = "0.1.0"
 
 type CLI struct {
 	projectSlug, configFile, username, password *string
+	proxy, caCert, clientCert, clientKey        *string
 	debug                                       *bool
 	rootDir                                     string
 }
@@ -21,10 +25,14 @@ type CLI struct {
 func NewCLI() CLI {
 	versionFlag := flag.Bool("version", false, "Print version")
 	cli := CLI{
-		projectSlug: flag.String("project", "", "REQUIRED - the transifex project slug"),
+		projectSlug: flag.String("project", "", "The transifex project slug.  Required unless the configuration file declares the projects, in which case it selects a single project"),
 		configFile:  flag.String("config", "", "REQUIRED - The location of the configuration file"),
 		username:    flag.String("username", "", "The transifex username"),
 		password:    flag.String("password", "", "The transifex password"),
+		proxy:       flag.String("proxy", "", "The url of the proxy to use for all requests to transifex"),
+		caCert:      flag.String("cacert", "", "A PEM file of additional CA certificates to trust"),
+		clientCert:  flag.String("cert", "", "A PEM client certificate for connecting to the proxy or transifex"),
+		clientKey:   flag.String("key", "", "The PEM private key of the client certificate"),
 		debug:       flag.Bool("v", false, "if true then debug information will be printed")}
 
 	flag.Parse()
@@ -38,11 +46,6 @@ func NewCLI() CLI {
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
-	if *cli.projectSlug == "" {
-		fmt.Printf("The 'project' flag is required.  \n\n")
-		flag.PrintDefaults()
-		os.Exit(1)
-	}
 
 	cli.rootDir = filepath.Dir(*cli.configFile)
 
@@ -68,6 +71,40 @@ func (cli CLI) Debug() bool {
 	return *cli.debug
 }
 
+func (cli CLI) HTTPSettings() transifex.HTTPSettings {
+	return transifex.HTTPSettings{
+		Proxy:      *cli.proxy,
+		CACert:     *cli.caCert,
+		ClientCert: *cli.clientCert,
+		ClientKey:  *cli.clientKey}
+}
+
+// Read the configuration file.  If the project flag is set only that project is loaded
+func (cli CLI) ReadConfig() (config.Config, error) {
+	conf, err := config.ReadProjectConfig(cli.ConfigFile(), cli.RootDir(), cli.ProjectSlug())
+	if err != nil {
+		return conf, err
+	}
+	for _, project := range conf.Projects {
+		if project.Slug == "" {
+			return conf, fmt.Errorf("The 'project' flag is required when the configuration file does not declare the projects")
+		}
+	}
+	return conf, nil
+}
+
+// Create the api for the project using the credentials referenced by the project or, if not set,
+// the credentials provided on the command line
+func (cli CLI) TransifexAPI(project config.Project, client *http.Client) transifex.TransifexAPI {
+	username, password, ok := project.Credentials()
+	if !ok {
+		username, password = cli.Username(), cli.Password()
+	}
+	api := transifex.NewTransifexAPI(project.Slug, username, password, transifex.WithHTTPClient(client))
+	api.Debug = cli.Debug()
+	return api
+}
+
 func (cli CLI) Username() string {
 	readAuth(cli.username, "username")
 	return *cli.username
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strings"
 	"transifex"
@@ -12,9 +13,11 @@ import (
 )
 
 type configElement struct {
-	Type      string             `json:"type"`
-	Structure string             `json:"structure"`
-	Resources []LocalizationFile `json:"resources"`
+	Type       string             `json:"type"`
+	Structure  string             `json:"structure"`
+	// the language of the source file for structures where the file name does not contain the language (ANDROID, LPROJ)
+	SourceLang string             `json:"sourceLang"`
+	Resources  []LocalizationFile `json:"resources"`
 }
 
 type LocalizationFile struct {
@@ -30,12 +33,26 @@ type LocalizationFile struct {
 
 func (f *LocalizationFile) init(rootDir string, elem configElement) error {
 	f.Category = strings.Join(f.Categories, " ")
-	f.Format = format.Formats[elem.Type]()
-	f.Format.init(f.ExtraParams)
+	newFormat, has := format.Formats[elem.Type]
+	if !has {
+		return fmt.Errorf("Unknown format %q for resource %s", elem.Type, f.Slug)
+	}
+	if _, has = format.FileLocators[elem.Structure]; !has {
+		return fmt.Errorf("Unknown structure %q for resource %s", elem.Structure, f.Slug)
+	}
+	f.Format = newFormat()
+	f.Format.Init(f.ExtraParams)
 	f.FileLocator = format.FileLocators[elem.Structure]
+	if locator, ok := f.FileLocator.(format.SourceLangLocator); ok && elem.SourceLang != "" {
+		f.FileLocator = locator.WithSourceLang(elem.SourceLang)
+	}
 
 	var readErr error
-	f.Translations, readErr = f.FileLocator.List(filepath.Join(rootDir, f.Dir), f.Fname, f.Format.Ext())
+	if multiLang, ok := f.Format.(format.MultiLangFormat); ok {
+		f.Translations, readErr = listLanguages(multiLang, f.FileLocator.Find(filepath.Join(rootDir, f.Dir), "", f.Fname, f.Format.Ext()))
+	} else {
+		f.Translations, readErr = f.FileLocator.List(filepath.Join(rootDir, f.Dir), f.Fname, f.Format.Ext())
+	}
 
 	if readErr != nil {
 		return readErr
@@ -44,6 +61,105 @@ func (f *LocalizationFile) init(rootDir string, elem configElement) error {
 	return nil
 }
 
+// all the languages of a file of a MultiLangFormat are in the same file
+func listLanguages(f format.MultiLangFormat, path string) (map[string]string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	langs, err := f.Languages(content)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the languages of %s: %s", path, err)
+	}
+	translations := map[string]string{}
+	for _, lang := range langs {
+		translations[lang] = path
+	}
+	return translations, nil
+}
+
+// Clean the content of the translation file of the language for uploading it to transifex
+func (f LocalizationFile) Clean(lang string, content []byte) ([]byte, string, error) {
+	if multiLang, ok := f.Format.(format.MultiLangFormat); ok {
+		return multiLang.CleanLang(content, lang)
+	}
+	return f.Format.Clean(content)
+}
+
+// Read the strings of the translation file of the language.  Returns an empty catalog if there is no file for the language
+func (f LocalizationFile) ReadCatalog(lang string) (*format.Catalog, error) {
+	path, has := f.Translations[lang]
+	if !has {
+		return format.NewCatalog(lang), nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c *format.Catalog
+	if multiLang, ok := f.Format.(format.MultiLangFormat); ok {
+		cleaned, _, cleanErr := multiLang.CleanLang(content, lang)
+		if cleanErr != nil {
+			return nil, fmt.Errorf("Unable to read %s: %s", path, cleanErr)
+		}
+		c, err = format.DecodeTranslation(cleaned, lang)
+	} else {
+		c, err = f.Format.Parse(content)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read %s: %s", path, err)
+	}
+	c.Lang = lang
+	return c, nil
+}
+
+// Read the strings of the source file of the language as they are uploaded to transifex.  Unlike ReadCatalog the
+// values are the source text for formats where the source file has no translations: the msgid of a PO template
+func (f LocalizationFile) ReadSourceCatalog(lang string) (*format.Catalog, error) {
+	c, err := f.ReadCatalog(lang)
+	path, has := f.Translations[lang]
+	if err != nil || !has {
+		return c, err
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cleaned, _, err := f.Clean(lang, content)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read %s: %s", path, err)
+	}
+	uploaded, err := format.DecodeTranslation(cleaned, lang)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read %s: %s", path, err)
+	}
+	source, _ := c.Translate(uploaded)
+	return source, nil
+}
+
+// The entries of the existing translation file of the language of c, with the entries of c added or replaced.
+// Used to write a part of the translations without losing the others
+func (f LocalizationFile) MergeCatalog(c *format.Catalog) (*format.Catalog, error) {
+	merged, err := f.ReadCatalog(c.Lang)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range c.Entries {
+		if existing := merged.Find(e.ID()); existing != nil {
+			*existing = *e
+		} else {
+			merged.Add(e)
+		}
+	}
+	return merged, nil
+}
+
+// Write the translation downloaded from transifex to the translation file of the language
+func (f LocalizationFile) WriteTranslation(rootDir, lang, srcLang, translation string) error {
+	return f.Format.Write(filepath.Join(rootDir, f.Dir), lang, srcLang, f.Fname, translation, f.FileLocator)
+}
+
 // type LocalizationFile struct {
 // 	transifex.BaseResource
 // 	Filename     string
@@ -56,31 +172,114 @@ func ReadConfig(configFile, rootDir, sourceLang string) (files []LocalizationFil
 	if sourceLang == "" {
 		return nil, fmt.Errorf("Source lang is empty.")
 	}
+
+	conf, err := ReadProjectConfig(configFile, rootDir, "")
+	if err != nil {
+		return nil, err
+	}
+
+	files = []LocalizationFile{}
+	for _, project := range conf.Projects {
+		files = append(files, project.Files...)
+	}
+	return files, nil
+}
+
+// A configuration file with one or more transifex projects
+type Config struct {
+	// network settings for connecting to transifex.  The command line flags take precedence
+	HTTP     transifex.HTTPSettings
+	Projects []Project
+}
+
+type Project struct {
+	// the transifex project slug
+	Slug string
+	// names of the environment variables containing the credentials for the project.
+	// If empty the credentials provided on the command line are used
+	UsernameEnv, PasswordEnv string
+	Files                    []LocalizationFile
+}
+
+// Returns the credentials read from the environment variables referenced by the project.
+// ok is false if the project does not reference credentials or the variables are not set
+func (p Project) Credentials() (username, password string, ok bool) {
+	if p.UsernameEnv == "" || p.PasswordEnv == "" {
+		return "", "", false
+	}
+	username, password = os.Getenv(p.UsernameEnv), os.Getenv(p.PasswordEnv)
+	return username, password, username != "" && password != ""
+}
+
+type projectElement struct {
+	Slug        string `json:"slug"`
+	Credentials struct {
+		UsernameEnv string `json:"usernameEnv"`
+		PasswordEnv string `json:"passwordEnv"`
+	} `json:"credentials"`
+	Resources []configElement `json:"resources"`
+}
+
+type configFileElement struct {
+	HTTP     transifex.HTTPSettings `json:"http"`
+	Projects []projectElement       `json:"projects"`
+}
+
+// Read a configuration file.  Two forms of configuration file are supported:
+//
+// * a list of resource groups, all belonging to defaultProject (the original format)
+// * an object with a list of projects, each with its own resource groups and the optional names of the
+//   environment variables of its credentials (usernameEnv, passwordEnv).
+//   If defaultProject is not empty only that project is loaded
+func ReadProjectConfig(configFile, rootDir, defaultProject string) (Config, error) {
 	bytes, err := ioutil.ReadFile(configFile)
 	if err != nil {
 		fmt.Printf("Unable to read %s", configFile)
-		return nil, err
+		return Config{}, err
 	}
 
-	var jsonData []configElement
-	if err := json.Unmarshal(bytes, &jsonData); err != nil {
-		return nil, err
+	var jsonData configFileElement
+	if trimmed := strings.TrimSpace(string(bytes)); strings.HasPrefix(trimmed, "[") {
+		var elements []configElement
+		if err := json.Unmarshal(bytes, &elements); err != nil {
+			return Config{}, err
+		}
+		jsonData.Projects = []projectElement{{Slug: defaultProject, Resources: elements}}
+	} else if err := json.Unmarshal(bytes, &jsonData); err != nil {
+		return Config{}, err
 	}
 
+	conf := Config{HTTP: jsonData.HTTP}
+	for _, path := range []*string{&conf.HTTP.CACert, &conf.HTTP.ClientCert, &conf.HTTP.ClientKey} {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(rootDir, *path)
+		}
+	}
 	logSummary := []string{}
-	files = []LocalizationFile{}
-	for _, elem := range jsonData {
-		for _, f := range elem.Resources {
-			if err = f.init(rootDir, elem); err != nil {
-				return nil, err
+	for _, p := range jsonData.Projects {
+		if defaultProject != "" && p.Slug != defaultProject {
+			continue
+		}
+		project := Project{Slug: p.Slug, UsernameEnv: p.Credentials.UsernameEnv, PasswordEnv: p.Credentials.PasswordEnv, Files: []LocalizationFile{}}
+		for _, elem := range p.Resources {
+			for _, f := range elem.Resources {
+				if err = f.init(rootDir, elem); err != nil {
+					return Config{}, err
+				}
+				logSummary = append(logSummary, fmt.Sprintf("%s: %s -- %s", p.Slug, f.Dir, f.Fname))
+				project.Files = append(project.Files, f)
 			}
-			logSummary = append(logSummary, fmt.Sprintf("%s -- %s", f.Dir, f.Fname))
-			files = append(files, f)
 		}
+		conf.Projects = append(conf.Projects, project)
 	}
 
+	if len(conf.Projects) == 0 && defaultProject != "" {
+		return Config{}, fmt.Errorf("No project %q declared in %s", defaultProject, configFile)
+	} else if len(conf.Projects) == 0 {
+		return Config{}, fmt.Errorf("No projects declared in %s", configFile)
+	}
 
 	log.Printf("\nSuccessfully loaded %q.  Configuration files are as follows: \n  * %s\n\n", configFile, strings.Join(logSummary, "\n  * "))
 
-	return files, nil
+	return conf, nil
 }
@@ -1,9 +1,12 @@
 package config
 
 import (
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	tu "testutil"
+	"transifex/format"
 )
 
 func Test_ReadConfig_LangDir(t *testing.T) {
@@ -61,8 +64,6 @@ func Test_ReadConfig_LangDir(t *testing.T) {
 		panic(err)
 	}
 
-
-t.Errorf("%v", files)
 	if len(files) != 3 {
 		t.Errorf("Expected 3 file: %v", files)
 	}
@@ -140,3 +141,253 @@ func Test_UnmappedLang(t *testing.T) {
 
 	t.Errorf("A panic should have occurred because xxz is not a valid lang code")
 }
+
+func Test_ReadProjectConfig_MultipleProjects(t *testing.T) {
+	configText := `
+{
+  "http": {"proxy": "http://proxy:3128", "caCert": "certs/ca.pem"},
+  "projects": [{
+    "slug": "web",
+    "credentials": {"usernameEnv": "TX_WEB_USER", "passwordEnv": "TX_WEB_PASSWORD"},
+    "resources": [{
+      "type": "KEYVALUEJSON",
+      "structure": "LANG-NAME",
+      "resources": [{"dir": "js", "fname": "core", "name": "Core", "slug": "core"}]
+    }]
+  }, {
+    "slug": "schemas",
+    "resources": [{
+      "type": "FLATTENXMLTOJSON",
+      "structure": "3-CHAR-LOC-DIR",
+      "resources": [{"dir": "loc", "fname": "string", "name": "Strings", "slug": "strings"}]
+    }]
+  }]
+}`
+
+	root := tu.CreateFileTree(
+		tu.Dir("xyz",
+			tu.FileAndData("config.json", []byte(configText)),
+			tu.Dir("loc",
+				tu.Dir("eng", tu.File("string.xml")),
+				tu.Dir("fre", tu.File("string.xml"))),
+			tu.Dir("js",
+				tu.File("en-core.json"),
+				tu.File("fr-core.json"))))
+
+	conf, err := ReadProjectConfig(filepath.Join(root, "config.json"), root, "")
+	if err != nil {
+		t.Fatalf("Error reading config. %v", err)
+	}
+
+	tu.AssertEquals("proxy", "http://proxy:3128", conf.HTTP.Proxy, t)
+	tu.AssertEquals("ca cert", filepath.Join(root, "certs", "ca.pem"), conf.HTTP.CACert, t)
+	tu.AssertEqualsInt("projects", 2, len(conf.Projects), t)
+	tu.AssertEquals("first project", "web", conf.Projects[0].Slug, t)
+	tu.AssertEquals("first project file", "core", conf.Projects[0].Files[0].Slug, t)
+	tu.AssertEquals("second project", "schemas", conf.Projects[1].Slug, t)
+	tu.AssertEqualsInt("second project translations", 2, len(conf.Projects[1].Files[0].Translations), t)
+
+	os.Setenv("TX_WEB_USER", "web-user")
+	os.Setenv("TX_WEB_PASSWORD", "web-password")
+	defer os.Unsetenv("TX_WEB_USER")
+	defer os.Unsetenv("TX_WEB_PASSWORD")
+
+	if username, password, ok := conf.Projects[0].Credentials(); !ok || username != "web-user" || password != "web-password" {
+		t.Errorf("Unexpected credentials: %s, %s, %v", username, password, ok)
+	}
+	if _, _, ok := conf.Projects[1].Credentials(); ok {
+		t.Errorf("The schemas project does not reference credentials")
+	}
+
+	conf, err = ReadProjectConfig(filepath.Join(root, "config.json"), root, "schemas")
+	if err != nil {
+		t.Fatalf("Error reading config. %v", err)
+	}
+	tu.AssertEqualsInt("selected projects", 1, len(conf.Projects), t)
+	tu.AssertEquals("selected project", "schemas", conf.Projects[0].Slug, t)
+
+	if _, err = ReadProjectConfig(filepath.Join(root, "config.json"), root, "unknown"); err == nil {
+		t.Errorf("Expected an error for an unknown project")
+	}
+}
+
+func Test_ReadProjectConfig_Legacy(t *testing.T) {
+	configText := `[{"type": "KEYVALUEJSON", "structure": "LANG-NAME", "resources": [{"dir": "js", "fname": "core", "slug": "core"}]}]`
+	root := tu.CreateFileTree(
+		tu.Dir("xyz",
+			tu.FileAndData("config.json", []byte(configText)),
+			tu.Dir("js", tu.File("en-core.json"))))
+
+	conf, err := ReadProjectConfig(filepath.Join(root, "config.json"), root, "project")
+	if err != nil {
+		t.Fatalf("Error reading config. %v", err)
+	}
+	tu.AssertEqualsInt("projects", 1, len(conf.Projects), t)
+	tu.AssertEquals("project", "project", conf.Projects[0].Slug, t)
+	tu.AssertEqualsInt("files", 1, len(conf.Projects[0].Files), t)
+}
+
+func Test_ReadProjectConfig_SourceLang(t *testing.T) {
+	configText := `[{"type": "ANDROID", "structure": "ANDROID", "sourceLang": "de", "resources": [{"dir": "res", "fname": "strings", "slug": "app"}]}]`
+	root := tu.CreateFileTree(
+		tu.Dir("xyz",
+			tu.FileAndData("config.json", []byte(configText)),
+			tu.Dir("res",
+				tu.Dir("values", tu.File("strings.xml")),
+				tu.Dir("values-en", tu.File("strings.xml")))))
+
+	conf, err := ReadProjectConfig(filepath.Join(root, "config.json"), root, "project")
+	if err != nil {
+		t.Fatalf("Error reading config. %v", err)
+	}
+	translations := conf.Projects[0].Files[0].Translations
+	tu.AssertEquals("source", filepath.Join(root, "res", "values", "strings.xml"), translations["de"], t)
+	tu.AssertEquals("translation", filepath.Join(root, "res", "values-en", "strings.xml"), translations["en"], t)
+}
+
+func Test_ReadProjectConfig_MultiLangFormat(t *testing.T) {
+	configText := `[{"type": "XCSTRINGS", "structure": "SINGLE-FILE", "resources": [{"dir": "app", "fname": "Localizable", "slug": "app"}]}]`
+	catalog := `{"sourceLanguage": "en", "strings": {"Hello": {"localizations": {"fr": {"stringUnit": {"state": "translated", "value": "Bonjour"}}}}}}`
+	root := tu.CreateFileTree(
+		tu.Dir("xyz",
+			tu.FileAndData("config.json", []byte(configText)),
+			tu.Dir("app", tu.FileAndData("Localizable.xcstrings", []byte(catalog)))))
+
+	conf, err := ReadProjectConfig(filepath.Join(root, "config.json"), root, "project")
+	if err != nil {
+		t.Fatalf("Error reading config. %v", err)
+	}
+	file := conf.Projects[0].Files[0]
+	tu.AssertEqualsInt("languages", 2, len(file.Translations), t)
+	tu.AssertEquals("fr", filepath.Join(root, "app", "Localizable.xcstrings"), file.Translations["fr"], t)
+
+	cleaned, _, err := file.Clean("fr", []byte(catalog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("cleaned", `{"Hello":{"string":"Bonjour"}}`, string(cleaned), t)
+
+	c, err := file.ReadCatalog("fr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("catalog lang", "fr", c.Lang, t)
+	tu.AssertEquals("catalog", "Bonjour", c.Find("Hello").Value, t)
+}
+
+func Test_ReadCatalog(t *testing.T) {
+	configText := `[{"type": "KEYVALUEJSON", "structure": "LANG-NAME", "resources": [{"dir": "app", "fname": "core", "slug": "core"}]}]`
+	root := tu.CreateFileTree(
+		tu.Dir("xyz",
+			tu.FileAndData("config.json", []byte(configText)),
+			tu.Dir("app", tu.FileAndData("en-core.json", []byte(`{"b": "Bye", "a": "Hello"}`)))))
+
+	conf, err := ReadProjectConfig(filepath.Join(root, "config.json"), root, "project")
+	if err != nil {
+		t.Fatalf("Error reading config. %v", err)
+	}
+	file := conf.Projects[0].Files[0]
+
+	c, err := file.ReadCatalog("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("lang", "en", c.Lang, t)
+	tu.AssertEqualsInt("entries", 2, len(c.Entries), t)
+	tu.AssertEquals("file order", "b", c.Entries[0].Key, t)
+
+	if c, err = file.ReadCatalog("fr"); err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("no file", 0, len(c.Entries), t)
+}
+
+func Test_ReadSourceCatalog_Po(t *testing.T) {
+	configText := `[{"type": "PO", "structure": "LANG-NAME", "resources": [{"dir": "po", "fname": "messages", "slug": "messages"}]}]`
+	po := `msgid ""
+msgstr ""
+"Language: en\n"
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+#. The greeting
+msgid "Welcome"
+msgstr ""
+
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] ""
+msgstr[1] ""
+`
+	root := tu.CreateFileTree(
+		tu.Dir("xyz",
+			tu.FileAndData("config.json", []byte(configText)),
+			tu.Dir("po",
+				tu.FileAndData("en-messages.po", []byte(po)),
+				tu.FileAndData("fr-messages.po", []byte(strings.Replace(po, `msgid "Welcome"
+msgstr ""`, `msgid "Welcome"
+msgstr "Bienvenue"`, 1))))))
+
+	conf, err := ReadProjectConfig(filepath.Join(root, "config.json"), root, "project")
+	if err != nil {
+		t.Fatalf("Error reading config. %v", err)
+	}
+	file := conf.Projects[0].Files[0]
+
+	source, err := file.ReadSourceCatalog("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("msgid", "Welcome", source.Find("Welcome").Value, t)
+	tu.AssertEquals("comment", "The greeting", source.Find("Welcome").Comment, t)
+	tu.AssertEquals("plural one", "%d file", source.Find("%d file").Plurals["one"], t)
+	tu.AssertEquals("plural other", "%d files", source.Find("%d file").Plurals["other"], t)
+
+	// the xliff export has the source text
+	target, err := file.ReadCatalog("fr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	xliff := string(format.Formats["XLIFF12"]().(*format.Xliff).EncodeBilingual("project/messages", source, target))
+	if !strings.Contains(xliff, "<source>Welcome</source>") || !strings.Contains(xliff, ">Bienvenue</target>") {
+		t.Errorf("Expected the source text in the xliff:\n%s", xliff)
+	}
+	if strings.Contains(xliff, "<source></source>") {
+		t.Errorf("Unexpected empty source in the xliff:\n%s", xliff)
+	}
+}
+
+func Test_MergeCatalog(t *testing.T) {
+	configText := `[{"type": "KEYVALUEJSON", "structure": "LANG-NAME", "resources": [{"dir": "app", "fname": "core", "slug": "core"}]}]`
+	root := tu.CreateFileTree(
+		tu.Dir("xyz",
+			tu.FileAndData("config.json", []byte(configText)),
+			tu.Dir("app",
+				tu.FileAndData("en-core.json", []byte(`{"a": "Hello", "b": "Bye", "c": "New"}`)),
+				tu.FileAndData("fr-core.json", []byte(`{"a": "Bonjour", "b": "Au revoir"}`)))))
+
+	conf, err := ReadProjectConfig(filepath.Join(root, "config.json"), root, "project")
+	if err != nil {
+		t.Fatalf("Error reading config. %v", err)
+	}
+	file := conf.Projects[0].Files[0]
+
+	// a partial import keeps the existing translations
+	imported := format.NewCatalog("fr")
+	imported.Add(&format.Entry{Key: "a", Value: "Salut"})
+	imported.Add(&format.Entry{Key: "c", Value: "Nouveau"})
+	merged, err := file.MergeCatalog(imported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("entries", 3, len(merged.Entries), t)
+	tu.AssertEquals("replaced", "Salut", merged.Find("a").Value, t)
+	tu.AssertEquals("kept", "Au revoir", merged.Find("b").Value, t)
+	tu.AssertEquals("added", "Nouveau", merged.Find("c").Value, t)
+
+	// without a translation file only the imported entries are merged
+	imported.Lang = "de"
+	if merged, err = file.MergeCatalog(imported); err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("new language", 2, len(merged.Entries), t)
+}
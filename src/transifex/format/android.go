@@ -0,0 +1,456 @@
+package format
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Android string resources (res/values/strings.xml).  <string> elements are keyed by their name, the items of a
+// <string-array> are keyed by name[index] and the quantities of <plurals> are kept as plural forms.
+// Strings marked translatable="false" are not uploaded and not written to the translations.
+//
+// The android escapes (\' \" \n \@ ...) are removed from the uploaded strings and added again when writing.
+// Strings with markup (<b>, <xliff:g> ...) are uploaded with the markup, CDATA sections are kept when writing.
+type Android struct{}
+
+func (f *Android) Init(initParams map[string]interface{}) {}
+func (f *Android) Ext() string                            { return "xml" }
+
+// flags of the entries recording how the value was written in the source file
+const (
+	androidMarkup = "markup"
+	androidCDATA  = "cdata"
+)
+
+func (f *Android) Parse(content []byte) (*Catalog, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	c := NewCatalog("")
+	comment := ""
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.Comment:
+			if depth == 1 {
+				comment = strings.TrimSpace(string(t))
+			}
+		case xml.EndElement:
+			depth--
+		case xml.StartElement:
+			if depth++; depth != 2 {
+				continue
+			}
+			// the element is read completely here
+			depth--
+			name := xmlAttr(t, "name")
+			if xmlAttr(t, "translatable") == "false" {
+				decoder.Skip()
+				comment = ""
+				continue
+			}
+			switch t.Name.Local {
+			case "string":
+				value, flags, err := readAndroidValue(decoder, content)
+				if err != nil {
+					return nil, err
+				}
+				c.Add(&Entry{Key: name, Value: value, Comment: comment, Flags: flags})
+			case "string-array":
+				index := 0
+				err := eachAndroidItem(decoder, func(item xml.StartElement) error {
+					value, flags, err := readAndroidValue(decoder, content)
+					c.Add(&Entry{Key: fmt.Sprintf("%s[%d]", name, index), Value: value, Comment: comment, Flags: flags})
+					index++
+					return err
+				})
+				if err != nil {
+					return nil, err
+				}
+			case "plurals":
+				plurals := map[string]string{}
+				var pluralFlags []string
+				err := eachAndroidItem(decoder, func(item xml.StartElement) error {
+					value, flags, err := readAndroidValue(decoder, content)
+					quantity := xmlAttr(item, "quantity")
+					plurals[quantity] = value
+					for _, flag := range flags {
+						pluralFlags = append(pluralFlags, quantity+":"+flag)
+					}
+					return err
+				})
+				if err != nil {
+					return nil, err
+				}
+				c.Add(&Entry{Key: name, Plurals: plurals, Comment: comment, Flags: pluralFlags})
+			default:
+				decoder.Skip()
+			}
+			comment = ""
+		}
+	}
+	return c, nil
+}
+
+func (f *Android) Clean(content []byte) ([]byte, string, error) {
+	return cleanToStructuredJson(f, content)
+}
+
+// The source file is replayed with the values of the catalog.  Strings without a translation are left out
+// so that android falls back to the source language
+func (f *Android) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	if template == nil {
+		return nil, fmt.Errorf("The Android format requires the source file to write a translation")
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(template))
+	var out bytes.Buffer
+	last := int64(0)
+	copyTo := func(offset int64) {
+		out.Write(template[last:offset])
+		last = offset
+	}
+	// leave out the element starting at offset, including the indentation of its line
+	drop := func(offset int64) {
+		copyTo(offset)
+		trimmed := bytes.TrimRight(out.Bytes(), " \t")
+		out.Truncate(len(bytes.TrimSuffix(trimmed, []byte("\n"))))
+		decoder.Skip()
+		last = decoder.InputOffset()
+	}
+
+	depth := 0
+	for {
+		before := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.EndElement:
+			depth--
+		case xml.StartElement:
+			if depth++; depth != 2 {
+				continue
+			}
+			depth--
+			name := xmlAttr(t, "name")
+			if xmlAttr(t, "translatable") == "false" {
+				drop(before)
+				continue
+			}
+			switch t.Name.Local {
+			case "string":
+				e := c.Find(name)
+				if e == nil || e.Value == "" || isSelfClosing(template, decoder.InputOffset()) {
+					drop(before)
+					continue
+				}
+				copyTo(decoder.InputOffset())
+				out.WriteString(androidValue(e.Value, e.Flags))
+				end, err := skipXmlContent(decoder)
+				if err != nil {
+					return nil, err
+				}
+				last = end
+			case "string-array":
+				if !hasAndroidArrayTranslation(c, name) {
+					drop(before)
+					continue
+				}
+				index := 0
+				err := eachAndroidItem(decoder, func(item xml.StartElement) error {
+					key := fmt.Sprintf("%s[%d]", name, index)
+					index++
+					if isSelfClosing(template, decoder.InputOffset()) {
+						return decoder.Skip()
+					}
+					copyTo(decoder.InputOffset())
+					if e := c.Find(key); e != nil {
+						out.WriteString(androidValue(e.Value, e.Flags))
+					}
+					end, err := skipXmlContent(decoder)
+					last = end
+					return err
+				})
+				if err != nil {
+					return nil, err
+				}
+			case "plurals":
+				e := c.Find(name)
+				if e == nil || !hasPluralTranslation(e) {
+					drop(before)
+					continue
+				}
+				copyTo(decoder.InputOffset())
+				end, err := writeAndroidPlurals(&out, e, template, decoder)
+				if err != nil {
+					return nil, err
+				}
+				last = end
+			default:
+				decoder.Skip()
+			}
+		}
+	}
+	copyTo(int64(len(template)))
+	return out.Bytes(), nil
+}
+
+// The source language file is not written: it contains the strings that are not translatable
+// and android uses it for all strings missing from the translations
+func (f *Android) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	if langCode == srcLang {
+		return nil
+	}
+	return writeCatalog(f, rootDir, langCode, srcLang, filename, translation, fileLocator)
+}
+
+// Replace the items of a <plurals> element with the quantities of the translation.  The indentation of the
+// first item and of the end tag of the source are kept.  Returns the offset of the end tag
+func writeAndroidPlurals(out *bytes.Buffer, e *Entry, template []byte, decoder *xml.Decoder) (int64, error) {
+	start := decoder.InputOffset()
+	firstItem, lastItemEnd := int64(-1), start
+	depth := 0
+	for {
+		before := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err != nil {
+			return 0, err
+		}
+		switch token.(type) {
+		case xml.StartElement:
+			if depth == 0 && firstItem < 0 {
+				firstItem = before
+			}
+			depth++
+		case xml.EndElement:
+			if depth == 0 {
+				indent := "\n"
+				if firstItem >= 0 {
+					indent = string(template[start:firstItem])
+				}
+				for _, category := range e.PluralForms() {
+					if e.Plurals[category] != "" {
+						fmt.Fprintf(out, "%s<item quantity=\"%s\">%s</item>", indent, category, androidValue(e.Plurals[category], androidPluralFlags(e, category)))
+					}
+				}
+				out.Write(template[lastItemEnd:before])
+				return before, nil
+			}
+			if depth--; depth == 0 {
+				lastItemEnd = decoder.InputOffset()
+			}
+		}
+	}
+}
+
+// The flags of a quantity of a <plurals> element, recorded as quantity:flag.  A quantity without flags (one
+// that is not in the source file) is written with the flags of the other quantity
+func androidPluralFlags(e *Entry, category string) []string {
+	var flags []string
+	for _, quantity := range []string{category, "other"} {
+		for _, flag := range e.Flags {
+			if strings.HasPrefix(flag, quantity+":") {
+				flags = append(flags, strings.TrimPrefix(flag, quantity+":"))
+			}
+		}
+		if len(flags) > 0 {
+			break
+		}
+	}
+	return flags
+}
+
+func hasPluralTranslation(e *Entry) bool {
+	for _, value := range e.Plurals {
+		if value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAndroidArrayTranslation(c *Catalog, name string) bool {
+	for i := 0; ; i++ {
+		e := c.Find(fmt.Sprintf("%s[%d]", name, i))
+		if e == nil {
+			return false
+		}
+		if e.Value != "" {
+			return true
+		}
+	}
+}
+
+// call fn for each <item> child element of the current element, fn must consume the item.
+// Returns after the end of the current element
+func eachAndroidItem(decoder *xml.Decoder, fn func(item xml.StartElement) error) error {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "item" {
+				if err := decoder.Skip(); err != nil {
+					return err
+				}
+			} else if err := fn(t.Copy()); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// Read the value of the current element.  Returns the value without the android escapes and the flags
+// recording whether the value contains markup or is a CDATA section
+func readAndroidValue(decoder *xml.Decoder, content []byte) (string, []string, error) {
+	start := decoder.InputOffset()
+	var text bytes.Buffer
+	markup := false
+	depth := 0
+	for depth >= 0 {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			markup = true
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			text.Write(t)
+		}
+	}
+	raw := content[start:decoder.InputOffset()]
+	if end := bytes.LastIndex(raw, []byte("</")); end >= 0 {
+		raw = raw[:end]
+	} else {
+		raw = nil
+	}
+
+	if markup {
+		return androidUnescape(string(raw)), []string{androidMarkup}, nil
+	}
+	var flags []string
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("<![CDATA[")) {
+		flags = []string{androidCDATA}
+	}
+	return androidUnescape(text.String()), flags, nil
+}
+
+// Remove the android escapes from the value.  A value enclosed in double quotes is unquoted
+func androidUnescape(value string) string {
+	if trimmed := strings.TrimSpace(value); len(trimmed) >= 2 && trimmed[0] == '"' && trimmed[len(trimmed)-1] == '"' {
+		value = trimmed[1 : len(trimmed)-1]
+	}
+	var out bytes.Buffer
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i == len(value)-1 {
+			out.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case 'u':
+			if i+5 <= len(value) {
+				if r, err := strconv.ParseUint(value[i+1:i+5], 16, 32); err == nil {
+					out.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+			}
+			out.WriteByte('u')
+		default:
+			out.WriteByte(value[i])
+		}
+	}
+	return out.String()
+}
+
+// Encode the value as the content of a string element, as the value of the source was written
+func androidValue(value string, flags []string) string {
+	var escaped string
+	cdata := false
+	if hasFlag(flags, androidCDATA) || hasFlag(flags, androidMarkup) {
+		cdata = hasFlag(flags, androidCDATA)
+		escaped = escapeAndroidMarkup(value)
+	} else {
+		escaped = xmlTextEscaper.Replace(escapeAndroidText(value))
+	}
+	if strings.HasPrefix(value, "@") || strings.HasPrefix(value, "?") {
+		// would be a reference to another resource
+		escaped = `\` + escaped
+	}
+	if strings.TrimFunc(value, unicode.IsSpace) != value {
+		// leading and trailing whitespace is only kept by android in quoted strings
+		escaped = `"` + escaped + `"`
+	}
+	if cdata {
+		return "<![CDATA[" + escaped + "]]>"
+	}
+	return escaped
+}
+
+// escapes the text content of an element.  Unlike xml.EscapeText quotes and newlines are kept as they are
+var xmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escape the text between the tags of a value with markup, the tags are kept as they are
+func escapeAndroidMarkup(value string) string {
+	var out bytes.Buffer
+	for len(value) > 0 {
+		if value[0] == '<' {
+			end := strings.Index(value, ">")
+			if end < 0 {
+				end = len(value) - 1
+			}
+			out.WriteString(value[:end+1])
+			value = value[end+1:]
+			continue
+		}
+		end := strings.Index(value, "<")
+		if end < 0 {
+			end = len(value)
+		}
+		out.WriteString(escapeAndroidText(value[:end]))
+		value = value[end:]
+	}
+	return out.String()
+}
+
+var androidEscaper = strings.NewReplacer(`\`, `\\`, "'", `\'`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+
+func escapeAndroidText(value string) string {
+	return androidEscaper.Replace(value)
+}
+
+func hasFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package format
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	tu "testutil"
+)
+
+const androidSource = `<?xml version="1.0" encoding="utf-8"?>
+<resources xmlns:xliff="urn:oasis:names:tc:xliff:document:1.2">
+    <string name="app_name" translatable="false">Catalog</string>
+    <!-- The title of the main screen -->
+    <string name="title">Don\'t panic</string>
+    <string name="welcome">Hello <b><xliff:g id="name">%s</xliff:g></b>!</string>
+    <string name="html"><![CDATA[<a href="x">Click & go</a>]]></string>
+    <string name="quoted">"  spaces  "</string>
+    <string name="lines">One\nTwo &amp; three</string>
+    <string-array name="planets">
+        <item>Mercury</item>
+        <item>Venus</item>
+    </string-array>
+    <plurals name="files">
+        <item quantity="one">%d file</item>
+        <item quantity="other">%d files</item>
+    </plurals>
+    <string name="untranslated">Not translated</string>
+</resources>
+`
+
+func Test_Android_Parse(t *testing.T) {
+	c, err := new(Android).Parse([]byte(androidSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tu.AssertEqualsInt("entries", 9, len(c.Entries), t)
+	if c.Find("app_name") != nil {
+		t.Errorf("Strings that are not translatable must be skipped")
+	}
+	title := c.Find("title")
+	tu.AssertEquals("unescaped", "Don't panic", title.Value, t)
+	tu.AssertEquals("comment", "The title of the main screen", title.Comment, t)
+	tu.AssertEquals("comment of next", "", c.Find("welcome").Comment, t)
+	tu.AssertEquals("markup", `Hello <b><xliff:g id="name">%s</xliff:g></b>!`, c.Find("welcome").Value, t)
+	tu.AssertEquals("cdata", `<a href="x">Click & go</a>`, c.Find("html").Value, t)
+	tu.AssertEquals("quoted", "  spaces  ", c.Find("quoted").Value, t)
+	tu.AssertEquals("newline", "One\nTwo & three", c.Find("lines").Value, t)
+	tu.AssertEquals("array", "Mercury", c.Find("planets[0]").Value, t)
+	tu.AssertEquals("array", "Venus", c.Find("planets[1]").Value, t)
+
+	files := c.Find("files")
+	tu.AssertEquals("one", "%d file", files.Plurals["one"], t)
+	tu.AssertEquals("other", "%d files", files.Plurals["other"], t)
+}
+
+func Test_Android_Clean(t *testing.T) {
+	cleaned, i18n, err := new(Android).Clean([]byte(androidSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("i18n type", "STRUCTURED_JSON", i18n, t)
+
+	var data map[string]map[string]string
+	json.Unmarshal(cleaned, &data)
+	tu.AssertEquals("plural", "{cnt, plural, one {%d file} other {%d files}}", data["files"]["string"], t)
+	tu.AssertEquals("comment", "The title of the main screen", data["title"]["developer_comment"], t)
+}
+
+func Test_Android_Write(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	createFile(tmpDir, "values", "strings.xml", t)
+	ioutil.WriteFile(filepath.Join(tmpDir, "values", "strings.xml"), []byte(androidSource), 0644)
+
+	translation := map[string]string{
+		"title":      "Не паникуй, это \"важно\"",
+		"welcome":    `Привет <b><xliff:g id="name">%s</xliff:g></b>!`,
+		"html":       `<a href="x">Нажми & иди</a>`,
+		"quoted":     " пробелы ",
+		"lines":      "Один\nДва & три",
+		"planets[0]": "Меркурий",
+		"planets[1]": "Венера",
+		"files":      "{cnt, plural, one {%d файл} few {%d файла} many {%d файлов} other {%d файла}}"}
+	data, _ := json.Marshal(translation)
+
+	f := new(Android)
+	locator := FileLocators["ANDROID"]
+	if err := f.Write(tmpDir, "ru", "en", "strings", string(data), locator); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?xml version="1.0" encoding="utf-8"?>
+<resources xmlns:xliff="urn:oasis:names:tc:xliff:document:1.2">
+    <!-- The title of the main screen -->
+    <string name="title">Не паникуй, это \"важно\"</string>
+    <string name="welcome">Привет <b><xliff:g id="name">%s</xliff:g></b>!</string>
+    <string name="html"><![CDATA[<a href="x">Нажми & иди</a>]]></string>
+    <string name="quoted">" пробелы "</string>
+    <string name="lines">Один\nДва &amp; три</string>
+    <string-array name="planets">
+        <item>Меркурий</item>
+        <item>Венера</item>
+    </string-array>
+    <plurals name="files">
+        <item quantity="one">%d файл</item>
+        <item quantity="few">%d файла</item>
+        <item quantity="many">%d файлов</item>
+        <item quantity="other">%d файла</item>
+    </plurals>
+</resources>
+`
+	written := mustRead(filepath.Join(tmpDir, "values-ru", "strings.xml"))
+	tu.AssertEquals("", expected, string(written), t)
+
+	// the source file is never overwritten
+	if err := f.Write(tmpDir, "en", "en", "strings", string(data), locator); err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("source", androidSource, string(mustRead(filepath.Join(tmpDir, "values", "strings.xml"))), t)
+}
+
+func Test_Android_Write_PluralMarkup(t *testing.T) {
+	source := `<resources>
+    <plurals name="files">
+        <item quantity="one"><b>%d</b> file</item>
+        <item quantity="other"><![CDATA[<b>%d</b> files & more]]></item>
+    </plurals>
+</resources>
+`
+	c, err := new(Android).Parse([]byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("markup", "<b>%d</b> file", c.Find("files").Plurals["one"], t)
+
+	tmpDir, _ := ioutil.TempDir("", "test")
+	createFile(tmpDir, "values", "strings.xml", t)
+	ioutil.WriteFile(filepath.Join(tmpDir, "values", "strings.xml"), []byte(source), 0644)
+	data, _ := json.Marshal(map[string]string{
+		"files": "{cnt, plural, one {<b>%d</b> файл} few {<b>%d</b> файла & др.} other {<b>%d</b> файлов}}"})
+	if err := new(Android).Write(tmpDir, "ru", "en", "strings", string(data), FileLocators["ANDROID"]); err != nil {
+		t.Fatal(err)
+	}
+
+	// few is not in the source, it is written like other
+	expected := `<resources>
+    <plurals name="files">
+        <item quantity="one"><b>%d</b> файл</item>
+        <item quantity="few"><![CDATA[<b>%d</b> файла & др.]]></item>
+        <item quantity="other"><![CDATA[<b>%d</b> файлов]]></item>
+    </plurals>
+</resources>
+`
+	tu.AssertEquals("", expected, string(mustRead(filepath.Join(tmpDir, "values-ru", "strings.xml"))), t)
+}
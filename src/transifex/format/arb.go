@@ -0,0 +1,92 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Flutter application resource bundles (.arb): json files with the messages, the @key metadata of the messages
+// (description, placeholders ...) and the @@locale of the file.  Only the messages are uploaded, with their description
+// as the comment.  The ICU messages (plural, select) are uploaded as they are.
+//
+// Translations are written with the source file as the template: the messages are translated, the @key metadata of the
+// translated messages is copied from the source file and @@locale is the language of the translation.  Messages without a
+// translation are left out so that Flutter uses the message of the template.  Use it with the RESOURCE-BUNDLE structure
+// for the app_en.arb, app_fr.arb ... files
+type Arb struct{}
+
+func (f *Arb) Init(initParams map[string]interface{}) {}
+func (f *Arb) Ext() string                            { return "arb" }
+
+const arbLocale = "@@locale"
+
+func (f *Arb) Parse(content []byte) (*Catalog, error) {
+	root, err := parseJsonValue(content)
+	if err != nil {
+		return nil, err
+	}
+	if root.kind != '{' {
+		return nil, fmt.Errorf("Not valid json: expected a json object")
+	}
+	c := NewCatalog(root.get(arbLocale).str())
+	for _, m := range root.members {
+		if strings.HasPrefix(m.key, "@") || !isJsonString(m.value) {
+			continue
+		}
+		c.Add(&Entry{Key: m.key, Value: m.value.str(), Comment: root.get("@" + m.key).get("description").str()})
+	}
+	return c, nil
+}
+
+func (f *Arb) Clean(content []byte) ([]byte, string, error) {
+	return cleanToStructuredJson(f, content)
+}
+
+func (f *Arb) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	root := newJsonObject()
+	if template != nil {
+		var err error
+		if root, err = parseJsonValue(template); err != nil {
+			return nil, err
+		}
+		if root.kind != '{' {
+			return nil, fmt.Errorf("Not valid json: expected a json object")
+		}
+	}
+
+	translated := newJsonObject()
+	translated.set(arbLocale, newJsonString(c.Lang))
+	if template == nil {
+		for _, e := range c.Entries {
+			if e.Value != "" {
+				translated.set(e.Key, newJsonString(e.Value))
+			}
+		}
+	}
+	for _, m := range root.members {
+		switch {
+		case m.key == arbLocale:
+		case strings.HasPrefix(m.key, "@@"):
+			// the global attributes of the file: @@last_modified, @@author ...
+			translated.set(m.key, m.value)
+		case strings.HasPrefix(m.key, "@"):
+			if e := c.Find(m.key[1:]); e != nil && e.Value != "" {
+				translated.set(m.key, m.value)
+			}
+		case isJsonString(m.value):
+			if e := c.Find(m.key); e != nil && e.Value != "" {
+				translated.set(m.key, newJsonString(e.Value))
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	translated.write(&out, jsonIndent(template), "", jsonColon(template))
+	out.WriteString("\n")
+	return out.Bytes(), nil
+}
+
+func (f *Arb) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	return writeCatalog(f, rootDir, langCode, srcLang, filename, translation, fileLocator)
+}
@@ -0,0 +1,101 @@
+package format
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	tu "testutil"
+)
+
+const arbSource = `{
+  "@@locale": "en",
+  "@@last_modified": "2026-10-01T10:00:00Z",
+  "title": "My app",
+  "@title": {
+    "description": "The title of the application"
+  },
+  "greeting": "Hello {name}",
+  "@greeting": {
+    "description": "Greets the user",
+    "placeholders": {
+      "name": {
+        "type": "String",
+        "example": "Bob"
+      }
+    }
+  },
+  "items": "{count, plural, =0{No items} one{1 item} other{{count} items}}",
+  "@items": {
+    "placeholders": {
+      "count": {
+        "type": "int"
+      }
+    }
+  }
+}
+`
+
+func Test_Arb_Parse(t *testing.T) {
+	c, err := new(Arb).Parse([]byte(arbSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("locale", "en", c.Lang, t)
+	tu.AssertEqualsInt("entries", 3, len(c.Entries), t)
+	tu.AssertEquals("message", "Hello {name}", c.Find("greeting").Value, t)
+	tu.AssertEquals("description", "The title of the application", c.Find("title").Comment, t)
+	tu.AssertEquals("icu message", "{count, plural, =0{No items} one{1 item} other{{count} items}}", c.Find("items").Value, t)
+	tu.AssertEquals("no description", "", c.Find("items").Comment, t)
+}
+
+func Test_Arb_Clean(t *testing.T) {
+	cleaned, i18n, err := new(Arb).Clean([]byte(arbSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("i18n type", "STRUCTURED_JSON", i18n, t)
+
+	var data map[string]map[string]string
+	json.Unmarshal(cleaned, &data)
+	tu.AssertEqualsInt("metadata is not uploaded", 3, len(data), t)
+	tu.AssertEquals("comment", "Greets the user", data["greeting"]["developer_comment"], t)
+}
+
+func Test_Arb_Write(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	ioutil.WriteFile(filepath.Join(tmpDir, "app_en.arb"), []byte(arbSource), 0644)
+
+	data, _ := json.Marshal(map[string]string{
+		"greeting": "Bonjour {name}",
+		"items":    "{count, plural, =0{Aucun élément} one{1 élément} other{{count} éléments}}"})
+
+	if err := new(Arb).Write(tmpDir, "fr", "en", "app", string(data), FileLocators["RESOURCE-BUNDLE"]); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{
+  "@@locale": "fr",
+  "@@last_modified": "2026-10-01T10:00:00Z",
+  "greeting": "Bonjour {name}",
+  "@greeting": {
+    "description": "Greets the user",
+    "placeholders": {
+      "name": {
+        "type": "String",
+        "example": "Bob"
+      }
+    }
+  },
+  "items": "{count, plural, =0{Aucun élément} one{1 élément} other{{count} éléments}}",
+  "@items": {
+    "placeholders": {
+      "count": {
+        "type": "int"
+      }
+    }
+  }
+}
+`
+	tu.AssertEquals("", expected, string(mustRead(filepath.Join(tmpDir, "app_fr.arb"))), t)
+}
@@ -0,0 +1,403 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The CLDR plural categories in their canonical order
+var PluralCategories = []string{"zero", "one", "two", "few", "many", "other"}
+
+// separates the context from the key in the id of an entry (the same convention as gettext)
+const contextSeparator = "\u0004"
+
+// A format independent representation of the strings of a translation file.  Formats parse their files
+// into a Catalog and serialize a Catalog back to their files so that validation, diffing and conversion
+// only need to be written once.
+type Catalog struct {
+	// the language of the strings
+	Lang string
+	// a comment on the whole file, for example the comment before the gettext header
+	Comment string
+	// format specific metadata of the file in the order it appears in the file.  For example the gettext header fields
+	Meta []MetaField
+	// the entries in the order they appear in the file
+	Entries []*Entry
+
+	// the position of the entries by id and the number of entries it covers
+	index   map[string]int
+	indexed int
+}
+
+type MetaField struct {
+	Key, Value string
+}
+
+// A single translatable string
+type Entry struct {
+	Key     string
+	Value   string
+	Context string
+	// a note for translators
+	Comment string
+	// a comment written by a translator
+	TranslatorComment string
+	// the locations in the source code where the string is used
+	References []string
+	// the plural forms of the value keyed by CLDR plural category.  Empty if the string has no plural forms
+	Plurals map[string]string
+	// the key of the plural form for formats where the key is the source text (gettext msgid_plural)
+	PluralKey string
+	// format specific flags.  For example fuzzy in gettext
+	Flags []string
+}
+
+func NewCatalog(lang string) *Catalog {
+	return &Catalog{Lang: lang, Entries: []*Entry{}}
+}
+
+// the unique id of the entry in the catalog: the key, prefixed by the context if there is one
+func (e *Entry) ID() string {
+	if e.Context == "" {
+		return e.Key
+	}
+	return e.Context + contextSeparator + e.Key
+}
+
+func (e *Entry) IsPlural() bool {
+	return len(e.Plurals) > 0
+}
+
+// The plural categories of the entry in canonical order
+func (e *Entry) PluralForms() []string {
+	forms := []string{}
+	for _, category := range PluralCategories {
+		if _, has := e.Plurals[category]; has {
+			forms = append(forms, category)
+		}
+	}
+	return forms
+}
+
+func (e *Entry) HasFlag(flag string) bool {
+	for _, f := range e.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Entry) copy() *Entry {
+	c := *e
+	if e.Plurals != nil {
+		c.Plurals = make(map[string]string, len(e.Plurals))
+		for k, v := range e.Plurals {
+			c.Plurals[k] = v
+		}
+	}
+	c.Flags = append([]string(nil), e.Flags...)
+	c.References = append([]string(nil), e.References...)
+	return &c
+}
+
+// Remove the flag from the entry if it is present
+func (e *Entry) RemoveFlag(flag string) {
+	flags := []string{}
+	for _, f := range e.Flags {
+		if f != flag {
+			flags = append(flags, f)
+		}
+	}
+	e.Flags = flags
+}
+
+// Returns the value of the metadata field
+func (c *Catalog) GetMeta(key string) (string, bool) {
+	for _, m := range c.Meta {
+		if m.Key == key {
+			return m.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set the value of the metadata field, adding it at the end if it does not exist
+func (c *Catalog) SetMeta(key, value string) {
+	for i, m := range c.Meta {
+		if m.Key == key {
+			c.Meta[i].Value = value
+			return
+		}
+	}
+	c.Meta = append(c.Meta, MetaField{key, value})
+}
+
+// Add an entry to the end of the catalog
+func (c *Catalog) Add(e *Entry) {
+	c.Entries = append(c.Entries, e)
+	if c.index != nil && c.indexed == len(c.Entries)-1 {
+		c.index[e.ID()] = c.indexed
+		c.indexed++
+	}
+}
+
+// Returns the entry with the id or nil
+func (c *Catalog) Find(id string) *Entry {
+	if c.index == nil || c.indexed != len(c.Entries) {
+		c.index = make(map[string]int, len(c.Entries))
+		for i, e := range c.Entries {
+			c.index[e.ID()] = i
+		}
+		c.indexed = len(c.Entries)
+	}
+	if i, has := c.index[id]; has {
+		return c.Entries[i]
+	}
+	return nil
+}
+
+// Creates a catalog in the language of translations with the entries of c (the source catalog) and the values of translations.
+// Entries without a translation have an empty value.  The ids of translations that are not in c are returned as unknown
+func (c *Catalog) Translate(translations *Catalog) (translated *Catalog, unknown []string) {
+	translated = NewCatalog(translations.Lang)
+	translated.Comment = c.Comment
+	translated.Meta = append([]MetaField(nil), c.Meta...)
+	for _, e := range c.Entries {
+		t := e.copy()
+		t.Value = ""
+		t.Plurals = nil
+		if tr := translations.Find(e.ID()); tr != nil {
+			t.Value = tr.Value
+			if tr.IsPlural() {
+				t.Plurals = tr.copy().Plurals
+			} else if e.IsPlural() {
+				if plurals, ok := decodeICUPlural(tr.Value); ok {
+					t.Value = ""
+					t.Plurals = plurals
+				}
+			}
+		}
+		if e.IsPlural() && t.Plurals == nil {
+			t.Plurals = map[string]string{}
+			for category := range e.Plurals {
+				t.Plurals[category] = t.Value
+			}
+			t.Value = ""
+		}
+		translated.Add(t)
+	}
+
+	for _, tr := range translations.Entries {
+		if c.Find(tr.ID()) == nil {
+			unknown = append(unknown, tr.ID())
+		}
+	}
+	return translated, unknown
+}
+
+// The value of the entry as a single string.  Plural forms are encoded as an ICU plural message
+func (e *Entry) FlatValue() string {
+	if !e.IsPlural() {
+		return e.Value
+	}
+	return encodeICUPlural(e)
+}
+
+// The catalog as a map of key to (flat) value.  Context is ignored
+func (c *Catalog) KeyValues() map[string]string {
+	data := make(map[string]string, len(c.Entries))
+	for _, e := range c.Entries {
+		data[e.Key] = e.FlatValue()
+	}
+	return data
+}
+
+// an entry of a transifex STRUCTURED_JSON file
+type structuredJsonEntry struct {
+	String           string `json:"string"`
+	Context          string `json:"context,omitempty"`
+	DeveloperComment string `json:"developer_comment,omitempty"`
+}
+
+// Encode the catalog as a transifex STRUCTURED_JSON file, which keeps the comment and context of each entry.
+// The keys are the ids of the entries
+func EncodeStructuredJson(c *Catalog) ([]byte, error) {
+	data := make(map[string]structuredJsonEntry, len(c.Entries))
+	for _, e := range c.Entries {
+		data[e.ID()] = structuredJsonEntry{e.FlatValue(), e.Context, e.Comment}
+	}
+	return marshalJson(data)
+}
+
+// Decode the content of a translation downloaded from transifex.  Both KEYVALUEJSON
+// (string values) and STRUCTURED_JSON (object values) content is supported
+func DecodeTranslation(content []byte, lang string) (*Catalog, error) {
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("The translation is not valid json: %s", err)
+	}
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	c := NewCatalog(lang)
+	for _, id := range keys {
+		e := &Entry{Key: id}
+		if i := strings.Index(id, contextSeparator); i >= 0 {
+			e.Context, e.Key = id[:i], id[i+len(contextSeparator):]
+		}
+
+		var value string
+		if err := json.Unmarshal(data[id], &value); err == nil {
+			e.Value = value
+		} else {
+			var structured structuredJsonEntry
+			if err := json.Unmarshal(data[id], &structured); err != nil {
+				return nil, fmt.Errorf("Unsupported value of %q in translation: %s", id, data[id])
+			}
+			e.Value = structured.String
+			e.Comment = structured.DeveloperComment
+		}
+		c.Add(e)
+	}
+	return c, nil
+}
+
+// Clean the content by parsing it with the format and encoding the catalog as STRUCTURED_JSON
+func cleanToStructuredJson(f Format, content []byte) ([]byte, string, error) {
+	c, err := f.Parse(content)
+	if err != nil {
+		return nil, "", err
+	}
+	cleaned, err := EncodeStructuredJson(c)
+	if err != nil {
+		return nil, "", err
+	}
+	return cleaned, "STRUCTURED_JSON", nil
+}
+
+// The Write implementation shared by the formats: the source language file is parsed and translated with
+// the downloaded translation, then serialized using the source file as the template
+func writeCatalog(f Format, rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	path := fileLocator.Find(rootDir, langCode, filename, f.Ext())
+	fmt.Println("Updating translations file: " + path)
+
+	translated, template, err := translateCatalog(f, rootDir, langCode, srcLang, filename, translation, fileLocator)
+	if err != nil {
+		return err
+	}
+
+	out, err := f.Serialize(translated, template)
+	if err != nil {
+		return err
+	}
+	return writeTranslationFile(path, out)
+}
+
+// Parse the source language file and translate it with the downloaded translation.
+// Returns the translated catalog and the content of the source file
+func translateCatalog(f Format, rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) (*Catalog, []byte, error) {
+	translations, err := DecodeTranslation([]byte(translation), langCode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template, err := ioutil.ReadFile(fileLocator.Find(rootDir, srcLang, filename, f.Ext()))
+	if err != nil {
+		return nil, nil, err
+	}
+	source, err := f.Parse(template)
+	if err != nil {
+		return nil, nil, err
+	}
+	source.Lang = srcLang
+
+	translated, unknown := source.Translate(translations)
+	if len(unknown) > 0 {
+		return nil, nil, fmt.Errorf("One or more translations did not have a matching key in the source file: [%s]", strings.Join(unknown, ", "))
+	}
+	return translated, template, nil
+}
+
+func writeTranslationFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
+
+// json.Marshal without escaping html characters, translations often contain markup
+func marshalJson(v interface{}) ([]byte, error) {
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(out.Bytes(), "\n"), nil
+}
+
+// Encode the plural forms of the entry as an ICU plural message: {cnt, plural, one {...} other {...}}
+func encodeICUPlural(e *Entry) string {
+	var out bytes.Buffer
+	out.WriteString("{cnt, plural,")
+	for _, category := range e.PluralForms() {
+		fmt.Fprintf(&out, " %s {%s}", category, e.Plurals[category])
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+// Decode an ICU plural message.  Returns false if the message is not a plural message
+func decodeICUPlural(message string) (map[string]string, bool) {
+	message = strings.TrimSpace(message)
+	if !strings.HasPrefix(message, "{") || !strings.HasSuffix(message, "}") {
+		return nil, false
+	}
+	parts := strings.SplitN(message[1:len(message)-1], ",", 3)
+	if len(parts) != 3 || strings.TrimSpace(parts[1]) != "plural" {
+		return nil, false
+	}
+
+	plurals := map[string]string{}
+	rest := parts[2]
+	for {
+		rest = strings.TrimLeft(rest, " \t\n")
+		if rest == "" {
+			break
+		}
+		open := strings.Index(rest, "{")
+		if open <= 0 {
+			return nil, false
+		}
+		category := strings.TrimSpace(rest[:open])
+		depth, end := 0, -1
+		for i := open; i < len(rest) && end < 0; i++ {
+			switch rest[i] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth == 0 {
+					end = i
+				}
+			}
+		}
+		if end < 0 {
+			return nil, false
+		}
+		plurals[category] = rest[open+1 : end]
+		rest = rest[end+1:]
+	}
+	return plurals, len(plurals) > 0
+}
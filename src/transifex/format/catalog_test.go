@@ -0,0 +1,127 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	tu "testutil"
+)
+
+func Test_Catalog_Translate(t *testing.T) {
+	source := NewCatalog("en")
+	source.Add(&Entry{Key: "title", Value: "Title", Comment: "page title"})
+	source.Add(&Entry{Key: "open", Value: "Open", Context: "menu"})
+	source.Add(&Entry{Key: "open", Value: "Open", Context: "door"})
+	source.Add(&Entry{Key: "items", Plurals: map[string]string{"one": "# item", "other": "# items"}})
+	source.Add(&Entry{Key: "missing", Value: "Missing"})
+
+	translations := NewCatalog("fr")
+	translations.Add(&Entry{Key: "title", Value: "Titre"})
+	translations.Add(&Entry{Key: "open", Value: "Ouvrir", Context: "menu"})
+	translations.Add(&Entry{Key: "open", Value: "Ouverte", Context: "door"})
+	translations.Add(&Entry{Key: "items", Value: "{cnt, plural, one {# élément} other {# éléments}}"})
+	translations.Add(&Entry{Key: "extra", Value: "Extra"})
+
+	translated, unknown := source.Translate(translations)
+
+	tu.AssertEquals("lang", "fr", translated.Lang, t)
+	tu.AssertEqualsInt("entries", 5, len(translated.Entries), t)
+	tu.AssertEquals("title", "Titre", translated.Entries[0].Value, t)
+	tu.AssertEquals("comment", "page title", translated.Entries[0].Comment, t)
+	tu.AssertEquals("menu context", "Ouvrir", translated.Find("menu\u0004open").Value, t)
+	tu.AssertEquals("door context", "Ouverte", translated.Find("door\u0004open").Value, t)
+	tu.AssertEquals("plural one", "# élément", translated.Entries[3].Plurals["one"], t)
+	tu.AssertEquals("plural other", "# éléments", translated.Entries[3].Plurals["other"], t)
+	tu.AssertEquals("missing", "", translated.Entries[4].Value, t)
+	if len(unknown) != 1 || unknown[0] != "extra" {
+		t.Errorf("Expected extra to be unknown: %v", unknown)
+	}
+	tu.AssertEquals("source unchanged", "Title", source.Entries[0].Value, t)
+}
+
+func Test_ICUPlural(t *testing.T) {
+	e := &Entry{Key: "files", Plurals: map[string]string{"other": "{n} files", "one": "one file", "few": "{n} soubory"}}
+	encoded := e.FlatValue()
+	tu.AssertEquals("encoded", "{cnt, plural, one {one file} few {{n} soubory} other {{n} files}}", encoded, t)
+
+	decoded, ok := decodeICUPlural(encoded)
+	if !ok {
+		t.Fatalf("Failed to decode %s", encoded)
+	}
+	for category, value := range e.Plurals {
+		tu.AssertEquals(category, value, decoded[category], t)
+	}
+
+	if _, ok := decodeICUPlural("{name} is not a plural"); ok {
+		t.Errorf("A placeholder is not a plural message")
+	}
+}
+
+func Test_StructuredJson_RoundTrip(t *testing.T) {
+	c := NewCatalog("en")
+	c.Add(&Entry{Key: "greeting", Value: "<b>Hello</b>", Comment: "shown on login"})
+	c.Add(&Entry{Key: "open", Value: "Open", Context: "menu"})
+
+	encoded, err := EncodeStructuredJson(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var raw map[string]map[string]string
+	if err := json.Unmarshal(encoded, &raw); err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("comment", "shown on login", raw["greeting"]["developer_comment"], t)
+	tu.AssertEquals("context", "menu", raw["menu\u0004open"]["context"], t)
+
+	decoded, err := DecodeTranslation(encoded, "fr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("value", "<b>Hello</b>", decoded.Find("greeting").Value, t)
+	tu.AssertEquals("context value", "Open", decoded.Find("menu\u0004open").Value, t)
+
+	decoded, err = DecodeTranslation([]byte(`{"greeting": "Bonjour"}`), "fr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("key value json", "Bonjour", decoded.Find("greeting").Value, t)
+}
+
+func Test_Catalog_Find(t *testing.T) {
+	c := NewCatalog("en")
+	// parsers alternate Add and Find: the index is kept up to date instead of being rebuilt
+	for i := 0; i < 20000; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if c.Find(key) != nil {
+			t.Fatalf("%s found before it was added", key)
+		}
+		c.Add(&Entry{Key: key, Value: key})
+	}
+	tu.AssertEquals("first", "key0", c.Find("key0").Value, t)
+	tu.AssertEquals("last", "key19999", c.Find("key19999").Value, t)
+
+	// the last entry with an id is found, also when the entries were changed without Add
+	c.Add(&Entry{Key: "key1", Value: "again"})
+	tu.AssertEquals("duplicate", "again", c.Find("key1").Value, t)
+	c.Entries = append(c.Entries, &Entry{Key: "appended", Value: "appended"})
+	tu.AssertEquals("appended", "appended", c.Find("appended").Value, t)
+	c.Entries = c.Entries[:1]
+	if c.Find("key1") != nil {
+		t.Errorf("Expected removed entries not to be found")
+	}
+}
+
+func Benchmark_KeyValueJson_Parse(b *testing.B) {
+	strings := map[string]string{}
+	for i := 0; i < 20000; i++ {
+		strings[fmt.Sprintf("key%d", i)] = fmt.Sprintf("Value %d", i)
+	}
+	content, _ := json.Marshal(strings)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Formats["KEYVALUEJSON"]().Parse(content); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Chrome extension messages (_locales/<lang>/messages.json): an object of the messages keyed by name, each message has a
+// message, a description for the translators and the placeholders of the message.  The message is uploaded with the
+// description as the comment.
+//
+// Translations are written with the source file as the template: the description and placeholders of each translated
+// message are copied from the source file.  Messages without a translation are left out so that the browser uses the
+// message of the default locale.  Use it with the CHROME structure
+type Chrome struct{}
+
+func (f *Chrome) Init(initParams map[string]interface{}) {}
+func (f *Chrome) Ext() string                            { return "json" }
+
+func (f *Chrome) Parse(content []byte) (*Catalog, error) {
+	root, err := parseChromeMessages(content)
+	if err != nil {
+		return nil, err
+	}
+	c := NewCatalog("")
+	for _, m := range root.members {
+		if message := m.value.get("message"); isJsonString(message) {
+			c.Add(&Entry{Key: m.key, Value: message.str(), Comment: m.value.get("description").str()})
+		}
+	}
+	return c, nil
+}
+
+func (f *Chrome) Clean(content []byte) ([]byte, string, error) {
+	return cleanToStructuredJson(f, content)
+}
+
+func (f *Chrome) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	root := newJsonObject()
+	if template != nil {
+		var err error
+		if root, err = parseChromeMessages(template); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, e := range c.Entries {
+			message := newJsonObject()
+			message.set("message", newJsonString(""))
+			if e.Comment != "" {
+				message.set("description", newJsonString(e.Comment))
+			}
+			root.set(e.Key, message)
+		}
+	}
+
+	translated := newJsonObject()
+	for _, m := range root.members {
+		if !isJsonString(m.value.get("message")) {
+			continue
+		}
+		if e := c.Find(m.key); e != nil && e.Value != "" {
+			m.value.set("message", newJsonString(e.Value))
+			translated.set(m.key, m.value)
+		}
+	}
+
+	var out bytes.Buffer
+	translated.write(&out, jsonIndent(template), "", jsonColon(template))
+	out.WriteString("\n")
+	return out.Bytes(), nil
+}
+
+func (f *Chrome) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	return writeCatalog(f, rootDir, langCode, srcLang, filename, translation, fileLocator)
+}
+
+func parseChromeMessages(content []byte) (*jsonValue, error) {
+	root, err := parseJsonValue(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")))
+	if err != nil {
+		return nil, err
+	}
+	if root.kind != '{' {
+		return nil, fmt.Errorf("Not valid json: expected a json object")
+	}
+	return root, nil
+}
@@ -0,0 +1,79 @@
+package format
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	tu "testutil"
+)
+
+const chromeSource = `{
+  "extName": {
+    "message": "My extension",
+    "description": "The name of the extension"
+  },
+  "greeting": {
+    "message": "Hello $user$",
+    "description": "Greets the user",
+    "placeholders": {
+      "user": {
+        "content": "$1",
+        "example": "Bob"
+      }
+    }
+  },
+  "close": {
+    "message": "Close"
+  }
+}
+`
+
+func Test_Chrome_Parse(t *testing.T) {
+	c, err := new(Chrome).Parse([]byte(chromeSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("entries", 3, len(c.Entries), t)
+	tu.AssertEquals("message", "Hello $user$", c.Find("greeting").Value, t)
+	tu.AssertEquals("description", "The name of the extension", c.Find("extName").Comment, t)
+	tu.AssertEquals("no description", "", c.Find("close").Comment, t)
+
+	if _, err = new(Chrome).Parse([]byte(`["not", "messages"]`)); err == nil {
+		t.Errorf("Expected an error for a json array")
+	}
+}
+
+func Test_Chrome_Write(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	os.MkdirAll(filepath.Join(tmpDir, "_locales", "en"), 0755)
+	ioutil.WriteFile(filepath.Join(tmpDir, "_locales", "en", "messages.json"), []byte(chromeSource), 0644)
+
+	data, _ := json.Marshal(map[string]string{
+		"extName":  "Minha extensão",
+		"greeting": "Olá $user$"})
+
+	if err := new(Chrome).Write(tmpDir, "pt_BR", "en", "messages", string(data), FileLocators["CHROME"]); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{
+  "extName": {
+    "message": "Minha extensão",
+    "description": "The name of the extension"
+  },
+  "greeting": {
+    "message": "Olá $user$",
+    "description": "Greets the user",
+    "placeholders": {
+      "user": {
+        "content": "$1",
+        "example": "Bob"
+      }
+    }
+  }
+}
+`
+	tu.AssertEquals("", expected, string(mustRead(filepath.Join(tmpDir, "_locales", "pt_BR", "messages.json"))), t)
+}
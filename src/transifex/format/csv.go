@@ -0,0 +1,83 @@
+package format
+
+import (
+	"bytes"
+	"encoding/csv"
+	"unicode/utf8"
+)
+
+// CSV files with a key, a value and an optional comment column (see tableColumns for the extra parameters
+// of the columns).  The strings of the value column are uploaded with the comment column as their comment.
+//
+// Translations are written with the source file as the template: the value column is replaced by the translations,
+// empty for the strings without a translation, and the other columns are kept as they are.
+//
+// Extra parameters:
+// * delimiter - the separator of the fields, , by default.  Use tab for tab separated files
+type Csv struct {
+	delimiter rune
+	columns   tableColumns
+}
+
+func (f *Csv) Init(initParams map[string]interface{}) {
+	f.delimiter = ','
+	if delimiter, has := initParams["delimiter"].(string); has && delimiter != "" {
+		if delimiter == "tab" {
+			delimiter = "\t"
+		}
+		f.delimiter, _ = utf8.DecodeRuneInString(delimiter)
+	}
+	f.columns.init(initParams)
+}
+
+func (f *Csv) Ext() string { return "csv" }
+
+func (f *Csv) Parse(content []byte) (*Catalog, error) {
+	rows, err := f.read(content)
+	if err != nil {
+		return nil, err
+	}
+	return f.columns.catalog(rows, f.columns.value, false)
+}
+
+func (f *Csv) Clean(content []byte) ([]byte, string, error) {
+	return cleanToStructuredJson(f, content)
+}
+
+func (f *Csv) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	rows := f.columns.newRows(c, f.columns.value)
+	if template != nil {
+		var err error
+		if rows, err = f.read(template); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.columns.translate(rows, c, f.columns.value); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if bytes.HasPrefix(template, utf8BOM) {
+		// excel needs the byte order mark to read utf-8
+		out.Write(utf8BOM)
+	}
+	writer := csv.NewWriter(&out)
+	writer.Comma = f.delimiter
+	writer.UseCRLF = bytes.Contains(template, []byte("\r\n"))
+	writer.WriteAll(rows)
+	return out.Bytes(), writer.Error()
+}
+
+func (f *Csv) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	return writeCatalog(f, rootDir, langCode, srcLang, filename, translation, fileLocator)
+}
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+func (f *Csv) read(content []byte) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(content, utf8BOM)))
+	reader.Comma = f.delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return reader.ReadAll()
+}
@@ -0,0 +1,65 @@
+package format
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	tu "testutil"
+)
+
+func Test_Csv_Parse(t *testing.T) {
+	f := new(Csv)
+	f.Init(map[string]interface{}{"delimiter": ";", "key": "ID", "value": "English", "comment": "Notes"})
+	c, err := f.Parse([]byte("\xef\xbb\xbfID;Notes;English\r\ntitle;Page title;\"Welcome; friend\"\r\n;;no key\r\nbody;;\"Say \"\"hi\"\"\nagain\"\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("entries", 2, len(c.Entries), t)
+	tu.AssertEquals("quoted", "Welcome; friend", c.Find("title").Value, t)
+	tu.AssertEquals("comment", "Page title", c.Find("title").Comment, t)
+	tu.AssertEquals("multi line", "Say \"hi\"\nagain", c.Find("body").Value, t)
+
+	f = new(Csv)
+	f.Init(map[string]interface{}{"key": 1.0, "value": 3.0, "header": false})
+	c, err = f.Parse([]byte("a,x,A\nb,y,B\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("column numbers", "B", c.Find("b").Value, t)
+
+	f = new(Csv)
+	f.Init(map[string]interface{}{})
+	if _, err = f.Parse([]byte("id,text\na,b\n")); err == nil {
+		t.Errorf("Expected an error for a file without a key column")
+	}
+}
+
+func Test_Csv_Write(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	ioutil.WriteFile(filepath.Join(tmpDir, "en-copy.csv"), []byte("\xef\xbb\xbfkey\tvalue\tcomment\r\ntitle\tWelcome\tPage title\r\nbody\tHello\t\r\n"), 0644)
+
+	data, _ := json.Marshal(map[string]string{"title": "Bienvenue, \"ami\""})
+
+	f := new(Csv)
+	f.Init(map[string]interface{}{"delimiter": "tab"})
+	if err := f.Write(tmpDir, "fr", "en", "copy", string(data), FileLocators["LANG-NAME"]); err != nil {
+		t.Fatal(err)
+	}
+	expected := "\xef\xbb\xbfkey\tvalue\tcomment\r\ntitle\t\"Bienvenue, \"\"ami\"\"\"\tPage title\r\nbody\t\t\r\n"
+	tu.AssertEquals("", expected, string(mustRead(filepath.Join(tmpDir, "fr-copy.csv"))), t)
+}
+
+func Test_Csv_Serialize_NoTemplate(t *testing.T) {
+	c := NewCatalog("fr")
+	c.Add(&Entry{Key: "title", Value: "Titre", Comment: "Page title"})
+	c.Add(&Entry{Key: "body", Value: "Corps"})
+
+	f := new(Csv)
+	f.Init(map[string]interface{}{"value": "text"})
+	out, err := f.Serialize(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("", "key,text,comment\ntitle,Titre,Page title\nbody,Corps,\n", string(out), t)
+}
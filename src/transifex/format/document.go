@@ -0,0 +1,1023 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Documents: Markdown (.md) and HTML (.html) pages segmented into translatable units: the paragraphs, headings, list
+// items and table cells, and the alt, title, placeholder and aria-label attributes.  Code blocks, front matter,
+// scripts and styles are not uploaded.
+//
+// The keys are stable within the sections of the document: the slug of a heading is its key and the units after it are
+// keyed by the heading and their kind and number in the section (installation.p2, installation.li1,
+// installation.alt1).  The units before the first heading have no prefix and an HTML element with an id is keyed by
+// its id.  Inline markup (emphasis, links, inline html tags) and code spans are uploaded as placeholders: {1} for a
+// tag or code span and {1}...{/1} around the text of an element.  Text of the source that looks like a placeholder
+// is escaped with a backslash: \{1}.
+//
+// Like FLATTENXMLTOJSON, translations are written by replaying the source document: the text of each translated unit
+// is replaced by its translation with the placeholders replaced by the markup of the source.  Units without a
+// translation, or with a translation that does not have each placeholder of the source once and nested like the
+// source, keep the source text
+type Document struct {
+	markdown bool
+}
+
+const (
+	htmlDocument     = false
+	markdownDocument = true
+)
+
+func (f *Document) Init(initParams map[string]interface{}) {}
+
+func (f *Document) Ext() string {
+	if f.markdown {
+		return "md"
+	}
+	return "html"
+}
+
+func (f *Document) Parse(content []byte) (*Catalog, error) {
+	s := f.segment(content)
+	c := NewCatalog("")
+	for _, e := range s.entries {
+		c.Add(e)
+	}
+	return c, nil
+}
+
+func (f *Document) Clean(content []byte) ([]byte, string, error) {
+	return cleanToStructuredJson(f, content)
+}
+
+// Replays the template (the source document) replacing the text of each unit with its translation
+func (f *Document) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	if template == nil {
+		return nil, fmt.Errorf("The source document is required to write a %s translation", strings.ToUpper(f.Ext()))
+	}
+	s := f.segment(template)
+	sort.SliceStable(s.segments, func(i, j int) bool { return s.segments[i].start < s.segments[j].start })
+	sort.SliceStable(s.attrs, func(i, j int) bool { return s.attrs[i].start < s.attrs[j].start })
+
+	var out bytes.Buffer
+	last := 0
+	for _, seg := range s.segments {
+		s.copySource(&out, c, last, seg.start)
+		if e := c.Find(seg.key); e != nil && e.Value != "" {
+			s.writeSegment(&out, c, seg, e.Value)
+		} else {
+			s.copySource(&out, c, seg.start, seg.end)
+		}
+		last = seg.end
+	}
+	s.copySource(&out, c, last, len(template))
+	return out.Bytes(), nil
+}
+
+func (f *Document) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	return writeCatalog(f, rootDir, langCode, srcLang, filename, translation, fileLocator)
+}
+
+func (f *Document) segment(content []byte) *docSegmenter {
+	s := &docSegmenter{content: content, sections: map[string]int{}, counters: map[string]int{}, used: map[string]bool{}}
+	if f.markdown {
+		s.segmentMarkdown()
+	} else {
+		s.segmentHtml(0, len(content))
+	}
+	return s
+}
+
+// A translatable unit of text
+type docSegment struct {
+	key        string
+	start, end int
+	// the text with the placeholders of the markup
+	text string
+	// the source of the placeholders: {1}, {/1} ...
+	tags map[string][2]int
+	// the indentation of the continuation lines of markdown (the lines of a list item, a quote ...)
+	indent   string
+	markdown bool
+}
+
+// A translatable attribute value (or the alt text of a markdown image)
+type docAttr struct {
+	key        string
+	start, end int
+	// the quote of the html value, 0 if the value is not quoted
+	quote    byte
+	markdown bool
+}
+
+type docSegmenter struct {
+	content []byte
+	// the units in the order of the document
+	entries  []*Entry
+	segments []*docSegment
+	attrs    []*docAttr
+
+	// the slug of the current section, the number of sections with each slug and the number of units of each kind
+	section  string
+	sections map[string]int
+	counters map[string]int
+	used     map[string]bool
+
+	// the html segment being read
+	seg      *docSegment
+	text     docText
+	space    bool
+	tagCount int
+	openTags []docOpenTag
+}
+
+type docOpenTag struct {
+	name string
+	n    int
+}
+
+var docPlaceholder = regexp.MustCompile(`\{/?\d+\}`)
+
+// a placeholder and the backslashes before it: text of the source that looks like a placeholder is escaped with a
+// backslash, \{1} is the text {1}
+var docEscapedPlaceholder = regexp.MustCompile(`(\\*)(\{/?\d+\})`)
+
+// The text of a segment: the literal text is escaped so that it cannot be read as a placeholder
+type docText struct {
+	out, text strings.Builder
+}
+
+func (t *docText) literal(text string) {
+	t.text.WriteString(text)
+}
+
+func (t *docText) placeholder(token string) {
+	t.flush()
+	t.out.WriteString(token)
+}
+
+func (t *docText) flush() {
+	t.out.WriteString(docEscapedPlaceholder.ReplaceAllStringFunc(t.text.String(), func(m string) string {
+		i := strings.Index(m, "{")
+		return strings.Repeat(`\`, 2*i+1) + m[i:]
+	}))
+	t.text.Reset()
+}
+
+func (t *docText) String() string {
+	t.flush()
+	return t.out.String()
+}
+
+func (t *docText) Reset() {
+	t.out.Reset()
+	t.text.Reset()
+}
+
+// A part of the text of a segment: the literal text without the escapes or a placeholder
+type docPart struct {
+	text, placeholder string
+}
+
+func splitDocText(text string) []docPart {
+	parts := []docPart{}
+	last := 0
+	for _, m := range docEscapedPlaceholder.FindAllStringSubmatchIndex(text, -1) {
+		backslashes := m[3] - m[2]
+		literal := text[last:m[2]] + strings.Repeat(`\`, backslashes/2)
+		if backslashes%2 == 1 {
+			parts = append(parts, docPart{text: literal + text[m[4]:m[5]]})
+		} else {
+			parts = append(parts, docPart{text: literal}, docPart{placeholder: text[m[4]:m[5]]})
+		}
+		last = m[1]
+	}
+	return append(parts, docPart{text: text[last:]})
+}
+
+// Whether the parts have each placeholder of the segment once with {n} before {/n}.  If nested the
+// placeholders must also be nested like the tags of html: {1}{2}...{/2}{/1}
+func (seg *docSegment) hasPlaceholders(parts []docPart, nested bool) bool {
+	seen := map[string]bool{}
+	open := []string{}
+	for _, part := range parts {
+		token := part.placeholder
+		if token == "" {
+			continue
+		}
+		if _, has := seg.tags[token]; !has || seen[token] {
+			return false
+		}
+		seen[token] = true
+		n := strings.Trim(token, "{/}")
+		if strings.HasPrefix(token, "{/") {
+			if !seen["{"+n+"}"] {
+				return false
+			}
+			if nested {
+				if len(open) == 0 || open[len(open)-1] != n {
+					return false
+				}
+				open = open[:len(open)-1]
+			}
+		} else if _, paired := seg.tags["{/"+n+"}"]; paired {
+			open = append(open, n)
+		}
+	}
+	return len(seen) == len(seg.tags)
+}
+
+func (s *docSegmenter) nextKey(kind string) string {
+	for {
+		s.counters[kind]++
+		key := fmt.Sprintf("%s%d", kind, s.counters[kind])
+		if s.section != "" {
+			key = s.section + "." + key
+		}
+		if !s.used[key] {
+			return key
+		}
+	}
+}
+
+func (s *docSegmenter) startSection(title string) string {
+	slug := strings.Trim(docSlugSeparators.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if slug == "" {
+		slug = "section"
+	}
+	s.sections[slug]++
+	if n := s.sections[slug]; n > 1 {
+		slug = fmt.Sprintf("%s-%d", slug, n)
+	}
+	s.section, s.counters = slug, map[string]int{}
+	return slug
+}
+
+var docSlugSeparators = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// Add the segment if it has text to translate
+func (s *docSegmenter) addSegment(seg *docSegment, kind, id string) bool {
+	plain := docPlaceholder.ReplaceAllString(seg.text, "")
+	if strings.IndexFunc(plain, isDocText) < 0 {
+		return false
+	}
+	switch {
+	case kind == "heading":
+		seg.key = s.startSection(plain)
+	case id != "" && !s.used[id]:
+		seg.key = id
+	default:
+		seg.key = s.nextKey(kind)
+	}
+	if s.used[seg.key] {
+		seg.key = s.nextKey(kind)
+	}
+	s.used[seg.key] = true
+	s.segments = append(s.segments, seg)
+	s.entries = append(s.entries, &Entry{Key: seg.key, Value: seg.text})
+	return true
+}
+
+func (s *docSegmenter) addAttr(a *docAttr, kind string) {
+	value := s.attrValue(a)
+	if strings.IndexFunc(value, isDocText) < 0 {
+		return
+	}
+	a.key = s.nextKey(kind)
+	s.used[a.key] = true
+	s.attrs = append(s.attrs, a)
+	s.entries = append(s.entries, &Entry{Key: a.key, Value: value})
+}
+
+func (s *docSegmenter) attrValue(a *docAttr) string {
+	if a.markdown {
+		return string(s.content[a.start:a.end])
+	}
+	return html.UnescapeString(string(s.content[a.start:a.end]))
+}
+
+func isDocText(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Copy the source from start to end with the translated attributes
+func (s *docSegmenter) copySource(out *bytes.Buffer, c *Catalog, start, end int) {
+	for _, a := range s.attrs {
+		if a.start < start || a.end > end {
+			continue
+		}
+		e := c.Find(a.key)
+		if e == nil || e.Value == "" {
+			continue
+		}
+		out.Write(s.content[start:a.start])
+		switch {
+		case a.markdown:
+			out.WriteString(strings.NewReplacer("[", `\[`, "]", `\]`).Replace(e.Value))
+		case a.quote == '\'':
+			out.WriteString(strings.Replace(xmlAttrEscaper.Replace(e.Value), "'", "&#39;", -1))
+		case a.quote == 0:
+			out.WriteString(`"` + xmlAttrEscaper.Replace(e.Value) + `"`)
+		default:
+			out.WriteString(xmlAttrEscaper.Replace(e.Value))
+		}
+		start = a.end
+	}
+	out.Write(s.content[start:end])
+}
+
+// Write the translation of the segment with the placeholders replaced by the markup of the source.  The source
+// text is kept if the translation does not have the placeholders of the source
+func (s *docSegmenter) writeSegment(out *bytes.Buffer, c *Catalog, seg *docSegment, translation string) {
+	parts := splitDocText(translation)
+	if !seg.hasPlaceholders(parts, seg.hasPlaceholders(splitDocText(seg.text), true)) {
+		// the markup would be broken
+		s.copySource(out, c, seg.start, seg.end)
+		return
+	}
+	for _, part := range parts {
+		switch {
+		case part.placeholder != "":
+			tag := seg.tags[part.placeholder]
+			s.copySource(out, c, tag[0], tag[1])
+		case seg.markdown:
+			out.WriteString(strings.Replace(part.text, "\n", "\n"+seg.indent, -1))
+		default:
+			out.WriteString(xmlTextEscaper.Replace(part.text))
+		}
+	}
+}
+
+// HTML
+
+var docInlineTags = docTagSet("a abbr b bdi bdo br cite code data dfn em font i img input kbd mark q s samp small span strike strong sub sup time tt u var wbr")
+var docVoidTags = docTagSet("area base br col embed hr img input link meta param source track wbr")
+var docRawTags = docTagSet("script style pre textarea template")
+
+// inline elements that are uploaded as one placeholder with their content
+var docCodeTags = docTagSet("code kbd samp")
+
+var docTranslatableAttrs = []string{"alt", "title", "placeholder", "aria-label"}
+
+func docTagSet(names string) map[string]bool {
+	set := map[string]bool{}
+	for _, name := range strings.Fields(names) {
+		set[name] = true
+	}
+	return set
+}
+
+type docHtmlTag struct {
+	name                 string
+	closing, selfClosing bool
+	attrs                []docHtmlAttr
+	end                  int
+}
+
+type docHtmlAttr struct {
+	name       string
+	start, end int
+	quote      byte
+}
+
+type docBlock struct {
+	name, id string
+}
+
+// Segment the html between start and end: the inline content of each block element is a segment
+func (s *docSegmenter) segmentHtml(start, end int) {
+	c := s.content
+	blocks := []docBlock{{}}
+	finish := func() {
+		if s.seg == nil {
+			return
+		}
+		block := &blocks[len(blocks)-1]
+		kind := block.name
+		switch {
+		case kind == "":
+			kind = "p"
+		case len(kind) == 2 && kind[0] == 'h' && kind[1] >= '1' && kind[1] <= '6':
+			kind = "heading"
+		case kind == "th":
+			kind = "td"
+		}
+		s.seg.text = s.text.String()
+		if s.addSegment(s.seg, kind, block.id) {
+			block.id = ""
+		}
+		s.seg, s.space, s.tagCount, s.openTags = nil, false, 0, nil
+		s.text.Reset()
+	}
+
+	for i := start; i < end; {
+		if c[i] != '<' {
+			j := bytes.IndexByte(c[i:end], '<')
+			if j < 0 {
+				j = end - i
+			}
+			s.htmlText(i, i+j)
+			i += j
+			continue
+		}
+		rest := c[i:end]
+		switch {
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			j := bytes.Index(rest, []byte("-->"))
+			if j < 0 {
+				j = len(rest) - 3
+			}
+			s.inlineTag(i, i+j+3, "")
+			i += j + 3
+			continue
+		case bytes.HasPrefix(rest, []byte("<!")) || bytes.HasPrefix(rest, []byte("<?")):
+			finish()
+			j := bytes.IndexByte(rest, '>')
+			if j < 0 {
+				j = len(rest) - 1
+			}
+			i += j + 1
+			continue
+		}
+
+		tag, ok := parseDocHtmlTag(c[:end], i)
+		if !ok {
+			s.htmlText(i, i+1)
+			i++
+			continue
+		}
+		for _, attr := range tag.attrs {
+			for _, name := range docTranslatableAttrs {
+				if attr.name == name {
+					s.addAttr(&docAttr{start: attr.start, end: attr.end, quote: attr.quote}, name)
+				}
+			}
+		}
+
+		switch {
+		case !tag.closing && (docRawTags[tag.name] || docCodeTags[tag.name]):
+			// the content is not translated
+			contentEnd := indexFold(c[tag.end:end], "</"+tag.name)
+			elementEnd := end
+			if contentEnd >= 0 {
+				if j := bytes.IndexByte(c[tag.end+contentEnd:end], '>'); j >= 0 {
+					elementEnd = tag.end + contentEnd + j + 1
+				}
+			}
+			if docCodeTags[tag.name] {
+				s.inlineTag(i, elementEnd, "")
+			} else {
+				finish()
+			}
+			i = elementEnd
+			continue
+		case docInlineTags[tag.name]:
+			name := tag.name
+			if tag.selfClosing || docVoidTags[name] {
+				name = ""
+			}
+			if tag.closing {
+				name = "/" + name
+			}
+			s.inlineTag(i, tag.end, name)
+		default:
+			finish()
+			switch {
+			case tag.closing:
+				for j := len(blocks) - 1; j > 0; j-- {
+					if blocks[j].name == tag.name {
+						blocks = blocks[:j]
+						break
+					}
+				}
+			case !tag.selfClosing && !docVoidTags[tag.name]:
+				block := docBlock{name: tag.name}
+				for _, attr := range tag.attrs {
+					if attr.name == "id" {
+						block.id = string(c[attr.start:attr.end])
+					}
+				}
+				blocks = append(blocks, block)
+			}
+		}
+		i = tag.end
+	}
+	finish()
+}
+
+var docHtmlSpace = regexp.MustCompile(`[ \t\r\n\f]+`)
+
+// Add the text between start and end to the segment, the whitespace is collapsed
+func (s *docSegmenter) htmlText(start, end int) {
+	raw := s.content[start:end]
+	core := bytes.TrimLeft(raw, " \t\r\n\f")
+	lead := len(raw) - len(core)
+	core = bytes.TrimRight(core, " \t\r\n\f")
+	if len(core) == 0 {
+		s.space = s.space || len(raw) > 0
+		return
+	}
+	if s.seg == nil {
+		s.seg = &docSegment{start: start + lead, tags: map[string][2]int{}}
+	} else if s.space || lead > 0 {
+		s.text.literal(" ")
+	}
+	s.text.literal(html.UnescapeString(docHtmlSpace.ReplaceAllString(string(core), " ")))
+	s.seg.end = start + lead + len(core)
+	s.space = len(raw) > lead+len(core)
+}
+
+// Add an inline tag to the segment as a placeholder.  name is the name of an element that is closed by a later
+// tag, /name for the closing tag and empty for a placeholder without content
+func (s *docSegmenter) inlineTag(start, end int, name string) {
+	if s.seg == nil {
+		s.seg = &docSegment{start: start, tags: map[string][2]int{}}
+	} else if s.space {
+		s.text.literal(" ")
+	}
+	s.space = false
+
+	token := ""
+	if strings.HasPrefix(name, "/") {
+		for i := len(s.openTags) - 1; i >= 0; i-- {
+			if s.openTags[i].name == name[1:] {
+				token = fmt.Sprintf("{/%d}", s.openTags[i].n)
+				s.openTags = s.openTags[:i]
+				break
+			}
+		}
+	}
+	if token == "" {
+		s.tagCount++
+		token = fmt.Sprintf("{%d}", s.tagCount)
+		if name != "" && !strings.HasPrefix(name, "/") {
+			s.openTags = append(s.openTags, docOpenTag{name, s.tagCount})
+		}
+	}
+	s.seg.tags[token] = [2]int{start, end}
+	s.text.placeholder(token)
+	s.seg.end = end
+}
+
+// Parse the html tag at i.  Returns false if it is not a tag
+func parseDocHtmlTag(c []byte, i int) (docHtmlTag, bool) {
+	tag := docHtmlTag{}
+	j := i + 1
+	if j < len(c) && c[j] == '/' {
+		tag.closing = true
+		j++
+	}
+	nameStart := j
+	for j < len(c) && (isDocText(rune(c[j])) || c[j] == '-' || c[j] == ':') && c[j] < 0x80 {
+		j++
+	}
+	if j == nameStart || !unicode.IsLetter(rune(c[nameStart])) {
+		return tag, false
+	}
+	tag.name = strings.ToLower(string(c[nameStart:j]))
+
+	isSpace := func(b byte) bool { return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f' }
+	for j < len(c) {
+		switch {
+		case isSpace(c[j]):
+			j++
+		case c[j] == '>':
+			tag.end = j + 1
+			return tag, true
+		case c[j] == '/' && j+1 < len(c) && c[j+1] == '>':
+			tag.selfClosing, tag.end = true, j+2
+			return tag, true
+		case c[j] == '/' || c[j] == '<':
+			if c[j] == '<' {
+				return tag, false
+			}
+			j++
+		default:
+			attrStart := j
+			for j < len(c) && !isSpace(c[j]) && c[j] != '=' && c[j] != '>' && c[j] != '/' {
+				j++
+			}
+			attr := docHtmlAttr{name: strings.ToLower(string(c[attrStart:j]))}
+			for j < len(c) && isSpace(c[j]) {
+				j++
+			}
+			if j < len(c) && c[j] == '=' {
+				j++
+				for j < len(c) && isSpace(c[j]) {
+					j++
+				}
+				if j < len(c) && (c[j] == '"' || c[j] == '\'') {
+					attr.quote = c[j]
+					valueEnd := bytes.IndexByte(c[j+1:], attr.quote)
+					if valueEnd < 0 {
+						return tag, false
+					}
+					attr.start, attr.end = j+1, j+1+valueEnd
+					j = attr.end + 1
+				} else {
+					attr.start = j
+					for j < len(c) && !isSpace(c[j]) && c[j] != '>' {
+						j++
+					}
+					attr.end = j
+				}
+			} else {
+				attr.start, attr.end = j, j
+			}
+			tag.attrs = append(tag.attrs, attr)
+		}
+	}
+	return tag, false
+}
+
+// the index of the case insensitive s in content or -1
+func indexFold(content []byte, s string) int {
+	return bytes.Index(bytes.ToLower(content), []byte(strings.ToLower(s)))
+}
+
+// Markdown
+
+var (
+	mdHeading        = regexp.MustCompile(`^ {0,3}#{1,6}(?:[ \t]+|$)(.*?)(?:[ \t]+#+)?[ \t]*$`)
+	mdSetext         = regexp.MustCompile(`^ {0,3}(?:=+|-+)[ \t]*$`)
+	mdThematicBreak  = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	mdListItem       = regexp.MustCompile(`^[ \t]*(?:[-*+]|\d{1,9}[.)])(?:[ \t]+|$)(?:\[[ xX]\][ \t]+)?`)
+	mdQuote          = regexp.MustCompile(`^ {0,3}> ?`)
+	mdHtmlBlock      = regexp.MustCompile(`^ {0,3}<(?:/?[A-Za-z][A-Za-z0-9-]*[\s/>]|/?[A-Za-z][A-Za-z0-9-]*$|!--)`)
+	mdReference      = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:`)
+	mdTableDelimiter = regexp.MustCompile(`^[ \t]*\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	mdAutolink       = regexp.MustCompile(`^<(?:[A-Za-z][A-Za-z0-9+.-]+:[^<>\s]*|[^<>\s@]+@[^<>\s@]+)>`)
+	mdInlineHtml     = regexp.MustCompile(`^<(/?)([A-Za-z][A-Za-z0-9-]*)(?:\s[^<>]*)?(/?)>`)
+)
+
+// a paragraph of markdown being read
+type mdParagraph struct {
+	kind   string
+	indent string
+	lines  [][2]int
+	quote  bool
+}
+
+func (s *docSegmenter) segmentMarkdown() {
+	c := s.content
+	lines := [][2]int{}
+	for start := 0; start < len(c); {
+		end := bytes.IndexByte(c[start:], '\n')
+		if end < 0 {
+			end = len(c) - start
+		}
+		line := [2]int{start, start + end}
+		if line[1] > line[0] && c[line[1]-1] == '\r' {
+			line[1]--
+		}
+		lines = append(lines, line)
+		start += end + 1
+	}
+	text := func(i int) string { return string(c[lines[i][0]:lines[i][1]]) }
+
+	var para *mdParagraph
+	finish := func() {
+		if para != nil {
+			s.markdownParagraph(para)
+		}
+		para = nil
+	}
+	// add the line from the offset to the paragraph, or start a paragraph
+	add := func(i, offset int, kind, indent string) {
+		line := [2]int{lines[i][0] + offset, lines[i][1]}
+		for line[0] < line[1] && (c[line[0]] == ' ' || c[line[0]] == '\t') {
+			line[0]++
+		}
+		for line[1] > line[0] && (c[line[1]-1] == ' ' || c[line[1]-1] == '\t') {
+			line[1]--
+		}
+		if para == nil {
+			para = &mdParagraph{kind: kind, indent: indent}
+		}
+		para.lines = append(para.lines, line)
+	}
+
+	i := 0
+	if len(lines) > 0 && strings.TrimSpace(text(0)) == "---" {
+		// front matter
+		for j := 1; j < len(lines); j++ {
+			if t := strings.TrimSpace(text(j)); t == "---" || t == "..." {
+				i = j + 1
+				break
+			}
+		}
+	}
+	inItem := false
+	for ; i < len(lines); i++ {
+		line := text(i)
+		trimmed := strings.TrimSpace(line)
+		lead := len(line) - len(strings.TrimLeft(line, " \t"))
+		switch {
+		case trimmed == "":
+			finish()
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			finish()
+			fence := trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(text(i)), fence); i++ {
+			}
+		case para == nil && lead >= 4 && !inItem:
+			// indented code
+		case para != nil && para.kind == "p" && !para.quote && mdSetext.MatchString(line):
+			para.kind = "heading"
+			finish()
+			inItem = false
+		case mdThematicBreak.MatchString(line):
+			finish()
+			inItem = false
+		case mdHeading.MatchString(line):
+			finish()
+			m := mdHeading.FindStringSubmatchIndex(line)
+			add(i, m[2], "heading", "")
+			finish()
+			inItem = false
+		case para == nil && mdHtmlBlock.MatchString(line):
+			j := i
+			for j+1 < len(lines) && strings.TrimSpace(text(j+1)) != "" {
+				j++
+			}
+			s.segmentHtml(lines[i][0], lines[j][1])
+			i = j
+		case mdReference.MatchString(line):
+			finish()
+		case strings.Contains(line, "|") && i+1 < len(lines) && strings.Contains(text(i+1), "|") && mdTableDelimiter.MatchString(text(i+1)):
+			finish()
+			s.markdownTableRow(lines[i])
+			for i++; i+1 < len(lines) && strings.Contains(text(i+1), "|"); i++ {
+				s.markdownTableRow(lines[i+1])
+			}
+		case mdListItem.MatchString(line):
+			finish()
+			marker := len(mdListItem.FindString(line))
+			add(i, marker, "li", strings.Repeat(" ", marker))
+			inItem = true
+		case mdQuote.MatchString(line):
+			prefix := mdQuote.FindString(line)
+			if para == nil || !para.quote || strings.TrimSpace(line[len(prefix):]) == "" {
+				finish()
+			}
+			if strings.TrimSpace(line[len(prefix):]) != "" {
+				add(i, len(prefix), "p", prefix)
+				para.quote = true
+			}
+		default:
+			add(i, 0, "p", line[:lead])
+		}
+	}
+	finish()
+}
+
+func (s *docSegmenter) markdownParagraph(para *mdParagraph) {
+	var content bytes.Buffer
+	pos := []int{}
+	for i, line := range para.lines {
+		if i > 0 {
+			content.WriteByte('\n')
+			pos = append(pos, para.lines[i-1][1])
+		}
+		for j := line[0]; j < line[1]; j++ {
+			content.WriteByte(s.content[j])
+			pos = append(pos, j)
+		}
+	}
+	if content.Len() == 0 {
+		return
+	}
+	seg := &docSegment{start: para.lines[0][0], end: para.lines[len(para.lines)-1][1], indent: para.indent, markdown: true}
+	s.markdownInline(seg, content.String(), pos)
+	s.addSegment(seg, para.kind, "")
+}
+
+// Each cell of the row is a segment
+func (s *docSegmenter) markdownTableRow(line [2]int) {
+	c := s.content
+	cellStart := line[0]
+	inCode := false
+	for i := line[0]; i <= line[1]; i++ {
+		if i < line[1] {
+			switch {
+			case c[i] == '\\':
+				i++
+				continue
+			case c[i] == '`':
+				inCode = !inCode
+				continue
+			case c[i] != '|' || inCode:
+				continue
+			}
+		}
+		cell := [2]int{cellStart, i}
+		for cell[0] < cell[1] && (c[cell[0]] == ' ' || c[cell[0]] == '\t') {
+			cell[0]++
+		}
+		for cell[1] > cell[0] && (c[cell[1]-1] == ' ' || c[cell[1]-1] == '\t') {
+			cell[1]--
+		}
+		if cell[1] > cell[0] {
+			s.markdownParagraph(&mdParagraph{kind: "td", lines: [][2]int{cell}})
+		}
+		cellStart = i + 1
+	}
+}
+
+// Replace the inline markup of the content with placeholders.  pos is the offset in the source of each byte
+func (s *docSegmenter) markdownInline(seg *docSegment, content string, pos []int) {
+	seg.tags = map[string][2]int{}
+	var text docText
+	n := 0
+	tag := func(start, end int, token string) {
+		seg.tags[token] = [2]int{pos[start], pos[end-1] + 1}
+		text.placeholder(token)
+	}
+	newTag := func(start, end int) int {
+		n++
+		tag(start, end, fmt.Sprintf("{%d}", n))
+		return n
+	}
+	// the closing part of the links: ](url) by the offset of the ]
+	closes := map[int][2]int{}
+	var open []docOpenTag
+
+	for i := 0; i < len(content); {
+		if close, has := closes[i]; has {
+			tag(i, close[0], fmt.Sprintf("{/%d}", close[1]))
+			i = close[0]
+			continue
+		}
+		ch := content[i]
+		switch {
+		case ch == '\\' && i+1 < len(content) && unicode.IsPunct(rune(content[i+1])):
+			text.literal(content[i : i+2])
+			i += 2
+		case ch == '`':
+			run := len(content[i:]) - len(strings.TrimLeft(content[i:], "`"))
+			end := mdCodeSpanEnd(content, i, run)
+			if end < 0 {
+				text.literal(content[i : i+run])
+				i += run
+				continue
+			}
+			newTag(i, end)
+			i = end
+		case ch == '!' && strings.HasPrefix(content[i:], "!["):
+			close, end := mdLinkEnd(content, i+1)
+			if end < 0 {
+				text.literal(string(ch))
+				i++
+				continue
+			}
+			newTag(i, end)
+			if close > i+2 {
+				s.addAttr(&docAttr{start: pos[i+2], end: pos[close-1] + 1, markdown: true}, "alt")
+			}
+			i = end
+		case ch == '[':
+			close, end := mdLinkEnd(content, i)
+			if end < 0 {
+				text.literal(string(ch))
+				i++
+				continue
+			}
+			closes[close] = [2]int{end, newTag(i, i+1)}
+			i++
+		case ch == '<':
+			if m := mdAutolink.FindString(content[i:]); m != "" {
+				newTag(i, i+len(m))
+				i += len(m)
+				continue
+			}
+			m := mdInlineHtml.FindStringSubmatch(content[i:])
+			if m == nil {
+				text.literal(string(ch))
+				i++
+				continue
+			}
+			end, name := i+len(m[0]), strings.ToLower(m[2])
+			closed := false
+			if m[1] == "/" {
+				for j := len(open) - 1; j >= 0; j-- {
+					if open[j].name == "<"+name {
+						tag(i, end, fmt.Sprintf("{/%d}", open[j].n))
+						open, closed = open[:j], true
+						break
+					}
+				}
+			}
+			if !closed {
+				k := newTag(i, end)
+				if m[1] == "" && m[3] == "" && !docVoidTags[name] {
+					open = append(open, docOpenTag{"<" + name, k})
+				}
+			}
+			i = end
+		case ch == '*' || ch == '_' || ch == '~':
+			run := len(content[i:]) - len(strings.TrimLeft(content[i:], string(ch)))
+			delim := content[i : i+run]
+			before, after := rune(' '), rune(' ')
+			if i > 0 {
+				before = rune(content[i-1])
+			}
+			if i+run < len(content) {
+				after = rune(content[i+run])
+			}
+			closer := !unicode.IsSpace(before) && (ch != '_' || !isDocText(after))
+			opener := !unicode.IsSpace(after) && (ch != '_' || !isDocText(before))
+			if ch == '~' && run != 2 {
+				closer, opener = false, false
+			}
+			closed := false
+			if closer {
+				for j := len(open) - 1; j >= 0; j-- {
+					if open[j].name == delim {
+						tag(i, i+run, fmt.Sprintf("{/%d}", open[j].n))
+						open, closed = open[:j], true
+						break
+					}
+				}
+			}
+			switch {
+			case closed:
+			case opener:
+				open = append(open, docOpenTag{delim, newTag(i, i+run)})
+			default:
+				text.literal(delim)
+			}
+			i += run
+		default:
+			text.literal(string(ch))
+			i++
+		}
+	}
+	seg.text = text.String()
+}
+
+// the end of the code span starting with a run of backticks, -1 if it is not closed
+func mdCodeSpanEnd(content string, start, run int) int {
+	for i := start + run; i < len(content); {
+		if content[i] != '`' {
+			i++
+			continue
+		}
+		closing := len(content[i:]) - len(strings.TrimLeft(content[i:], "`"))
+		if closing == run {
+			return i + run
+		}
+		i += closing
+	}
+	return -1
+}
+
+// The offsets of the ] and of the end of the link or image starting with the [ at start: [text](url) or [text][ref].
+// Returns -1, -1 if it is not a link
+func mdLinkEnd(content string, start int) (int, int) {
+	depth := 0
+	for i := start; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case '`':
+			run := len(content[i:]) - len(strings.TrimLeft(content[i:], "`"))
+			if end := mdCodeSpanEnd(content, i, run); end > 0 {
+				i = end - 1
+			} else {
+				i += run - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			if depth--; depth > 0 {
+				continue
+			}
+			if i+1 >= len(content) || (content[i+1] != '(' && content[i+1] != '[') {
+				return -1, -1
+			}
+			closing, parens := content[i+1], 0
+			for j := i + 1; j < len(content); j++ {
+				switch {
+				case content[j] == '\\':
+					j++
+				case closing == '[' && content[j] == ']':
+					return i, j + 1
+				case closing == '(' && content[j] == '(':
+					parens++
+				case closing == '(' && content[j] == ')':
+					if parens--; parens == 0 {
+						return i, j + 1
+					}
+				}
+			}
+			return -1, -1
+		}
+	}
+	return -1, -1
+}
@@ -0,0 +1,221 @@
+package format
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	tu "testutil"
+)
+
+const markdownSource = `---
+layout: help
+---
+Welcome to the **help** pages.
+
+# Getting started
+
+Install it with ` + "`npm install`" + ` and read
+the [guide](http://example.com/guide) first.
+
+![A screenshot](shot.png)
+
+* First step
+* Second _step_
+
+` + "```" + `
+npm install
+` + "```" + `
+
+| Name | Description |
+| ---- | ----------- |
+| save | Saves the file |
+
+> Quoted
+> text
+
+Getting started
+---------------
+
+Again
+`
+
+const htmlSource = `<!DOCTYPE html>
+<html>
+<head><title>Help</title><style>p { color: red }</style></head>
+<body>
+<h1>Getting started</h1>
+<p>Click <b>Save</b> then run <code>make &amp; install</code>.<br/>
+  Done &amp; dusted.</p>
+<p id="intro"><img src="a.png" alt="A screenshot"> Intro</p>
+<ul><li>One</li><li>Two <a href="x.html" title='The second'>link</a></li></ul>
+<pre>not translated</pre>
+</body>
+</html>
+`
+
+func Test_Document_Parse(t *testing.T) {
+	c, err := Formats["MARKDOWN"]().Parse([]byte(markdownSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range map[string]string{
+		"p1":                   "Welcome to the {1}help{/1} pages.",
+		"getting-started":      "Getting started",
+		"getting-started.p1":   "Install it with {1} and read\nthe {2}guide{/2} first.",
+		"getting-started.alt1": "A screenshot",
+		"getting-started.li1":  "First step",
+		"getting-started.li2":  "Second {1}step{/1}",
+		"getting-started.td1":  "Name",
+		"getting-started.td4":  "Saves the file",
+		"getting-started.p2":   "Quoted\ntext",
+		"getting-started-2":    "Getting started",
+		"getting-started-2.p1": "Again",
+	} {
+		if e := c.Find(key); e == nil {
+			t.Errorf("Missing %s", key)
+		} else {
+			tu.AssertEquals(key, value, e.Value, t)
+		}
+	}
+	tu.AssertEqualsInt("markdown units", 13, len(c.Entries), t)
+
+	c, err = Formats["HTML"]().Parse([]byte(htmlSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range map[string]string{
+		"title1":                 "Help",
+		"getting-started":        "Getting started",
+		"getting-started.p1":     "Click {1}Save{/1} then run {2}.{3} Done & dusted.",
+		"getting-started.alt1":   "A screenshot",
+		"intro":                  "{1} Intro",
+		"getting-started.li1":    "One",
+		"getting-started.title1": "The second",
+		"getting-started.li2":    "Two {1}link{/1}",
+	} {
+		if e := c.Find(key); e == nil {
+			t.Errorf("Missing %s", key)
+		} else {
+			tu.AssertEquals(key, value, e.Value, t)
+		}
+	}
+	tu.AssertEqualsInt("html units", 8, len(c.Entries), t)
+}
+
+func Test_Document_Write(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	ioutil.WriteFile(filepath.Join(tmpDir, "en-help.md"), []byte(markdownSource), 0644)
+	ioutil.WriteFile(filepath.Join(tmpDir, "en-help.html"), []byte(htmlSource), 0644)
+
+	data, _ := json.Marshal(map[string]string{
+		"getting-started":      "Premiers pas",
+		"getting-started.p1":   "Lisez d'abord le {2}guide{/2}\net installez avec {1}.",
+		"getting-started.alt1": "Une capture [écran]",
+		"getting-started.li2":  "Deuxième {1}étape{/1}",
+		"getting-started.td4":  "Enregistre le fichier",
+		"getting-started.p2":   "Texte\ncité",
+		"getting-started-2.p1": "Encore"})
+	if err := Formats["MARKDOWN"]().Write(tmpDir, "fr", "en", "help", string(data), FileLocators["LANG-NAME"]); err != nil {
+		t.Fatal(err)
+	}
+	expected := `---
+layout: help
+---
+Welcome to the **help** pages.
+
+# Premiers pas
+
+Lisez d'abord le [guide](http://example.com/guide)
+et installez avec ` + "`npm install`" + `.
+
+![Une capture \[écran\]](shot.png)
+
+* First step
+* Deuxième _étape_
+
+` + "```" + `
+npm install
+` + "```" + `
+
+| Name | Description |
+| ---- | ----------- |
+| save | Enregistre le fichier |
+
+> Texte
+> cité
+
+Getting started
+---------------
+
+Encore
+`
+	tu.AssertEquals("markdown", expected, string(mustRead(filepath.Join(tmpDir, "fr-help.md"))), t)
+
+	data, _ = json.Marshal(map[string]string{
+		"getting-started.p1":     "Cliquez sur {1}Enregistrer{/1} puis {2}.{3} Voilà & fini.",
+		"intro":                  "Introduction {1}",
+		"getting-started.alt1":   "Une \"capture\"",
+		"getting-started.title1": "L'autre",
+		"getting-started.li2":    "Deux {1}lien{/1}"})
+	if err := Formats["HTML"]().Write(tmpDir, "fr", "en", "help", string(data), FileLocators["LANG-NAME"]); err != nil {
+		t.Fatal(err)
+	}
+	expected = `<!DOCTYPE html>
+<html>
+<head><title>Help</title><style>p { color: red }</style></head>
+<body>
+<h1>Getting started</h1>
+<p>Cliquez sur <b>Enregistrer</b> puis <code>make &amp; install</code>.<br/> Voilà &amp; fini.</p>
+<p id="intro">Introduction <img src="a.png" alt="Une &quot;capture&quot;"></p>
+<ul><li>One</li><li>Deux <a href="x.html" title='L&#39;autre'>lien</a></li></ul>
+<pre>not translated</pre>
+</body>
+</html>
+`
+	tu.AssertEquals("html", expected, string(mustRead(filepath.Join(tmpDir, "fr-help.html"))), t)
+
+	if _, err := Formats["HTML"]().Serialize(NewCatalog("fr"), nil); err == nil {
+		t.Errorf("Expected an error without the source document")
+	}
+}
+
+func Test_Document_Placeholders(t *testing.T) {
+	source := `<p>Write {1} or \{2} for <b>the</b> count</p>`
+	c, err := Formats["HTML"]().Parse([]byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("escaped", `Write \{1} or \\\{2} for {1}the{/1} count`, c.Find("p1").Value, t)
+
+	for translation, expected := range map[string]string{
+		`Ecrire \{1} ou \\\{2} pour {1}le{/1} compte`: `<p>Ecrire {1} ou \{2} pour <b>le</b> compte</p>`,
+		// the placeholders of the source are required, once, with {n} before {/n}
+		`Ecrire {1} pour {2}le{/2} compte`: source,
+		`Ecrire pour le compte`:            source,
+		`Ecrire {1}{1}le{/1} compte`:       source,
+		`Ecrire {/1}le{1} compte`:          source,
+		`Ecrire \{1} pour {1}le compte`:    source,
+	} {
+		tc := NewCatalog("fr")
+		tc.Add(&Entry{Key: "p1", Value: translation})
+		out, err := Formats["HTML"]().Serialize(tc, []byte(source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tu.AssertEquals(translation, expected, string(out), t)
+	}
+
+	// the placeholders must be nested as in the source
+	source = "Some **bold _and_ italic** text\n"
+	c, _ = Formats["MARKDOWN"]().Parse([]byte(source))
+	tu.AssertEquals("markdown", "Some {1}bold {2}and{/2} italic{/1} text", c.Find("p1").Value, t)
+	tc := NewCatalog("fr")
+	tc.Add(&Entry{Key: "p1", Value: "Du {1}gras {2}et{/1} italique{/2}"})
+	out, _ := Formats["MARKDOWN"]().Serialize(tc, []byte(source))
+	tu.AssertEquals("not nested", source, string(out), t)
+	tc = NewCatalog("fr")
+	tc.Add(&Entry{Key: "p1", Value: "Du {1}gras {2}et{/2} italique{/1}"})
+	out, _ = Formats["MARKDOWN"]().Serialize(tc, []byte(source))
+	tu.AssertEquals("nested", "Du **gras _et_ italique**\n", string(out), t)
+}
@@ -6,9 +6,6 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"os"
-	"path/filepath"
 	"strings"
 )
 
@@ -16,54 +13,56 @@ import (
 // since the xml can be nested and the nodes can have attributes, the path to the leaf node is encoded
 // as the key of the Json.
 // the format that is uploaded to transifex will be a valid Json key value formatted file
+//
+// Repeated nodes are keyed by their position (c, c<2> ...) unless the key extra parameter names the attribute or
+// child of a node that identifies it: @id, name, meta/name or meta/@id.  The node is then keyed by its name and
+// id (string<welcome>) whatever its position and attributes, and the text of a key child is not uploaded.  The
+// translations without such a node keep their positional keys and are listed in a warning on upload.
+//
+// The names in the keys have the namespace prefixes of the file (android:string).  Translations are written as a
+// copy of the source xml with only the text of the translation nodes replaced: the declaration, comments, processing
+// instructions and attribute quoting are kept and the text of a CDATA section is written as CDATA.
 type FlattenXmlToJson struct{
 	key string
 }
 func (f *FlattenXmlToJson) Init(initParams map[string]interface{}) {
-	if keyInterface, has := initParams["key"]; has {
-		f.key = keyInterface.(string)
+	if key, has := initParams["key"].(string); has {
+		f.key = key
 	}
 }
 func (f *FlattenXmlToJson) Ext() string { return "xml" }
 
-func (f *FlattenXmlToJson) Clean(content []byte) ([]byte, string, error) {
-
-	r := strings.NewReader(string(content))
-	parser := xml.NewDecoder(r)
+func (f *FlattenXmlToJson) Parse(content []byte) (*Catalog, error) {
+	c, _, err := f.parse(content)
+	return c, err
+}
 
-	contentJson := make(map[string]string)
-	rawKeys := make(map[string]int)
+// The catalog and the keys of the translations that have no key param and are keyed by their position
+func (f *FlattenXmlToJson) parse(content []byte) (*Catalog, []string, error) {
+	x, err := f.flatten(content)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	key := []string{}
-	for {
-		token, err := parser.Token()
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return nil, "", err
-		}
-		switch t := token.(type) {
-		case xml.StartElement:
-			name := nodeName(t)
-			if name == "" {
-				panic("A node name is an empty string: \n" + string(content))
-			}
-			key = append(key, name)
-		case xml.EndElement:
-			key = key[:len(key)-1]
-		case xml.CharData:
-			if len(key) > 1 {
-				fkey, add := finalKey(key, rawKeys, xml.CharData(t))
-				if add {
-					contentJson[fkey] = string(xml.CharData(t))
-				}
-			}
+	c := NewCatalog("")
+	for i, token := range x.tokens {
+		if x.keys[i] != "" {
+			c.Add(&Entry{Key: x.keys[i], Value: string(token.(xml.CharData))})
 		}
 	}
+	return c, x.lacking, nil
+}
+
+func (f *FlattenXmlToJson) Clean(content []byte) ([]byte, string, error) {
+	c, lacking, err := f.parse(content)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(lacking) > 0 {
+		fmt.Printf("Warning: %d translations have no %s, their keys depend on their position:\n  %s\n", len(lacking), f.key, strings.Join(lacking, "\n  "))
+	}
 
-	var err error
-	content, err = json.Marshal(contentJson)
+	content, err = json.Marshal(c.KeyValues())
 	if err != nil {
 		return nil, "", err
 	}
@@ -78,91 +77,233 @@ type Node struct {
 	children map[string]Node
 }
 
-func (f FlattenXmlToJson) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
-	path := fileLocator.Find(rootDir, langCode, filename, "xml")
-	fmt.Println("Updating translations file: " + path)
+// Replays the template (the source xml) replacing the text of each translation node with the value in the catalog
+func (f *FlattenXmlToJson) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	if template == nil {
+		return nil, fmt.Errorf("The source xml is required to write a FLATTENXMLTOJSON translation")
+	}
 
-	var translationJson map[string]string
-	if err := json.Unmarshal([]byte(translation), &translationJson); err != nil {
-		return err
+	x, err := f.flatten(template)
+	if err != nil {
+		return nil, err
 	}
+
+	// everything but the translated text is copied from the template
 	var out = bytes.Buffer{}
-	encoder := xml.NewEncoder(&out)
+	last := 0
+	for i, key := range x.keys {
+		if key == "" {
+			continue
+		}
+		newData := ""
+		if e := c.Find(key); e != nil {
+			newData = e.Value
+		}
+		start, end := x.offsets[i][0], x.offsets[i][1]
+		out.Write(template[last:start])
+		if bytes.HasPrefix(template[start:end], []byte("<![CDATA[")) {
+			out.WriteString("<![CDATA[" + strings.Replace(newData, "]]>", "]]]]><![CDATA[>", -1) + "]]>")
+		} else {
+			out.WriteString(xmlTextEscaper.Replace(newData))
+		}
+		last = end
+	}
+	out.Write(template[last:])
+	return out.Bytes(), nil
+}
 
-	srcFile := fileLocator.Find(rootDir, srcLang, filename, "xml")
+func (f FlattenXmlToJson) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	return writeCatalog(&f, rootDir, langCode, srcLang, filename, translation, fileLocator)
+}
 
-	srcContent, err := os.Open(srcFile)
-	if err != nil {
-		return err
+func nodeName(t xml.StartElement, prefixes xmlPrefixes) string {
+	elmt := xml.StartElement(t)
+	name := prefixes.name(elmt.Name)
+	nodeRep := bytes.Buffer{}
+	nodeRep.WriteString(name)
+	if len(elmt.Attr) > 0 {
+		nodeRep.WriteString("[")
+		for i, att := range elmt.Attr {
+			if i > 0 {
+				nodeRep.WriteString(" and ")
+			}
+			nodeRep.WriteString(prefixes.name(att.Name))
+			nodeRep.WriteString("=")
+			nodeRep.WriteString(att.Value)
+		}
+		nodeRep.WriteString("]")
 	}
-	defer srcContent.Close()
 
-	parser := xml.NewDecoder(srcContent)
+	return strings.TrimSpace(nodeRep.String())
+}
 
-	key := []string{}
-	rawKeys := map[string]int{}
+// The tokens of an xml file with their offsets in the file and the key of each token that is the text of a
+// translation, "" for the other tokens.  lacking has the keys of the translations that are keyed by their position
+// because no node has the key param
+type flatXml struct {
+	tokens  []xml.Token
+	offsets [][2]int
+	keys    []string
+	lacking []string
+}
+
+func (f *FlattenXmlToJson) flatten(content []byte) (*flatXml, error) {
+	x := &flatXml{}
+	parser := xml.NewDecoder(bytes.NewReader(content))
 	for {
+		start := parser.InputOffset()
 		token, err := parser.Token()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return err
+			return nil, err
 		}
+		x.tokens = append(x.tokens, xml.CopyToken(token))
+		x.offsets = append(x.offsets, [2]int{int(start), int(parser.InputOffset())})
+	}
+
+	// the ids of the nodes by the index of their start token and the nodes whose text is an id
+	ids, idNodes := f.ids(x.tokens)
+
+	x.keys = make([]string, len(x.tokens))
+	rawKeys := map[string]int{}
+	key := []string{}
+	starts := []int{}
+	prefixes := xmlPrefixes{}
+	keyed := 0
+	for i, token := range x.tokens {
 		switch t := token.(type) {
 		case xml.StartElement:
-			key = append(key, nodeName(t))
-			encoder.EncodeToken(t)
+			prefixes.push(t)
+			name := nodeName(t, prefixes)
+			if name == "" {
+				return nil, fmt.Errorf("A node name is an empty string: \n%s", content)
+			}
+			if id, has := ids[i]; has {
+				name = fmt.Sprintf("%s<%s>", prefixes.name(t.Name), id)
+				keyed++
+			}
+			key = append(key, name)
+			starts = append(starts, i)
 		case xml.EndElement:
-			key = key[:len(key)-1]
-			encoder.EncodeToken(t)
+			if _, has := ids[starts[len(starts)-1]]; has {
+				keyed--
+			}
+			key, starts = key[:len(key)-1], starts[:len(starts)-1]
+			prefixes.pop()
 		case xml.CharData:
-			fkey, add := finalKey(key, rawKeys, xml.CharData(t))
-			if add {
-				newData := translationJson[fkey]
-				delete(translationJson, fkey)
-
-				encoder.EncodeToken(xml.CharData(newData))
+			if len(key) < 2 || idNodes[starts[len(starts)-1]] {
+				continue
+			}
+			if fkey, add := finalKey(key, rawKeys, t); add {
+				x.keys[i] = fkey
+				if f.key != "" && keyed == 0 {
+					x.lacking = append(x.lacking, fkey)
+				}
 			}
 		}
 	}
+	return x, nil
+}
 
-	if len(translationJson) > 0 {
-		extraKeys := []string{}
-		for key, _ := range translationJson {
-			extraKeys = append(extraKeys, key)
+// The namespace prefixes declared by the open nodes, the decoder replaces the prefixes by the namespace urls
+type xmlPrefixes []map[string]string
+
+func (p *xmlPrefixes) push(t xml.StartElement) {
+	declared := map[string]string{}
+	for _, a := range t.Attr {
+		switch {
+		case a.Name.Space == "xmlns":
+			declared[a.Value] = a.Name.Local
+		case a.Name.Space == "" && a.Name.Local == "xmlns":
+			declared[a.Value] = ""
 		}
-		return fmt.Errorf("One or more translations did not have a matching key in the xml file: [%s]", strings.Join(extraKeys, ", "))
 	}
+	*p = append(*p, declared)
+}
+
+func (p *xmlPrefixes) pop() {
+	*p = (*p)[:len(*p)-1]
+}
 
-	if err = encoder.Flush(); err != nil {
-		return err
+// The name with the prefix of its namespace as written in the file: android:name
+func (p xmlPrefixes) name(name xml.Name) string {
+	switch name.Space {
+	case "":
+		return name.Local
+	case "xmlns":
+		return "xmlns:" + name.Local
+	case "http://www.w3.org/XML/1998/namespace":
+		return "xml:" + name.Local
 	}
-	if err = os.MkdirAll(filepath.Dir(path), 644); err != nil {
-		return err
+	for i := len(p) - 1; i >= 0; i-- {
+		if prefix, has := p[i][name.Space]; has {
+			if prefix == "" {
+				return name.Local
+			}
+			return prefix + ":" + name.Local
+		}
 	}
-	return ioutil.WriteFile(path, out.Bytes(), 644)
+	// an undeclared prefix is not replaced
+	return name.Space + ":" + name.Local
 }
 
-func nodeName(t xml.StartElement) string {
-	elmt := xml.StartElement(t)
-	name := elmt.Name.Local
-	nodeRep := bytes.Buffer{}
-	nodeRep.WriteString(name)
-	if len(elmt.Attr) > 0 {
-		nodeRep.WriteString("[")
-		for i, att := range elmt.Attr {
-			if i > 0 {
-				nodeRep.WriteString(" and ")
+// Find the id of each node with the key param: the value of the attribute or the text of the child.  The names
+// of the param are compared with the names of the file with their namespace prefix: @android:name
+func (f *FlattenXmlToJson) ids(tokens []xml.Token) (map[int]string, map[int]bool) {
+	ids, idNodes := map[int]string{}, map[int]bool{}
+	if f.key == "" {
+		return ids, idNodes
+	}
+	path := strings.Split(strings.TrimPrefix(f.key, "./"), "/")
+	attr := ""
+	if last := path[len(path)-1]; strings.HasPrefix(last, "@") {
+		attr, path = last[1:], path[:len(path)-1]
+	}
+
+	names := []string{}
+	starts := []int{}
+	prefixes := xmlPrefixes{}
+	// the node whose id is the text of the child by the index of the child
+	owners := map[int]int{}
+	for i, token := range tokens {
+		switch t := token.(type) {
+		case xml.StartElement:
+			prefixes.push(t)
+			names, starts = append(names, prefixes.name(t.Name)), append(starts, i)
+			if len(names) <= len(path) || strings.Join(names[len(names)-len(path):], "/") != strings.Join(path, "/") {
+				continue
+			}
+			owner := starts[len(starts)-1-len(path)]
+			if _, has := ids[owner]; has {
+				continue
+			}
+			if attr == "" {
+				owners[i] = owner
+				continue
+			}
+			for _, a := range t.Attr {
+				if prefixes.name(a.Name) == attr && strings.TrimSpace(a.Value) != "" {
+					ids[owner] = strings.TrimSpace(a.Value)
+				}
+			}
+		case xml.EndElement:
+			names, starts = names[:len(names)-1], starts[:len(starts)-1]
+			prefixes.pop()
+		case xml.CharData:
+			if len(starts) == 0 {
+				continue
+			}
+			if owner, has := owners[starts[len(starts)-1]]; has {
+				idNodes[starts[len(starts)-1]] = true
+				if _, has := ids[owner]; !has && strings.TrimSpace(string(t)) != "" {
+					ids[owner] = strings.TrimSpace(string(t))
+				}
 			}
-			nodeRep.WriteString(att.Name.Local)
-			nodeRep.WriteString("=")
-			nodeRep.WriteString(att.Value)
 		}
-		nodeRep.WriteString("]")
 	}
-
-	return strings.TrimSpace(nodeRep.String())
+	return ids, idNodes
 }
 
 // returns the final key and true or "", false.  If the second return value is false 
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"testing"
 	"bytes"
+	"strings"
 	"text/template"
 	tu "testutil"
 )
@@ -66,19 +67,19 @@ func Test_Clean_Write_KeyText(t *testing.T) {
 	<list id="list1">
 		<el>
 			<translationKey>{{.Key}} 1</translationKey>
-			<value>{{.Value}} 1<value>
+			<value>{{.Value}} 1</value>
 		</el>
 		<el>
+			<value>{{.Value}} 2</value>
 			<translationKey>{{.Key}} 2</translationKey>
-			<value>{{.Value}} 2<value>
 		</el>
 	</list>
-	<list id="list2"/>
+	<list id="list2">
 		<el>
 			<translationKey>{{.Key}} 3</translationKey>
-			<value>{{.Value}} 3<value>
+			<value>{{.Value}} 3</value>
 		</el>
-	</list>	
+	</list>
 </strings>`
 	tmpl, err := template.New("xmlTemplate").Parse(keyXmlTemplateText)
 
@@ -97,35 +98,68 @@ func Test_Clean_Write_KeyText(t *testing.T) {
 	json.Unmarshal(cleaned, &prop)
 
 	if len(prop) != 3 {
-		t.Errorf("Expected 4 items but found %d in %s", len(prop), prop)
+		t.Errorf("Expected 3 items but found %d in %s", len(prop), prop)
 	}
 
 	for key, item := range prop {
 		switch key {
-		case "list[id=list1] el <key 1>":
-			tu.AssertEquals("", "value1", item, t)
-		case "list[id=list1] el <key 2>":
-			tu.AssertEquals("", "value2", item, t)
-		case "list[id=list2] el <key 3>":
-			tu.AssertEquals("", "value3", item, t)
+		case "list[id=list1] el<key 1> value":
+			tu.AssertEquals("", "value 1", item, t)
+		case "list[id=list1] el<key 2> value":
+			tu.AssertEquals("", "value 2", item, t)
+		case "list[id=list2] el<key 3> value":
+			tu.AssertEquals("", "value 3", item, t)
 		default:
 			t.Errorf("Unexpected key: %s in %s", key, prop)
 		}
 	}
 
-	beforeWriteData := new(bytes.Buffer)
-	tmpl.Execute(beforeWriteData, templateFiller{Key: "before", Value: "before"})
 	tmpDir, _ := ioutil.TempDir("", "test")
 	fileToUpdate := filepath.Join(tmpDir, "en-name.xml")
-	if err := ioutil.WriteFile(fileToUpdate, beforeWriteData.Bytes(), 644); err != nil {
+	if err := ioutil.WriteFile(fileToUpdate, xmlData.Bytes(), 644); err != nil {
 		panic(err)
 	}
 
-	format.Write(tmpDir, "en", "fr", "name", string(cleaned), FileLocators["LANG-NAME"])
+	translation := map[string]string{}
+	for key, item := range prop {
+		translation[key] = strings.Replace(item, "value", "valeur", 1)
+	}
+	translationAsBytes, _ := json.Marshal(translation)
+	if err := format.Write(tmpDir, "fr", "en", "name", string(translationAsBytes), FileLocators["LANG-NAME"]); err != nil {
+		t.Fatal(err)
+	}
 
 	updatedXmlData, _ := ioutil.ReadFile(filepath.Join(tmpDir, "fr-name.xml"))
 
-	tu.AssertEquals("", xmlData.String(), string(updatedXmlData), t)
+	expectedXmlData := new(bytes.Buffer)
+	tmpl.Execute(expectedXmlData, templateFiller{Key: "key", Value: "valeur"})
+	tu.AssertEquals("", expectedXmlData.String(), string(updatedXmlData), t)
+}
+
+func Test_Clean_KeyAttribute(t *testing.T) {
+	format := FlattenXmlToJson{}
+	format.Init(map[string]interface{}{"key": "@id"})
+
+	source := `<strings><string id="welcome" at="1">Welcome</string><string>Untitled</string><string>Unknown</string><group id="menu"><item>Open</item></group></strings>`
+	c, err := format.Parse([]byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("entries", 4, len(c.Entries), t)
+	tu.AssertEquals("attribute", "Welcome", c.Find("string<welcome>").Value, t)
+	tu.AssertEquals("ancestor", "Open", c.Find("group<menu> item").Value, t)
+
+	// a key that is not a string is ignored
+	other := FlattenXmlToJson{}
+	other.Init(map[string]interface{}{"key": 1})
+	tu.AssertEquals("not a string", "", other.key, t)
+	tu.AssertEquals("positional", "Unknown", c.Find("string<2>").Value, t)
+	_, lacking, _ := format.parse([]byte(source))
+	tu.AssertEquals("lacking", "string, string<2>", strings.Join(lacking, ", "), t)
+
+	// inserting a node does not change the keys of the nodes with an id
+	c, _ = format.Parse([]byte(strings.Replace(source, "<strings>", `<strings><string id="new">New</string>`, 1)))
+	tu.AssertEquals("stable", "Welcome", c.Find("string<welcome>").Value, t)
 }
 
 func Test_WriteUpdateExisting(t *testing.T) {
@@ -197,3 +231,70 @@ func Test_Write_MissingKey(t *testing.T) {
 		t.Errorf("Expected an error")
 	}
 }
+
+func Test_Write_Fidelity(t *testing.T) {
+	source := `<?xml version='1.0' encoding="utf-8"?>
+<!-- Strings of the plugin -->
+<strings xmlns:p="http://example.com/plugin" xmlns:q="http://example.com/other">
+	<?plugin keep?>
+	<p:item name='a'>Tom &amp; Jerry</p:item>
+	<q:item name='a'><![CDATA[<b>Bold</b>]]></q:item>
+	<item xml:lang="en"   name="b">Plain</item>
+</strings>
+`
+	format := FlattenXmlToJson{}
+	c, err := format.Parse([]byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("entries", 3, len(c.Entries), t)
+	tu.AssertEquals("prefix", "Tom & Jerry", c.Find("p:item[name=a]").Value, t)
+	tu.AssertEquals("cdata", "<b>Bold</b>", c.Find("q:item[name=a]").Value, t)
+	tu.AssertEquals("xml prefix", "Plain", c.Find("item[xml:lang=en and name=b]").Value, t)
+
+	translation := NewCatalog("fr")
+	translation.Add(&Entry{Key: "p:item[name=a]", Value: "Tom & Jerry <fr>"})
+	translation.Add(&Entry{Key: "q:item[name=a]", Value: "<b>Gras</b> ]]> fin"})
+	translation.Add(&Entry{Key: "item[xml:lang=en and name=b]", Value: "Simple"})
+	out, err := format.Serialize(translation, []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.NewReplacer(
+		"Tom &amp; Jerry", "Tom &amp; Jerry &lt;fr&gt;",
+		"<![CDATA[<b>Bold</b>]]>", "<![CDATA[<b>Gras</b> ]]]]><![CDATA[> fin]]>",
+		"Plain", "Simple").Replace(source)
+	tu.AssertEquals("fidelity", expected, string(out), t)
+}
+
+func Test_Clean_KeyPrefixedAttribute(t *testing.T) {
+	source := `<resources xmlns:android="http://schemas.android.com/apk/res/android" xmlns:p="http://example.com/plugin">
+	<string android:name="title">Title</string>
+	<string name="other">Other</string>
+	<p:group><p:key>menu</p:key><item>Open</item></p:group>
+</resources>`
+	format := FlattenXmlToJson{}
+	format.Init(map[string]interface{}{"key": "@android:name"})
+	c, lacking, err := format.parse([]byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("prefixed attribute", "Title", c.Find("string<title>").Value, t)
+	tu.AssertEquals("lacking", "string[name=other], p:group p:key, p:group item", strings.Join(lacking, ", "), t)
+
+	// the name without a prefix is not the android:name attribute
+	format.Init(map[string]interface{}{"key": "@name"})
+	c, _, _ = format.parse([]byte(source))
+	tu.AssertEquals("attribute", "Other", c.Find("string<other>").Value, t)
+	tu.AssertEquals("other namespace", "Title", c.Find("string[android:name=title]").Value, t)
+
+	// prefixed elements in the path
+	format.Init(map[string]interface{}{"key": "p:key"})
+	c, lacking, _ = format.parse([]byte(source))
+	tu.AssertEquals("prefixed child", "Open", c.Find("p:group<menu> item").Value, t)
+	tu.AssertEqualsInt("lacking", 2, len(lacking), t)
+	format.Init(map[string]interface{}{"key": "key"})
+	if c, _, _ = format.parse([]byte(source)); c.Find("p:group<menu> item") != nil {
+		t.Errorf("key must not match p:key")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
@@ -23,6 +24,12 @@ type Format interface {
 	// * new i18nType - it is possible that the format decided that in order to make the format useable it needed to convert it to a new type
 	// * an error or nil if no errors occurred
 	Clean([]byte) ([]byte, string, error)
+	// Parse the content of a translation file into a Catalog
+	Parse([]byte) (*Catalog, error)
+	// Serialize the catalog in the format.
+	// * template - the content of the source language file.  Formats that cannot be generated from the catalog
+	//   alone (for example because the file contains data that is not translated) replay the template
+	Serialize(catalog *Catalog, template []byte) ([]byte, error)
 	// Write a new translation to the correct translation file
 	// * rootDir - path to the root of the translation files directory tree
 	// * langCode - the language code of the translation
@@ -35,7 +42,39 @@ type Format interface {
 // factory methods for creating a format object
 var Formats = map[string]func()Format {
 	"KEYVALUEJSON": func()Format {return new(KeyValueJson)}, 
-	"FLATTENXMLTOJSON": func()Format {return new(FlattenXmlToJson)}}
+	"FLATTENXMLTOJSON": func()Format {return new(FlattenXmlToJson)},
+	"PO": func()Format {return new(Po)},
+	"ANDROID": func()Format {return new(Android)},
+	"STRINGS": func()Format {return new(IosStrings)},
+	"STRINGSDICT": func()Format {return new(IosStringsDict)},
+	"XCSTRINGS": func()Format {return new(XcStrings)},
+	"PROPERTIES": func()Format {return new(Properties)},
+	"NESTEDJSON": func()Format {return new(NestedJson)},
+	"YAML": func()Format {return new(Yaml)},
+	"XLIFF12": func()Format {return &Xliff{xliff12}},
+	"XLIFF20": func()Format {return &Xliff{xliff20}},
+	"ARB": func()Format {return new(Arb)},
+	"RESX": func()Format {return new(Resx)},
+	"QT": func()Format {return new(Qt)},
+	"CHROME": func()Format {return new(Chrome)},
+	"PHPARRAY": func()Format {return new(PhpArray)},
+	"CSV": func()Format {return new(Csv)},
+	"XLSX": func()Format {return new(Xlsx)},
+	"XLSXLANGS": func()Format {return new(XlsxLangs)},
+	"SRT": func()Format {return &Subtitles{srt}},
+	"VTT": func()Format {return &Subtitles{vtt}},
+	"HTML": func()Format {return &Document{htmlDocument}},
+	"MARKDOWN": func()Format {return &Document{markdownDocument}}}
+
+// A Format that stores the strings of all languages in one file.  The translations are listed from the
+// content of the file instead of by the FileLocator
+type MultiLangFormat interface {
+	Format
+	// the languages with strings in the file
+	Languages(content []byte) ([]string, error)
+	// Clean the strings of one language of the file
+	CleanLang(content []byte, lang string) ([]byte, string, error)
+}
 
 // Strategy for creating the path to a translation file
 type FileLocator interface {
@@ -47,6 +86,14 @@ type FileLocator interface {
 	List(path, name, ext string) (map[string]string, error)
 }
 
+// A FileLocator for structures where the source language file is not named after its language.
+// For example the values directory of android resources
+type SourceLangLocator interface {
+	FileLocator
+	// a copy of the locator with lang as the language of the source file
+	WithSourceLang(lang string) FileLocator
+}
+
 var identityMapper = func(lang string, reverse bool) string { return lang }
 
 var langCodeMapper2To3 = func(lang string, reverse bool) string {
@@ -67,7 +114,13 @@ var FileLocators = map[string]FileLocator{
 	"LANG-NAME":        LangNameLocator{identityMapper},
 	"3-CHAR-LANG-NAME": LangNameLocator{langCodeMapper2To3},
 	"3-CHAR-LOC-DIR":   LocDirLocator{langCodeMapper2To3},
-	"LOC-DIR":          LocDirLocator{identityMapper}}
+	"LOC-DIR":          LocDirLocator{identityMapper},
+	"ANDROID":          AndroidLocator{"en"},
+	"LPROJ":            LprojLocator{"en"},
+	"SINGLE-FILE":      SingleFileLocator{},
+	"RESOURCE-BUNDLE":  ResourceBundleLocator{"en"},
+	"NAME-LANG":        NameLangLocator{"en"},
+	"CHROME":           ChromeLocator{}}
 
 // All translation files in same directory and have pattern: lang-name.ext
 type LangNameLocator struct {
@@ -158,6 +211,299 @@ func (l LocDirLocator) List(path, name, ext string) (map[string]string, error) {
 	return translationFiles, nil
 }
 
+// Locate android resource files.  The source language file is in the values directory and the translations
+// are in the directories qualified by the language: values-fr, values-pt-rBR, values-b+sr+Latn
+// <root>
+// -- values
+//    -- strings.xml
+// -- values-pt-rBR
+//    -- strings.xml
+type AndroidLocator struct {
+	// the language of the file in the values directory
+	sourceLang string
+}
+
+func (l AndroidLocator) WithSourceLang(lang string) FileLocator {
+	return AndroidLocator{lang}
+}
+
+func (l AndroidLocator) Find(path, lang, name, ext string) string {
+	dir := "values"
+	if lang != l.sourceLang {
+		dir += "-" + androidQualifier(lang)
+		// keep the directory of the old language code if it is the one that exists: values-iw for he
+		parts := strings.Split(lang, "_")
+		for legacy, code := range androidLegacyLangCodes {
+			if parts[0] == code {
+				parts[0] = legacy
+				legacyDir := "values-" + androidQualifier(strings.Join(parts, "_"))
+				if isDir(filepath.Join(path, legacyDir)) && !isDir(filepath.Join(path, dir)) {
+					dir = legacyDir
+				}
+			}
+		}
+	}
+	return filepath.Join(path, dir, name+"."+ext)
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func (l AndroidLocator) List(path, name, ext string) (map[string]string, error) {
+	dirs, readErr := ioutil.ReadDir(path)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	translationFiles := map[string]string{}
+	for _, dir := range dirs {
+		lang := l.sourceLang
+		if dir.Name() != "values" {
+			qualifier := strings.TrimPrefix(dir.Name(), "values-")
+			if qualifier == dir.Name() || !androidLangQualifier.MatchString(qualifier) {
+				// other resource qualifiers: values-land, values-v21, values-night...
+				continue
+			}
+			lang = androidLang(qualifier)
+		}
+		fname := filepath.Join(path, dir.Name(), name+"."+ext)
+		if _, err := os.Stat(fname); err == nil {
+			translationFiles[lang] = fname
+		}
+	}
+	return translationFiles, nil
+}
+
+var androidLangQualifier = regexp.MustCompile(`^([a-z]{2,3}(-r([A-Z]{2}|[0-9]{3}))?|b\+[a-zA-Z0-9+]+)$`)
+var androidRegion = regexp.MustCompile(`^([A-Z]{2}|[0-9]{3})$`)
+
+// the old language codes android still uses for some languages
+var androidLegacyLangCodes = map[string]string{"iw": "he", "in": "id", "ji": "yi"}
+
+// the android resource qualifier of a transifex language code: fr, pt-rBR, b+sr+Latn
+func androidQualifier(lang string) string {
+	parts := strings.Split(lang, "_")
+	if len(parts) == 1 {
+		return lang
+	}
+	if len(parts) == 2 && androidRegion.MatchString(parts[1]) {
+		return parts[0] + "-r" + parts[1]
+	}
+	return "b+" + strings.Join(parts, "+")
+}
+
+// the transifex language code of an android resource qualifier
+func androidLang(qualifier string) string {
+	var parts []string
+	if strings.HasPrefix(qualifier, "b+") {
+		parts = strings.Split(qualifier[2:], "+")
+	} else {
+		parts = strings.Split(strings.Replace(qualifier, "-r", "-", 1), "-")
+	}
+	if code, has := androidLegacyLangCodes[parts[0]]; has {
+		parts[0] = code
+	}
+	return strings.Join(parts, "_")
+}
+
+// Locate the localization directories of an iOS/macOS bundle.  The language codes use - instead of _: pt-BR.lproj.
+// The source language file is in Base.lproj if there is one
+// <root>
+// -- Base.lproj
+//    -- Localizable.strings
+// -- fr.lproj
+//    -- Localizable.strings
+type LprojLocator struct {
+	// the language of the file in Base.lproj
+	sourceLang string
+}
+
+const baseLproj = "Base.lproj"
+
+func (l LprojLocator) WithSourceLang(lang string) FileLocator {
+	return LprojLocator{lang}
+}
+
+func (l LprojLocator) Find(path, lang, name, ext string) string {
+	fileName := name + "." + ext
+	if lang == l.sourceLang {
+		base := filepath.Join(path, baseLproj, fileName)
+		if _, err := os.Stat(base); err == nil {
+			return base
+		}
+	}
+	return filepath.Join(path, strings.Replace(lang, "_", "-", -1)+".lproj", fileName)
+}
+
+func (l LprojLocator) List(path, name, ext string) (map[string]string, error) {
+	dirs, readErr := ioutil.ReadDir(path)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	_, baseErr := os.Stat(filepath.Join(path, baseLproj, name+"."+ext))
+	translationFiles := map[string]string{}
+	for _, dir := range dirs {
+		if !strings.HasSuffix(dir.Name(), ".lproj") {
+			continue
+		}
+		lang := strings.Replace(strings.TrimSuffix(dir.Name(), ".lproj"), "-", "_", -1)
+		if dir.Name() == baseLproj {
+			lang = l.sourceLang
+		} else if lang == l.sourceLang && baseErr == nil {
+			// Base.lproj is the source file
+			continue
+		}
+		fname := filepath.Join(path, dir.Name(), name+"."+ext)
+		if _, err := os.Stat(fname); err == nil {
+			translationFiles[lang] = fname
+		}
+	}
+	return translationFiles, nil
+}
+
+// One file with all languages: <root>/name.ext.  Only for formats that store all languages in one file (MultiLangFormat)
+type SingleFileLocator struct{}
+
+func (l SingleFileLocator) Find(path, lang, name, ext string) string {
+	return filepath.Join(path, name+"."+ext)
+}
+
+func (l SingleFileLocator) List(path, name, ext string) (map[string]string, error) {
+	return nil, fmt.Errorf("The languages of %s are listed by its format, the SINGLE-FILE structure requires a format with all languages in one file", l.Find(path, "", name, ext))
+}
+
+// Locate the files of a java resource bundle: name_lang.ext, name_lang_REGION.ext in the same directory.
+// The source language file is the base file of the bundle (name.ext) if there is one
+type ResourceBundleLocator struct {
+	// the language of the base file
+	sourceLang string
+}
+
+var bundleLang = regexp.MustCompile(`^[a-z]{2,3}(_([A-Z]{2}|[0-9]{3}))?(_[A-Za-z0-9]+)?$`)
+
+func (l ResourceBundleLocator) WithSourceLang(lang string) FileLocator {
+	return ResourceBundleLocator{lang}
+}
+
+func (l ResourceBundleLocator) Find(path, lang, name, ext string) string {
+	if lang == l.sourceLang {
+		base := filepath.Join(path, name+"."+ext)
+		if _, err := os.Stat(base); err == nil {
+			return base
+		}
+	}
+	return filepath.Join(path, fmt.Sprintf("%s_%s.%s", name, lang, ext))
+}
+
+func (l ResourceBundleLocator) List(path, name, ext string) (map[string]string, error) {
+	candidates, readErr := ioutil.ReadDir(path)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	_, baseErr := os.Stat(filepath.Join(path, name+"."+ext))
+	translationFiles := map[string]string{}
+	for _, f := range candidates {
+		lang := strings.TrimSuffix(strings.TrimPrefix(f.Name(), name), "."+ext)
+		switch {
+		case f.Name() == name+"."+ext:
+			lang = l.sourceLang
+		case !strings.HasPrefix(lang, "_") || !strings.HasSuffix(f.Name(), "."+ext) || !bundleLang.MatchString(lang[1:]):
+			continue
+		case lang[1:] == l.sourceLang && baseErr == nil:
+			// the base file is the source file
+			continue
+		default:
+			lang = lang[1:]
+		}
+		translationFiles[lang] = filepath.Join(path, f.Name())
+	}
+	return translationFiles, nil
+}
+
+// Locate the files of .NET resources: name.lang.ext, name.pt-BR.ext in the same directory.
+// The source language file is the neutral file (name.ext) if there is one
+type NameLangLocator struct {
+	// the language of the neutral file
+	sourceLang string
+}
+
+var cultureName = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+func (l NameLangLocator) WithSourceLang(lang string) FileLocator {
+	return NameLangLocator{lang}
+}
+
+func (l NameLangLocator) Find(path, lang, name, ext string) string {
+	if lang == l.sourceLang {
+		neutral := filepath.Join(path, name+"."+ext)
+		if _, err := os.Stat(neutral); err == nil {
+			return neutral
+		}
+	}
+	return filepath.Join(path, fmt.Sprintf("%s.%s.%s", name, strings.Replace(lang, "_", "-", -1), ext))
+}
+
+func (l NameLangLocator) List(path, name, ext string) (map[string]string, error) {
+	candidates, readErr := ioutil.ReadDir(path)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	_, neutralErr := os.Stat(filepath.Join(path, name+"."+ext))
+	translationFiles := map[string]string{}
+	for _, f := range candidates {
+		culture := strings.TrimSuffix(strings.TrimPrefix(f.Name(), name+"."), "."+ext)
+		lang := strings.Replace(culture, "-", "_", -1)
+		switch {
+		case f.Name() == name+"."+ext:
+			lang = l.sourceLang
+		case !strings.HasPrefix(f.Name(), name+".") || !strings.HasSuffix(f.Name(), "."+ext) || !cultureName.MatchString(culture):
+			continue
+		case lang == l.sourceLang && neutralErr == nil:
+			// the neutral file is the source file
+			continue
+		}
+		translationFiles[lang] = filepath.Join(path, f.Name())
+	}
+	return translationFiles, nil
+}
+
+// Locate the messages of a chrome extension: _locales/<lang>/name.ext with underscore regional codes
+// (_locales/pt_BR/messages.json).  The path is the directory of the extension or the _locales directory
+type ChromeLocator struct{}
+
+func (l ChromeLocator) Find(path, lang, name, ext string) string {
+	return filepath.Join(chromeLocales(path), strings.Replace(lang, "-", "_", -1), fmt.Sprintf("%s.%s", name, ext))
+}
+
+func (l ChromeLocator) List(path, name, ext string) (map[string]string, error) {
+	locales := chromeLocales(path)
+	locDirs, readErr := ioutil.ReadDir(locales)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	translationFiles := map[string]string{}
+	for _, locDir := range locDirs {
+		fname := filepath.Join(locales, locDir.Name(), fmt.Sprintf("%s.%s", name, ext))
+		if _, err := os.Stat(fname); err == nil {
+			translationFiles[strings.Replace(locDir.Name(), "-", "_", -1)] = fname
+		}
+	}
+	return translationFiles, nil
+}
+
+func chromeLocales(path string) string {
+	if filepath.Base(path) == "_locales" {
+		return path
+	}
+	return filepath.Join(path, "_locales")
+}
+
 var threeToTwoLetterIsoCode map[string]string
 
 func init() {
@@ -33,7 +33,7 @@ func Test_LangNameLocator_List(t *testing.T) {
 
 	translations, err := l.List(root, "name", "json")
 	if err != nil {
-		t.Errorf(err.Error())
+		t.Error(err)
 	}
 
 	index := map[string]bool{}
@@ -72,7 +72,7 @@ func Test_LangNameLocator_List_3charlang(t *testing.T) {
 
 	translations, err := l.List(root, "name", "json")
 	if err != nil {
-		t.Errorf(err.Error())
+		t.Error(err)
 	}
 
 	index := map[string]bool{}
@@ -126,7 +126,7 @@ func Test_LocDirLocator_List(t *testing.T) {
 
 	translations, err := l.List(root, "name", "json")
 	if err != nil {
-		t.Errorf(err.Error())
+		t.Error(err)
 	}
 
 	index := map[string]bool{}
@@ -164,7 +164,7 @@ func Test_LocDirLocator_List_3charlang(t *testing.T) {
 
 	translations, err := l.List(root, "name", "json")
 	if err != nil {
-		t.Errorf(err.Error())
+		t.Error(err)
 	}
 
 	index := map[string]bool{}
@@ -192,11 +192,146 @@ func Test_LocDirLocator_List_3charlang(t *testing.T) {
 	}
 }
 
+func Test_AndroidLocator_Find(t *testing.T) {
+	l := FileLocators["ANDROID"]
+	tu.AssertEquals("", filepath.Join("res", "values", "strings.xml"), l.Find("res", "en", "strings", "xml"), t)
+	tu.AssertEquals("", filepath.Join("res", "values-fr", "strings.xml"), l.Find("res", "fr", "strings", "xml"), t)
+	tu.AssertEquals("", filepath.Join("res", "values-pt-rBR", "strings.xml"), l.Find("res", "pt_BR", "strings", "xml"), t)
+	tu.AssertEquals("", filepath.Join("res", "values-es-r419", "strings.xml"), l.Find("res", "es_419", "strings", "xml"), t)
+	tu.AssertEquals("", filepath.Join("res", "values-b+sr+Latn", "strings.xml"), l.Find("res", "sr_Latn", "strings", "xml"), t)
+	tu.AssertEquals("", filepath.Join("res", "values-he", "strings.xml"), l.Find("res", "he", "strings", "xml"), t)
+
+	l = l.(SourceLangLocator).WithSourceLang("de")
+	tu.AssertEquals("", filepath.Join("res", "values", "strings.xml"), l.Find("res", "de", "strings", "xml"), t)
+	tu.AssertEquals("", filepath.Join("res", "values-en", "strings.xml"), l.Find("res", "en", "strings", "xml"), t)
+}
+
+func Test_AndroidLocator_List(t *testing.T) {
+	l := FileLocators["ANDROID"]
+	root, _ := ioutil.TempDir("", "root")
+
+	for _, dir := range []string{"values", "values-fr", "values-pt-rBR", "values-b+zh+Hant", "values-iw", "values-land", "values-v21"} {
+		createFile(root, dir, "strings.xml", t)
+	}
+	createFile(root, "values-de", "colors.xml", t)
+
+	translations, err := l.List(root, "strings", "xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{"en": "values", "fr": "values-fr", "pt_BR": "values-pt-rBR", "zh_Hant": "values-b+zh+Hant", "he": "values-iw"}
+	tu.AssertEqualsInt("translations", len(expected), len(translations), t)
+	for lang, dir := range expected {
+		tu.AssertEquals(lang, filepath.Join(root, dir, "strings.xml"), translations[lang], t)
+	}
+
+	// the translations are written to the directories that were listed
+	for lang, dir := range expected {
+		tu.AssertEquals("find "+lang, filepath.Join(root, dir, "strings.xml"), l.Find(root, lang, "strings", "xml"), t)
+	}
+	createFile(root, "values-in-rID", "strings.xml", t)
+	tu.AssertEquals("legacy region", filepath.Join(root, "values-in-rID", "strings.xml"), l.Find(root, "id_ID", "strings", "xml"), t)
+	tu.AssertEquals("new language", filepath.Join(root, "values-yi", "strings.xml"), l.Find(root, "yi", "strings", "xml"), t)
+}
+
+func Test_LprojLocator(t *testing.T) {
+	l := FileLocators["LPROJ"]
+	root, _ := ioutil.TempDir("", "root")
+
+	tu.AssertEquals("", filepath.Join(root, "en.lproj", "Localizable.strings"), l.Find(root, "en", "Localizable", "strings"), t)
+	tu.AssertEquals("", filepath.Join(root, "pt-BR.lproj", "Localizable.strings"), l.Find(root, "pt_BR", "Localizable", "strings"), t)
+
+	for _, dir := range []string{"Base.lproj", "en.lproj", "fr.lproj", "zh-Hans.lproj", "Resources"} {
+		createFile(root, dir, "Localizable.strings", t)
+	}
+	tu.AssertEquals("", filepath.Join(root, "Base.lproj", "Localizable.strings"), l.Find(root, "en", "Localizable", "strings"), t)
+
+	translations, err := l.List(root, "Localizable", "strings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"en": "Base.lproj", "fr": "fr.lproj", "zh_Hans": "zh-Hans.lproj"}
+	tu.AssertEqualsInt("translations", len(expected), len(translations), t)
+	for lang, dir := range expected {
+		tu.AssertEquals(lang, filepath.Join(root, dir, "Localizable.strings"), translations[lang], t)
+	}
+}
+
+func Test_ResourceBundleLocator(t *testing.T) {
+	l := FileLocators["RESOURCE-BUNDLE"]
+	root, _ := ioutil.TempDir("", "root")
+
+	tu.AssertEquals("", filepath.Join(root, "messages_en.properties"), l.Find(root, "en", "messages", "properties"), t)
+	tu.AssertEquals("", filepath.Join(root, "messages_pt_BR.properties"), l.Find(root, "pt_BR", "messages", "properties"), t)
+
+	for _, name := range []string{"messages.properties", "messages_en.properties", "messages_fr.properties", "messages_pt_BR.properties", "messages_admin.properties", "messages_de.txt", "other_it.properties"} {
+		os.Create(filepath.Join(root, name))
+	}
+	tu.AssertEquals("", filepath.Join(root, "messages.properties"), l.Find(root, "en", "messages", "properties"), t)
+
+	translations, err := l.List(root, "messages", "properties")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"en": "messages.properties", "fr": "messages_fr.properties", "pt_BR": "messages_pt_BR.properties"}
+	tu.AssertEqualsInt("translations", len(expected), len(translations), t)
+	for lang, name := range expected {
+		tu.AssertEquals(lang, filepath.Join(root, name), translations[lang], t)
+	}
+}
+
+func Test_NameLangLocator(t *testing.T) {
+	l := FileLocators["NAME-LANG"]
+	root, _ := ioutil.TempDir("", "root")
+
+	tu.AssertEquals("", filepath.Join(root, "Strings.en.resx"), l.Find(root, "en", "Strings", "resx"), t)
+	tu.AssertEquals("", filepath.Join(root, "Strings.pt-BR.resx"), l.Find(root, "pt_BR", "Strings", "resx"), t)
+
+	for _, name := range []string{"Strings.resx", "Strings.en.resx", "Strings.fr.resx", "Strings.pt-BR.resx", "Strings.zh-Hans.resx", "Strings.Designer.cs", "Strings.Designer.resx", "Other.de.resx"} {
+		os.Create(filepath.Join(root, name))
+	}
+	tu.AssertEquals("", filepath.Join(root, "Strings.resx"), l.Find(root, "en", "Strings", "resx"), t)
+
+	translations, err := l.List(root, "Strings", "resx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"en": "Strings.resx", "fr": "Strings.fr.resx", "pt_BR": "Strings.pt-BR.resx", "zh_Hans": "Strings.zh-Hans.resx"}
+	tu.AssertEqualsInt("translations", len(expected), len(translations), t)
+	for lang, name := range expected {
+		tu.AssertEquals(lang, filepath.Join(root, name), translations[lang], t)
+	}
+
+	translations, _ = l.(SourceLangLocator).WithSourceLang("de").List(root, "Strings", "resx")
+	tu.AssertEquals("source lang", filepath.Join(root, "Strings.resx"), translations["de"], t)
+}
+
+func Test_ChromeLocator(t *testing.T) {
+	l := FileLocators["CHROME"]
+	root, _ := ioutil.TempDir("", "root")
+
+	tu.AssertEquals("", filepath.Join(root, "_locales", "pt_BR", "messages.json"), l.Find(root, "pt-BR", "messages", "json"), t)
+	tu.AssertEquals("locales dir", filepath.Join(root, "_locales", "fr", "messages.json"), l.Find(filepath.Join(root, "_locales"), "fr", "messages", "json"), t)
+
+	os.Mkdir(filepath.Join(root, "_locales"), 0755)
+	createFile(filepath.Join(root, "_locales"), "en", "messages.json", t)
+	createFile(filepath.Join(root, "_locales"), "pt_BR", "messages.json", t)
+	createFile(filepath.Join(root, "_locales"), "de", "other.json", t)
+
+	translations, err := l.List(root, "messages", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("translations", 2, len(translations), t)
+	tu.AssertEquals("", filepath.Join(root, "_locales", "pt_BR", "messages.json"), translations["pt_BR"], t)
+}
+
 func createFile(root, loc, name string, t *testing.T) {
 	locDir := filepath.Join(root, loc)
 	os.Mkdir(locDir, 644)
 	if _, err := os.Create(filepath.Join(locDir, name)); err != nil {
-		t.Errorf(err.Error())
+		t.Error(err)
 	}
 
 }
@@ -0,0 +1,321 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// iOS/macOS .strings files: "key" = "value"; pairs with /* comments */.  Files are read as UTF-8 or UTF-16
+// (detected by the byte order mark) and translations are written in the encoding of the source file.
+//
+// The comment directly before a string is uploaded as the developer comment, a comment at the start of the file
+// that is separated from the first string by a blank line is kept as the comment of the file.
+// Strings without a translation are not written so that iOS falls back to the development language
+type IosStrings struct{}
+
+func (f *IosStrings) Init(initParams map[string]interface{}) {}
+func (f *IosStrings) Ext() string                            { return "strings" }
+
+func (f *IosStrings) Parse(content []byte) (*Catalog, error) {
+	text, _, err := decodeText(content)
+	if err != nil {
+		return nil, err
+	}
+	p := &stringsParser{data: []rune(text), line: 1}
+	c := NewCatalog("")
+	for {
+		p.skipSpace()
+		if p.eof() {
+			break
+		}
+		line := p.line
+		key, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		value := key
+		p.skipSpace()
+		if p.peek() == '=' {
+			p.pos++
+			p.skipSpace()
+			if value, err = p.token(); err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+		}
+		if p.peek() != ';' {
+			return nil, fmt.Errorf("Invalid strings file: missing ; after the string %q on line %d", key, line)
+		}
+		p.pos++
+
+		e := &Entry{Key: key, Value: value}
+		if n := len(p.comments); n > 0 {
+			if !p.comments[n-1].blankAfter {
+				e.Comment = p.comments[n-1].text
+				p.comments = p.comments[:n-1]
+			}
+			if len(c.Entries) == 0 {
+				texts := []string{}
+				for _, comment := range p.comments {
+					texts = append(texts, comment.text)
+				}
+				c.Comment = strings.Join(texts, "\n")
+			}
+			p.comments = nil
+		}
+		c.Add(e)
+	}
+	return c, nil
+}
+
+func (f *IosStrings) Clean(content []byte) ([]byte, string, error) {
+	return cleanToStructuredJson(f, content)
+}
+
+func (f *IosStrings) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	var out bytes.Buffer
+	if c.Comment != "" {
+		fmt.Fprintf(&out, "%s\n\n", formatStringsComment(c.Comment))
+	}
+	for _, e := range c.Entries {
+		if e.Value == "" {
+			continue
+		}
+		if e.Comment != "" {
+			fmt.Fprintf(&out, "%s\n", formatStringsComment(e.Comment))
+		}
+		fmt.Fprintf(&out, "%s = %s;\n\n", quoteStrings(e.Key), quoteStrings(e.Value))
+	}
+	_, encoding, err := decodeText(template)
+	if err != nil {
+		return nil, err
+	}
+	return encodeText(strings.TrimSuffix(out.String(), "\n"), encoding), nil
+}
+
+func (f *IosStrings) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	return writeCatalog(f, rootDir, langCode, srcLang, filename, translation, fileLocator)
+}
+
+type stringsComment struct {
+	text string
+	// true if the comment is followed by an empty line
+	blankAfter bool
+}
+
+type stringsParser struct {
+	data     []rune
+	pos      int
+	line     int
+	comments []stringsComment
+}
+
+func (p *stringsParser) eof() bool { return p.pos >= len(p.data) }
+
+func (p *stringsParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.data[p.pos]
+}
+
+func (p *stringsParser) next() rune {
+	r := p.data[p.pos]
+	p.pos++
+	if r == '\n' {
+		p.line++
+	}
+	return r
+}
+
+// skip whitespace and comments, the comments are collected
+func (p *stringsParser) skipSpace() {
+	newlines := 0
+	for !p.eof() {
+		r := p.peek()
+		switch {
+		case r == '\n':
+			newlines++
+			p.next()
+		case r == ' ' || r == '\t' || r == '\r' || r == '\ufeff':
+			p.next()
+		case r == '/' && p.pos+1 < len(p.data) && (p.data[p.pos+1] == '*' || p.data[p.pos+1] == '/'):
+			if n := len(p.comments); n > 0 && newlines > 1 {
+				p.comments[n-1].blankAfter = true
+			}
+			p.comments = append(p.comments, stringsComment{text: p.comment()})
+			newlines = 0
+		default:
+			if n := len(p.comments); n > 0 && newlines > 1 {
+				p.comments[n-1].blankAfter = true
+			}
+			return
+		}
+	}
+}
+
+func (p *stringsParser) comment() string {
+	start := p.pos + 2
+	block := p.data[p.pos+1] == '*'
+	p.pos += 2
+	for !p.eof() {
+		if block && p.peek() == '*' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '/' {
+			text := string(p.data[start:p.pos])
+			p.pos += 2
+			return strings.TrimSpace(text)
+		}
+		if !block && p.peek() == '\n' {
+			break
+		}
+		p.next()
+	}
+	return strings.TrimSpace(string(p.data[start:p.pos]))
+}
+
+// a quoted string or an unquoted word
+func (p *stringsParser) token() (string, error) {
+	if p.peek() != '"' {
+		start := p.pos
+		for !p.eof() && isUnquotedStringsRune(p.peek()) {
+			p.next()
+		}
+		if start == p.pos {
+			return "", fmt.Errorf("Invalid strings file: unexpected %q on line %d", p.peek(), p.line)
+		}
+		return string(p.data[start:p.pos]), nil
+	}
+
+	line := p.line
+	p.next()
+	var out []rune
+	for {
+		if p.eof() {
+			return "", fmt.Errorf("Invalid strings file: the string starting on line %d is not terminated", line)
+		}
+		r := p.next()
+		switch r {
+		case '"':
+			return string(out), nil
+		case '\\':
+			if p.eof() {
+				continue
+			}
+			out = p.escape(out)
+		default:
+			out = append(out, r)
+		}
+	}
+}
+
+func (p *stringsParser) escape(out []rune) []rune {
+	r := p.next()
+	switch r {
+	case 'n':
+		return append(out, '\n')
+	case 't':
+		return append(out, '\t')
+	case 'r':
+		return append(out, '\r')
+	case 'U', 'u':
+		if p.pos+4 <= len(p.data) {
+			if code, err := strconv.ParseUint(string(p.data[p.pos:p.pos+4]), 16, 32); err == nil {
+				p.pos += 4
+				// characters outside the BMP are written as surrogate pairs: \UD83D\UDE00
+				if n := len(out); n > 0 && utf16.IsSurrogate(out[n-1]) {
+					if decoded := utf16.DecodeRune(out[n-1], rune(code)); decoded != utf8.RuneError {
+						return append(out[:n-1], decoded)
+					}
+				}
+				return append(out, rune(code))
+			}
+		}
+		return append(out, r)
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		end := p.pos
+		for end < len(p.data) && end < p.pos+2 && p.data[end] >= '0' && p.data[end] <= '7' {
+			end++
+		}
+		code, _ := strconv.ParseUint(string(r)+string(p.data[p.pos:end]), 8, 32)
+		p.pos = end
+		return append(out, rune(code))
+	default:
+		return append(out, r)
+	}
+}
+
+func isUnquotedStringsRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_$.:/-", r)
+}
+
+var stringsEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+
+func quoteStrings(s string) string {
+	return `"` + stringsEscaper.Replace(s) + `"`
+}
+
+func formatStringsComment(text string) string {
+	return "/* " + strings.Replace(text, "*/", "* /", -1) + " */"
+}
+
+// the encodings of text files detected by decodeText
+const (
+	encodingUTF8    = "UTF-8"
+	encodingUTF16LE = "UTF-16LE"
+	encodingUTF16BE = "UTF-16BE"
+)
+
+// Decode a text file that is either UTF-8 or UTF-16.  UTF-16 is detected by the byte order mark
+// or, without one, by a zero byte in the first character
+func decodeText(content []byte) (string, string, error) {
+	var order binary.ByteOrder
+	encoding := encodingUTF8
+	switch {
+	case bytes.HasPrefix(content, []byte{0xff, 0xfe}):
+		order, encoding, content = binary.LittleEndian, encodingUTF16LE, content[2:]
+	case bytes.HasPrefix(content, []byte{0xfe, 0xff}):
+		order, encoding, content = binary.BigEndian, encodingUTF16BE, content[2:]
+	case len(content) >= 2 && content[0] == 0:
+		order, encoding = binary.BigEndian, encodingUTF16BE
+	case len(content) >= 2 && content[1] == 0:
+		order, encoding = binary.LittleEndian, encodingUTF16LE
+	default:
+		content = bytes.TrimPrefix(content, []byte{0xef, 0xbb, 0xbf})
+		if !utf8.Valid(content) {
+			return "", "", fmt.Errorf("The file is not valid UTF-8 or UTF-16")
+		}
+		return string(content), encoding, nil
+	}
+
+	if len(content)%2 != 0 {
+		return "", "", fmt.Errorf("The file is not valid %s, it has an odd number of bytes", encoding)
+	}
+	units := make([]uint16, len(content)/2)
+	for i := range units {
+		units[i] = order.Uint16(content[2*i:])
+	}
+	return string(utf16.Decode(units)), encoding, nil
+}
+
+// Encode the text in one of the encodings returned by decodeText.  UTF-16 is written with a byte order mark
+func encodeText(text, encoding string) []byte {
+	var order binary.ByteOrder
+	switch encoding {
+	case encodingUTF16LE:
+		order = binary.LittleEndian
+	case encodingUTF16BE:
+		order = binary.BigEndian
+	default:
+		return []byte(text)
+	}
+	units := utf16.Encode([]rune("\ufeff" + text))
+	out := make([]byte, 2*len(units))
+	for i, unit := range units {
+		order.PutUint16(out[2*i:], unit)
+	}
+	return out
+}
@@ -0,0 +1,76 @@
+package format
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	tu "testutil"
+)
+
+const iosStringsSource = `/*
+  Localizable.strings
+  Catalog
+*/
+
+/* The title of the main screen */
+"title" = "Don't \"panic\"";
+
+// The greeting
+"welcome" = "Hello\n%@";
+"emoji" = "\UD83D\UDE00 é";
+untranslated = "Not translated";
+`
+
+func Test_IosStrings_Parse(t *testing.T) {
+	for _, content := range [][]byte{[]byte(iosStringsSource), encodeText(iosStringsSource, encodingUTF16LE), encodeText(iosStringsSource, encodingUTF16BE)} {
+		c, err := new(IosStrings).Parse(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tu.AssertEqualsInt("entries", 4, len(c.Entries), t)
+		tu.AssertEquals("file comment", "Localizable.strings\n  Catalog", c.Comment, t)
+		tu.AssertEquals("escaped", `Don't "panic"`, c.Find("title").Value, t)
+		tu.AssertEquals("comment", "The title of the main screen", c.Find("title").Comment, t)
+		tu.AssertEquals("line comment", "The greeting", c.Find("welcome").Comment, t)
+		tu.AssertEquals("newline", "Hello\n%@", c.Find("welcome").Value, t)
+		tu.AssertEquals("unicode", "\U0001F600 é", c.Find("emoji").Value, t)
+		tu.AssertEquals("no comment", "", c.Find("emoji").Comment, t)
+		tu.AssertEquals("unquoted", "Not translated", c.Find("untranslated").Value, t)
+	}
+
+	if _, err := new(IosStrings).Parse([]byte(`"a" = "b"` + "\n" + `"c" = "d";`)); err == nil {
+		t.Errorf("Expected an error for a missing ;")
+	}
+}
+
+func Test_IosStrings_Write(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	createFile(tmpDir, "Base.lproj", "Localizable.strings", t)
+	ioutil.WriteFile(filepath.Join(tmpDir, "Base.lproj", "Localizable.strings"), encodeText(iosStringsSource, encodingUTF16LE), 0644)
+
+	data, _ := json.Marshal(map[string]string{"title": `Pas de "panique"`, "welcome": "Bonjour\n%@", "emoji": "😀"})
+	if err := new(IosStrings).Write(tmpDir, "fr", "en", "Localizable", string(data), FileLocators["LPROJ"]); err != nil {
+		t.Fatal(err)
+	}
+
+	written := mustRead(filepath.Join(tmpDir, "fr.lproj", "Localizable.strings"))
+	text, encoding, err := decodeText(written)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("encoding", encodingUTF16LE, encoding, t)
+	expected := `/* Localizable.strings
+  Catalog */
+
+/* The title of the main screen */
+"title" = "Pas de \"panique\"";
+
+/* The greeting */
+"welcome" = "Bonjour\n%@";
+
+"emoji" = "😀";
+`
+	tu.AssertEquals("", expected, text, t)
+}
@@ -0,0 +1,262 @@
+package format
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// iOS/macOS .stringsdict property lists with the plural variations of strings.
+//
+// A string whose format is a single variable (%#@files@) is uploaded as one plural string keyed by the key of
+// the string.  Otherwise the format is uploaded as a string and each variable as a plural string keyed by key:variable.
+// When writing a translation the source file is the template, strings that are not completely translated are left out
+type IosStringsDict struct{}
+
+func (f *IosStringsDict) Init(initParams map[string]interface{}) {}
+func (f *IosStringsDict) Ext() string                            { return "stringsdict" }
+
+const (
+	stringsDictFormatKey = "NSStringLocalizedFormatKey"
+	stringsDictSpecType  = "NSStringFormatSpecTypeKey"
+	stringsDictPlural    = "NSStringPluralRuleType"
+)
+
+func (f *IosStringsDict) Parse(content []byte) (*Catalog, error) {
+	root, err := parsePlist(content)
+	if err != nil {
+		return nil, err
+	}
+	c := NewCatalog("")
+	for i, key := range root.keys {
+		def := root.values[i]
+		if def.tag != "dict" || def.get(stringsDictFormatKey) == nil {
+			continue
+		}
+		variables := stringsDictVariables(def)
+		if isSingleStringsDictVariable(def, variables) {
+			c.Add(&Entry{Key: key, Plurals: stringsDictPlurals(def.get(variables[0]))})
+			continue
+		}
+		c.Add(&Entry{Key: key, Value: def.get(stringsDictFormatKey).text})
+		for _, variable := range variables {
+			c.Add(&Entry{Key: key + ":" + variable, Plurals: stringsDictPlurals(def.get(variable))})
+		}
+	}
+	return c, nil
+}
+
+func (f *IosStringsDict) Clean(content []byte) ([]byte, string, error) {
+	return cleanToStructuredJson(f, content)
+}
+
+func (f *IosStringsDict) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	if template == nil {
+		return nil, fmt.Errorf("The stringsdict format requires the source file to write a translation")
+	}
+	root, err := parsePlist(template)
+	if err != nil {
+		return nil, err
+	}
+
+	translated := &plistNode{tag: "dict"}
+	for i, key := range root.keys {
+		def := root.values[i]
+		if def.tag != "dict" || def.get(stringsDictFormatKey) == nil {
+			continue
+		}
+		variables := stringsDictVariables(def)
+		if isSingleStringsDictVariable(def, variables) {
+			if e := c.Find(key); e == nil || !setStringsDictPlurals(def.get(variables[0]), e) {
+				continue
+			}
+		} else {
+			e := c.Find(key)
+			complete := e != nil && e.Value != ""
+			for _, variable := range variables {
+				v := c.Find(key + ":" + variable)
+				complete = complete && v != nil && setStringsDictPlurals(def.get(variable), v)
+			}
+			if !complete {
+				continue
+			}
+			def.get(stringsDictFormatKey).text = e.Value
+		}
+		translated.keys = append(translated.keys, key)
+		translated.values = append(translated.values, def)
+	}
+
+	var out bytes.Buffer
+	out.WriteString(xml.Header)
+	out.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	out.WriteString(`<plist version="1.0">` + "\n")
+	translated.write(&out, "")
+	out.WriteString("\n</plist>\n")
+	return out.Bytes(), nil
+}
+
+func (f *IosStringsDict) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	return writeCatalog(f, rootDir, langCode, srcLang, filename, translation, fileLocator)
+}
+
+// the names of the plural variables of a string definition
+func stringsDictVariables(def *plistNode) []string {
+	variables := []string{}
+	for i, key := range def.keys {
+		if spec := def.values[i].get(stringsDictSpecType); spec != nil && spec.text == stringsDictPlural {
+			variables = append(variables, key)
+		}
+	}
+	return variables
+}
+
+// true if the format of the string is only the plural variable: %#@files@
+func isSingleStringsDictVariable(def *plistNode, variables []string) bool {
+	return len(variables) == 1 && def.get(stringsDictFormatKey).text == "%#@"+variables[0]+"@"
+}
+
+func stringsDictPlurals(variable *plistNode) map[string]string {
+	plurals := map[string]string{}
+	for _, category := range PluralCategories {
+		if value := variable.get(category); value != nil {
+			plurals[category] = value.text
+		}
+	}
+	return plurals
+}
+
+// Replace the plural forms of the variable with the translated forms.  Returns false if there is no translation
+func setStringsDictPlurals(variable *plistNode, e *Entry) bool {
+	if !hasPluralTranslation(e) {
+		return false
+	}
+	keys, values := []string{}, []*plistNode{}
+	for i, key := range variable.keys {
+		if !isPluralCategory(key) {
+			keys, values = append(keys, key), append(values, variable.values[i])
+		}
+	}
+	for _, category := range e.PluralForms() {
+		if e.Plurals[category] != "" {
+			keys, values = append(keys, category), append(values, &plistNode{tag: "string", text: e.Plurals[category]})
+		}
+	}
+	variable.keys, variable.values = keys, values
+	return true
+}
+
+func isPluralCategory(key string) bool {
+	for _, category := range PluralCategories {
+		if key == category {
+			return true
+		}
+	}
+	return false
+}
+
+// A value of a property list.  Dictionaries keep the order of their keys, other values are kept as their text
+type plistNode struct {
+	// the element of the value: dict, array, string, integer, true ...
+	tag  string
+	text string
+	// the keys of a dict
+	keys []string
+	// the values of a dict or the elements of an array
+	values []*plistNode
+}
+
+// the value of the key of a dict or nil
+func (n *plistNode) get(key string) *plistNode {
+	if n == nil {
+		return nil
+	}
+	for i, k := range n.keys {
+		if k == key {
+			return n.values[i]
+		}
+	}
+	return nil
+}
+
+// write the value in the layout used by Xcode, indented with tabs
+func (n *plistNode) write(out *bytes.Buffer, indent string) {
+	switch n.tag {
+	case "dict", "array":
+		if len(n.values) == 0 {
+			fmt.Fprintf(out, "<%s/>", n.tag)
+			return
+		}
+		fmt.Fprintf(out, "<%s>\n", n.tag)
+		for i, value := range n.values {
+			if n.tag == "dict" {
+				fmt.Fprintf(out, "%s\t<key>%s</key>\n", indent, xmlTextEscaper.Replace(n.keys[i]))
+			}
+			out.WriteString(indent + "\t")
+			value.write(out, indent+"\t")
+			out.WriteString("\n")
+		}
+		fmt.Fprintf(out, "%s</%s>", indent, n.tag)
+	case "true", "false":
+		fmt.Fprintf(out, "<%s/>", n.tag)
+	default:
+		fmt.Fprintf(out, "<%s>%s</%s>", n.tag, xmlTextEscaper.Replace(n.text), n.tag)
+	}
+}
+
+// Parse a property list, the root value must be a dict
+func parsePlist(content []byte) (*plistNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("The property list does not contain a dict")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local != "plist" {
+			if start.Name.Local != "dict" {
+				return nil, fmt.Errorf("The root of the property list must be a dict, not %s", start.Name.Local)
+			}
+			return readPlistNode(decoder, start)
+		}
+	}
+}
+
+func readPlistNode(decoder *xml.Decoder, start xml.StartElement) (*plistNode, error) {
+	n := &plistNode{tag: start.Name.Local}
+	if n.tag != "dict" && n.tag != "array" {
+		if err := decoder.DecodeElement(&n.text, &start); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+
+	key := ""
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "key" {
+				if err := decoder.DecodeElement(&key, &t); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			value, err := readPlistNode(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+			if n.tag == "dict" {
+				n.keys = append(n.keys, key)
+			}
+			n.values = append(n.values, value)
+		case xml.EndElement:
+			return n, nil
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package format
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	tu "testutil"
+)
+
+const iosStringsDictSource = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>%d files</key>
+	<dict>
+		<key>NSStringLocalizedFormatKey</key>
+		<string>%#@files@</string>
+		<key>files</key>
+		<dict>
+			<key>NSStringFormatSpecTypeKey</key>
+			<string>NSStringPluralRuleType</string>
+			<key>NSStringFormatValueTypeKey</key>
+			<string>d</string>
+			<key>one</key>
+			<string>%d file</string>
+			<key>other</key>
+			<string>%d files</string>
+		</dict>
+	</dict>
+	<key>%d files in %d folders</key>
+	<dict>
+		<key>NSStringLocalizedFormatKey</key>
+		<string>%#@files@ in %#@folders@</string>
+		<key>files</key>
+		<dict>
+			<key>NSStringFormatSpecTypeKey</key>
+			<string>NSStringPluralRuleType</string>
+			<key>NSStringFormatValueTypeKey</key>
+			<string>d</string>
+			<key>one</key>
+			<string>%d file</string>
+			<key>other</key>
+			<string>%d files</string>
+		</dict>
+		<key>folders</key>
+		<dict>
+			<key>NSStringFormatSpecTypeKey</key>
+			<string>NSStringPluralRuleType</string>
+			<key>NSStringFormatValueTypeKey</key>
+			<string>d</string>
+			<key>one</key>
+			<string>%d folder</string>
+			<key>other</key>
+			<string>%d folders</string>
+		</dict>
+	</dict>
+</dict>
+</plist>
+`
+
+func Test_IosStringsDict_Parse(t *testing.T) {
+	c, err := new(IosStringsDict).Parse([]byte(iosStringsDictSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tu.AssertEqualsInt("entries", 4, len(c.Entries), t)
+	tu.AssertEquals("single variable", "%d files", c.Find("%d files").Plurals["other"], t)
+	tu.AssertEquals("format", "%#@files@ in %#@folders@", c.Find("%d files in %d folders").Value, t)
+	tu.AssertEquals("variable", "%d folder", c.Find("%d files in %d folders:folders").Plurals["one"], t)
+
+	cleaned, _, err := new(IosStringsDict).Clean([]byte(iosStringsDictSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data map[string]map[string]string
+	json.Unmarshal(cleaned, &data)
+	tu.AssertEquals("icu", "{cnt, plural, one {%d file} other {%d files}}", data["%d files"]["string"], t)
+}
+
+func Test_IosStringsDict_Write(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	createFile(tmpDir, "en.lproj", "Localizable.stringsdict", t)
+	ioutil.WriteFile(filepath.Join(tmpDir, "en.lproj", "Localizable.stringsdict"), []byte(iosStringsDictSource), 0644)
+
+	// the second string is not completely translated
+	data, _ := json.Marshal(map[string]string{
+		"%d files":                     "{cnt, plural, one {%d файл} few {%d файла} many {%d файлов} other {%d файла}}",
+		"%d files in %d folders":       "%#@files@ в %#@folders@",
+		"%d files in %d folders:files": "{cnt, plural, one {%d файл} other {%d файла}}"})
+	if err := new(IosStringsDict).Write(tmpDir, "ru", "en", "Localizable", string(data), FileLocators["LPROJ"]); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>%d files</key>
+	<dict>
+		<key>NSStringLocalizedFormatKey</key>
+		<string>%#@files@</string>
+		<key>files</key>
+		<dict>
+			<key>NSStringFormatSpecTypeKey</key>
+			<string>NSStringPluralRuleType</string>
+			<key>NSStringFormatValueTypeKey</key>
+			<string>d</string>
+			<key>one</key>
+			<string>%d файл</string>
+			<key>few</key>
+			<string>%d файла</string>
+			<key>many</key>
+			<string>%d файлов</string>
+			<key>other</key>
+			<string>%d файла</string>
+		</dict>
+	</dict>
+</dict>
+</plist>
+`
+	tu.AssertEquals("", expected, string(mustRead(filepath.Join(tmpDir, "ru.lproj", "Localizable.stringsdict"))), t)
+}
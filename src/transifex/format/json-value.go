@@ -0,0 +1,192 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// A json value that keeps the order of the members of objects.  Used by the formats that rewrite json files
+// and must keep the data that is not translated as it is
+type jsonValue struct {
+	// '{' for objects, '[' for arrays and 0 for strings, numbers, booleans and null
+	kind    byte
+	members []jsonMember
+	items   []*jsonValue
+	// the encoded value of strings, numbers, booleans and null
+	raw json.RawMessage
+}
+
+type jsonMember struct {
+	key   string
+	value *jsonValue
+}
+
+func parseJsonValue(content []byte) (*jsonValue, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("Not valid json: %s", err)
+	}
+	switch token {
+	case json.Delim('{'):
+		v := &jsonValue{kind: '{'}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, fmt.Errorf("Not valid json: %s", err)
+			}
+			member, err := decodeJsonValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			v.members = append(v.members, jsonMember{key.(string), member})
+		}
+		return v, nil
+	case json.Delim('['):
+		v := &jsonValue{kind: '['}
+		for decoder.More() {
+			item, err := decodeJsonValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			v.items = append(v.items, item)
+		}
+		return v, nil
+	}
+	return &jsonValue{raw: json.RawMessage(bytes.TrimSpace(content))}, nil
+}
+
+func decodeJsonValue(decoder *json.Decoder) (*jsonValue, error) {
+	var raw json.RawMessage
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("Not valid json: %s", err)
+	}
+	return parseJsonValue(raw)
+}
+
+func newJsonString(s string) *jsonValue {
+	raw, _ := marshalJson(s)
+	return &jsonValue{raw: raw}
+}
+
+func newJsonObject() *jsonValue {
+	return &jsonValue{kind: '{'}
+}
+
+// the value of the member of an object or nil
+func (v *jsonValue) get(key string) *jsonValue {
+	if v == nil {
+		return nil
+	}
+	for _, m := range v.members {
+		if m.key == key {
+			return m.value
+		}
+	}
+	return nil
+}
+
+// the value of a string, empty for other values
+func (v *jsonValue) str() string {
+	var s string
+	if v != nil && v.kind == 0 {
+		json.Unmarshal(v.raw, &s)
+	}
+	return s
+}
+
+func (v *jsonValue) isFalse() bool {
+	return v != nil && string(v.raw) == "false"
+}
+
+// Set the member of an object.  A new member is added at the end
+func (v *jsonValue) set(key string, value *jsonValue) {
+	for i, m := range v.members {
+		if m.key == key {
+			v.members[i].value = value
+			return
+		}
+	}
+	v.members = append(v.members, jsonMember{key, value})
+}
+
+func (v *jsonValue) remove(key string) {
+	members := []jsonMember{}
+	for _, m := range v.members {
+		if m.key != key {
+			members = append(members, m)
+		}
+	}
+	v.members = members
+}
+
+// Write the value with each member and item on its own line.
+// * indent - the indentation of one level
+// * prefix - the indentation of the line of the value
+// * colon - the separator of the keys and values, for example ": " or " : "
+func (v *jsonValue) write(out *bytes.Buffer, indent, prefix, colon string) {
+	switch v.kind {
+	case '{':
+		if len(v.members) == 0 {
+			out.WriteString("{}")
+			return
+		}
+		out.WriteString("{")
+		for i, m := range v.members {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			key, _ := marshalJson(m.key)
+			fmt.Fprintf(out, "\n%s%s%s", prefix+indent, key, colon)
+			m.value.write(out, indent, prefix+indent, colon)
+		}
+		out.WriteString("\n" + prefix + "}")
+	case '[':
+		if len(v.items) == 0 {
+			out.WriteString("[]")
+			return
+		}
+		out.WriteString("[")
+		for i, item := range v.items {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			out.WriteString("\n" + prefix + indent)
+			item.write(out, indent, prefix+indent, colon)
+		}
+		out.WriteString("\n" + prefix + "]")
+	default:
+		out.Write(v.raw)
+	}
+}
+
+// the separator of the keys and values of the json file: ": " or " : " (used by Xcode)
+func jsonColon(template []byte) string {
+	if bytes.Contains(template, []byte(`" : `)) {
+		return " : "
+	}
+	return ": "
+}
+
+// Set the member of an object.  A new member is inserted in the order of the keys if they are sorted (as Xcode writes them)
+func (v *jsonValue) setSorted(key string, value *jsonValue) {
+	if v.get(key) != nil {
+		v.set(key, value)
+		return
+	}
+	at := len(v.members)
+	for i, m := range v.members {
+		if i > 0 && v.members[i-1].key > m.key {
+			// not sorted
+			at = len(v.members)
+			break
+		}
+		if m.key > key && at == len(v.members) {
+			at = i
+		}
+	}
+	v.members = append(v.members, jsonMember{})
+	copy(v.members[at+1:], v.members[at:])
+	v.members[at] = jsonMember{key, value}
+}
@@ -1,9 +1,12 @@
 package format
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"regexp"
+	"strings"
 )
 
 type KeyValueJson struct{}
@@ -12,30 +15,127 @@ func (f *KeyValueJson) Init(initParams map[string]interface{}) {
 }
 func (f KeyValueJson) Ext() string { return "json" }
 
+func (f KeyValueJson) Parse(content []byte) (*Catalog, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("Not valid json: expected a json object")
+	}
+
+	c := NewCatalog("")
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("Not valid json: %s", err)
+		}
+		key := token.(string)
+		var value string
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("Not valid json: %s", err)
+		}
+		if e := c.Find(key); e != nil {
+			e.Value = value
+		} else {
+			c.Add(&Entry{Key: key, Value: value})
+		}
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("Not valid json: %s", err)
+	}
+	return c, nil
+}
+
+// Writes the entries in catalog order using the indentation of the template
+func (f KeyValueJson) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	indent := jsonIndent(template)
+	var out bytes.Buffer
+	out.WriteString("{")
+	for i, e := range c.Entries {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		key, err := marshalJson(e.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := marshalJson(e.FlatValue())
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&out, "\n%s%s: %s", indent, key, value)
+	}
+	if len(c.Entries) > 0 {
+		out.WriteString("\n")
+	}
+	out.WriteString("}\n")
+	return out.Bytes(), nil
+}
+
 func (f KeyValueJson) Clean(content []byte) ([]byte, string, error) {
-	var data map[string]string
-	jsonErr := json.Unmarshal(content, &data)
-	if jsonErr != nil {
-		return nil, "", fmt.Errorf("Not valid json: %s", jsonErr)
+	c, err := f.Parse(content)
+	if err != nil {
+		return nil, "", err
 	}
+	data := c.KeyValues()
 	for key, value := range data {
 		if key == "" {
 			delete(data, key)
-		}
-		if value == "" {
+		} else if value == "" {
 			data[key] = " "
 		}
-		content, jsonErr = json.Marshal(data)
-		if jsonErr != nil {
-			panic("An error occurred when encoding json after updating json so that transifex can use it")
-		}
+	}
+	content, err = json.Marshal(data)
+	if err != nil {
+		panic("An error occurred when encoding json after updating json so that transifex can use it")
 	}
 
 	return content, "KEYVALUEJSON", nil
 
 }
+// Writes every key of the downloaded translation with the value transifex returned, in the order of the source
+// file.  Keys that are not in the source file are written after the others with a warning
 func (f KeyValueJson) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
-	path := fileLocator.Find(rootDir, langCode, filename, "json")
+	path := fileLocator.Find(rootDir, langCode, filename, f.Ext())
 	fmt.Println("Updating translations file: " + path)
-	return ioutil.WriteFile(path, []byte(translation), 0644)
+
+	translations, err := f.Parse([]byte(translation))
+	if err != nil {
+		return fmt.Errorf("The translation is not valid json: %s", err)
+	}
+
+	ordered := NewCatalog(langCode)
+	// the translation is written even without a readable source file
+	template, _ := ioutil.ReadFile(fileLocator.Find(rootDir, srcLang, filename, f.Ext()))
+	if source, err := f.Parse(template); err == nil {
+		for _, e := range source.Entries {
+			if tr := translations.Find(e.Key); tr != nil {
+				ordered.Add(tr)
+			}
+		}
+	}
+	unknown := []string{}
+	for _, tr := range translations.Entries {
+		if ordered.Find(tr.Key) == nil {
+			ordered.Add(tr)
+			unknown = append(unknown, tr.Key)
+		}
+	}
+	if template != nil && len(unknown) > 0 {
+		fmt.Printf("Warning: the translation has keys that are not in the source file: [%s]\n", strings.Join(unknown, ", "))
+	}
+
+	out, err := f.Serialize(ordered, template)
+	if err != nil {
+		return err
+	}
+	return writeTranslationFile(path, out)
+}
+
+var jsonIndentPattern = regexp.MustCompile(`\n([ \t]+)"`)
+
+// the indentation used by the first nested line of a json file or 4 spaces
+func jsonIndent(template []byte) string {
+	if m := jsonIndentPattern.FindSubmatch(template); m != nil {
+		return string(m[1])
+	}
+	return "    "
 }
@@ -0,0 +1,65 @@
+package format
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	tu "testutil"
+)
+
+func Test_KeyValueJson_Parse(t *testing.T) {
+	c, err := KeyValueJson{}.Parse([]byte(`{"b": "B", "a": "A", "": "empty key", "c": ""}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("entries", 4, len(c.Entries), t)
+	tu.AssertEquals("order", "b", c.Entries[0].Key, t)
+	tu.AssertEquals("order", "a", c.Entries[1].Key, t)
+
+	cleaned, i18n, err := KeyValueJson{}.Clean([]byte(`{"b": "B", "a": "A", "": "empty key", "c": ""}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("i18n type", "KEYVALUEJSON", i18n, t)
+	tu.AssertEquals("cleaned", `{"a":"A","b":"B","c":" "}`, string(cleaned), t)
+
+	if _, err := (KeyValueJson{}).Parse([]byte(`{"nested": {"a": "b"}}`)); err == nil {
+		t.Errorf("Expected an error for nested json")
+	}
+}
+
+func Test_KeyValueJson_Write(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	source := "{\n\t\"title\": \"Title\",\n\t\"body\": \"<p>Body</p>\"\n}\n"
+	ioutil.WriteFile(filepath.Join(tmpDir, "en-name.json"), []byte(source), 0644)
+
+	err := KeyValueJson{}.Write(tmpDir, "fr", "en", "name", `{"body": "<p>Corps</p>", "title": "Titre"}`, FileLocators["LANG-NAME"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	written, _ := ioutil.ReadFile(filepath.Join(tmpDir, "fr-name.json"))
+	tu.AssertEquals("", "{\n\t\"title\": \"Titre\",\n\t\"body\": \"<p>Corps</p>\"\n}\n", string(written), t)
+}
+
+func Test_KeyValueJson_Write_Lenient(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	source := "{\n  \"title\": \"Title\",\n  \"body\": \"Body\"\n}\n"
+	ioutil.WriteFile(filepath.Join(tmpDir, "en-name.json"), []byte(source), 0644)
+
+	// keys missing from the source file are written, untranslated keys keep the value of the server
+	err := KeyValueJson{}.Write(tmpDir, "fr", "en", "name", `{"extra": "En plus", "body": "Body", "title": "Titre"}`, FileLocators["LANG-NAME"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, _ := ioutil.ReadFile(filepath.Join(tmpDir, "fr-name.json"))
+	tu.AssertEquals("", "{\n  \"title\": \"Titre\",\n  \"body\": \"Body\",\n  \"extra\": \"En plus\"\n}\n", string(written), t)
+
+	// without a source file the translation is written as it is
+	err = KeyValueJson{}.Write(tmpDir, "fr", "en", "other", `{"b": "B", "a": "A"}`, FileLocators["LANG-NAME"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, _ = ioutil.ReadFile(filepath.Join(tmpDir, "fr-other.json"))
+	tu.AssertEquals("no source", "{\n    \"b\": \"B\",\n    \"a\": \"A\"\n}\n", string(written), t)
+}
@@ -0,0 +1,127 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"strings"
+)
+
+const moMagic = 0x950412de
+
+// Compile the catalog into a binary gettext MO file, including the hash table used by gettext for lookups.
+// As with msgfmt, untranslated and fuzzy entries are left out
+func CompileMo(c *Catalog) []byte {
+	type moString struct{ id, str string }
+
+	header := bytes.Buffer{}
+	for _, m := range c.Meta {
+		header.WriteString(m.Key + ": " + m.Value + "\n")
+	}
+	strs := []moString{{"", header.String()}}
+
+	categories := c.pluralCategories()
+	for _, e := range c.Entries {
+		if e.HasFlag("fuzzy") {
+			continue
+		}
+		id := e.Key
+		if e.Context != "" {
+			id = e.Context + contextSeparator + id
+		}
+		if e.IsPlural() || e.PluralKey != "" {
+			forms := make([]string, len(categories))
+			translated := false
+			for i, category := range categories {
+				forms[i] = e.Plurals[category]
+				translated = translated || forms[i] != ""
+			}
+			if translated {
+				strs = append(strs, moString{id + "\x00" + e.PluralKey, strings.Join(forms, "\x00")})
+			}
+		} else if e.Value != "" {
+			strs = append(strs, moString{id, e.Value})
+		}
+	}
+	sort.Slice(strs, func(i, j int) bool { return strs[i].id < strs[j].id })
+
+	n := uint32(len(strs))
+	hashSize := moHashSize(n)
+	originalsOffset := uint32(28)
+	translationsOffset := originalsOffset + 8*n
+	hashOffset := translationsOffset + 8*n
+	dataOffset := hashOffset + 4*hashSize
+
+	var data bytes.Buffer
+	originals := make([]uint32, 0, 2*n)
+	translations := make([]uint32, 0, 2*n)
+	for _, s := range strs {
+		originals = append(originals, uint32(len(s.id)), dataOffset+uint32(data.Len()))
+		data.WriteString(s.id)
+		data.WriteByte(0)
+	}
+	for _, s := range strs {
+		translations = append(translations, uint32(len(s.str)), dataOffset+uint32(data.Len()))
+		data.WriteString(s.str)
+		data.WriteByte(0)
+	}
+
+	hashTable := make([]uint32, hashSize)
+	for i, s := range strs {
+		// gettext looks plural strings up by their msgid, without the msgid_plural
+		hash := moHash(strings.SplitN(s.id, "\x00", 2)[0])
+		idx := hash % hashSize
+		incr := 1 + hash%(hashSize-2)
+		for hashTable[idx] != 0 {
+			idx = (idx + incr) % hashSize
+		}
+		hashTable[idx] = uint32(i + 1)
+	}
+
+	var out bytes.Buffer
+	for _, v := range []uint32{moMagic, 0, n, originalsOffset, translationsOffset, hashSize, hashOffset} {
+		binary.Write(&out, binary.LittleEndian, v)
+	}
+	binary.Write(&out, binary.LittleEndian, originals)
+	binary.Write(&out, binary.LittleEndian, translations)
+	binary.Write(&out, binary.LittleEndian, hashTable)
+	out.Write(data.Bytes())
+	return out.Bytes()
+}
+
+// the hashpjw function used by gettext
+func moHash(s string) uint32 {
+	var hash uint32
+	for i := 0; i < len(s); i++ {
+		hash = (hash << 4) + uint32(s[i])
+		if g := hash & 0xf0000000; g != 0 {
+			hash ^= g >> 24
+			hash ^= g
+		}
+	}
+	return hash
+}
+
+// the smallest prime larger than 4/3 of the number of strings (the same size msgfmt uses), at least 3
+func moHashSize(n uint32) uint32 {
+	size := n * 4 / 3
+	if size < 3 {
+		size = 3
+	}
+	for !isPrime(size) {
+		size++
+	}
+	return size
+}
+
+func isPrime(n uint32) bool {
+	if n < 2 {
+		return false
+	}
+	for d := uint32(2); d*d <= n; d++ {
+		if n%d == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package format
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	tu "testutil"
+)
+
+// a minimal MO reader that looks strings up through the hash table, as gettext does: plural strings are found by
+// their msgid, the original string is compared up to its first NUL
+type moReader struct {
+	data                                             []byte
+	n, originals, translations, hashSize, hashOffset uint32
+}
+
+func newMoReader(data []byte) (*moReader, error) {
+	if len(data) < 28 || binary.LittleEndian.Uint32(data) != moMagic {
+		return nil, fmt.Errorf("not a little endian mo file")
+	}
+	word := func(i int) uint32 { return binary.LittleEndian.Uint32(data[4*i:]) }
+	return &moReader{data, word(2), word(3), word(4), word(5), word(6)}, nil
+}
+
+func (r *moReader) word(offset uint32) uint32 {
+	return binary.LittleEndian.Uint32(r.data[offset:])
+}
+
+func (r *moReader) str(table, i uint32) string {
+	length, offset := r.word(table+8*i), r.word(table+8*i+4)
+	return string(r.data[offset : offset+length])
+}
+
+func (r *moReader) lookup(id string) (string, bool) {
+	hash := moHash(id)
+	idx := hash % r.hashSize
+	incr := 1 + hash%(r.hashSize-2)
+	for {
+		i := r.word(r.hashOffset + 4*idx)
+		if i == 0 {
+			return "", false
+		}
+		if strings.SplitN(r.str(r.originals, i-1), "\x00", 2)[0] == id {
+			return r.str(r.translations, i-1), true
+		}
+		idx = (idx + incr) % r.hashSize
+	}
+}
+
+func Test_CompileMo_RoundTrip(t *testing.T) {
+	c, err := new(Po).Parse([]byte(poSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Find("Welcome").Value = "Bienvenue"
+	c.Find("door\u0004Open").Value = "Ouverte"
+	c.Find("menu\u0004Open").Value = "Ouvrir"
+	c.Find("%d file").Plurals = map[string]string{"one": "%d fichier", "other": "%d fichiers"}
+
+	r, err := newMoReader(CompileMo(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// header, Welcome, door, plural (menu is fuzzy, the long text is not translated)
+	tu.AssertEqualsInt("strings", 4, int(r.n), t)
+	for i := uint32(1); i < r.n; i++ {
+		if r.str(r.originals, i-1) >= r.str(r.originals, i) {
+			t.Errorf("The original strings are not sorted")
+		}
+	}
+
+	expected := map[string]string{
+		"Welcome":        "Bienvenue",
+		"door\u0004Open": "Ouverte",
+		"%d file":        "%d fichier\x00%d fichiers",
+		"":               "Project-Id-Version: webapp 1.0\nContent-Type: text/plain; charset=UTF-8\nLanguage: en\nPlural-Forms: nplurals=2; plural=(n != 1);\n"}
+	for id, str := range expected {
+		actual, found := r.lookup(id)
+		if !found {
+			t.Errorf("%q was not found through the hash table", id)
+		}
+		tu.AssertEquals(id, str, actual, t)
+	}
+	if _, found := r.lookup("menu\u0004Open"); found {
+		t.Errorf("Fuzzy entries must not be compiled")
+	}
+}
+
+func Test_Po_Write_Mo(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	createFile(tmpDir, "en", "messages.po", t)
+	ioutil.WriteFile(filepath.Join(tmpDir, "en", "messages.po"), []byte(poSource), 0644)
+
+	f := new(Po)
+	f.Init(map[string]interface{}{"mo": true, "ext": 1})
+	tu.AssertEquals("ext that is not a string", "po", f.Ext(), t)
+	data, _ := json.Marshal(map[string]string{"Welcome": "Willkommen", "%d file": "{cnt, plural, one {%d Datei} other {%d Dateien}}"})
+	if err := f.Write(tmpDir, "de", "en", "messages", string(data), FileLocators["LOC-DIR"]); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := newMoReader(mustRead(filepath.Join(tmpDir, "de", "messages.mo")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	welcome, _ := r.lookup("Welcome")
+	tu.AssertEquals("welcome", "Willkommen", welcome, t)
+	files, _ := r.lookup("%d file")
+	tu.AssertEquals("plural", "%d Datei\x00%d Dateien", files, t)
+}
@@ -0,0 +1,227 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Json files with nested objects (i18next, angular-translate).  The nested keys are joined with the separator
+// for the upload: {"menu": {"open": "Open"}} is uploaded as menu.open.  The elements of arrays are keyed by their index.
+//
+// The i18next plural suffixes are uploaded as plural strings: key_one, key_other ... and the older key, key_plural.
+// Translations are written with the nesting, key order and indentation of the source file, strings without
+// a translation are left out so that the fallback language is used.  An array is left out unless all its strings
+// are translated.
+//
+// Extra parameters:
+// * separator - the separator of the nested keys, . by default
+type NestedJson struct {
+	separator string
+}
+
+func (f *NestedJson) Init(initParams map[string]interface{}) {
+	if separator, has := initParams["separator"].(string); has {
+		f.separator = separator
+	}
+}
+
+func (f *NestedJson) Ext() string { return "json" }
+
+const i18nextPluralSuffix = "_plural"
+
+func (f *NestedJson) Parse(content []byte) (*Catalog, error) {
+	root, err := parseJsonValue(content)
+	if err != nil {
+		return nil, err
+	}
+	if root.kind != '{' {
+		return nil, fmt.Errorf("Not valid json: expected a json object")
+	}
+	c := NewCatalog("")
+	f.addEntries(c, "", root)
+	return c, nil
+}
+
+func (f *NestedJson) addEntries(c *Catalog, key string, v *jsonValue) {
+	switch v.kind {
+	case '{':
+		for _, m := range v.members {
+			base, _, plural := i18nextPlural(v, m.key)
+			if !plural {
+				f.addEntries(c, f.join(key, m.key), m.value)
+			} else if c.Find(f.join(key, base)) == nil {
+				c.Add(&Entry{Key: f.join(key, base), Plurals: i18nextPlurals(v, base)})
+			}
+		}
+	case '[':
+		for i, item := range v.items {
+			f.addEntries(c, f.join(key, strconv.Itoa(i)), item)
+		}
+	default:
+		if isJsonString(v) {
+			c.Add(&Entry{Key: key, Value: v.str()})
+		}
+	}
+}
+
+func (f *NestedJson) Clean(content []byte) ([]byte, string, error) {
+	return cleanToStructuredJson(f, content)
+}
+
+// The template is rewritten with the translations of its strings.  Without a template the nesting is
+// created from the keys of the catalog
+func (f *NestedJson) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	var root *jsonValue
+	if template == nil {
+		root = f.nest(c)
+	} else {
+		var err error
+		if root, err = parseJsonValue(template); err != nil {
+			return nil, err
+		}
+		if !f.translate(c, "", root) {
+			root.members = nil
+		}
+	}
+
+	var out bytes.Buffer
+	root.write(&out, jsonIndent(template), "", jsonColon(template))
+	out.WriteString("\n")
+	return out.Bytes(), nil
+}
+
+func (f *NestedJson) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	return writeCatalog(f, rootDir, langCode, srcLang, filename, translation, fileLocator)
+}
+
+// Replace the strings of v with their translations.  Returns false if nothing in v is translated
+func (f *NestedJson) translate(c *Catalog, key string, v *jsonValue) bool {
+	switch v.kind {
+	case '{':
+		members := []jsonMember{}
+		// the plural forms are written once, at the position of the first form
+		written := map[string]bool{}
+		for _, m := range v.members {
+			base, _, plural := i18nextPlural(v, m.key)
+			if !plural {
+				if f.translate(c, f.join(key, m.key), m.value) {
+					members = append(members, m)
+				}
+				continue
+			}
+			e := c.Find(f.join(key, base))
+			if written[base] || e == nil || !hasPluralTranslation(e) {
+				continue
+			}
+			written[base] = true
+			if v.get(base+i18nextPluralSuffix) != nil {
+				members = append(members,
+					jsonMember{base, newJsonString(e.Plurals["one"])},
+					jsonMember{base + i18nextPluralSuffix, newJsonString(e.Plurals["other"])})
+			} else {
+				for _, category := range e.PluralForms() {
+					if e.Plurals[category] != "" {
+						members = append(members, jsonMember{base + "_" + category, newJsonString(e.Plurals[category])})
+					}
+				}
+			}
+		}
+		v.members = members
+		return len(members) > 0
+	case '[':
+		// the items of an array cannot be left out without moving the others: the whole array is left out
+		for i, item := range v.items {
+			if !f.translate(c, f.join(key, strconv.Itoa(i)), item) {
+				return false
+			}
+		}
+		return len(v.items) > 0
+	default:
+		if !isJsonString(v) {
+			return true
+		}
+		e := c.Find(key)
+		if e == nil || e.Value == "" {
+			return false
+		}
+		v.raw = newJsonString(e.Value).raw
+		return true
+	}
+}
+
+// the nested objects of the keys of the catalog
+func (f *NestedJson) nest(c *Catalog) *jsonValue {
+	root := newJsonObject()
+	for _, e := range c.Entries {
+		path := strings.Split(e.Key, f.sep())
+		parent := root
+		for _, name := range path[:len(path)-1] {
+			child := parent.get(name)
+			if child == nil || child.kind != '{' {
+				child = newJsonObject()
+				parent.set(name, child)
+			}
+			parent = child
+		}
+		name := path[len(path)-1]
+		if !e.IsPlural() {
+			parent.set(name, newJsonString(e.Value))
+			continue
+		}
+		for _, category := range e.PluralForms() {
+			parent.set(name+"_"+category, newJsonString(e.Plurals[category]))
+		}
+	}
+	return root
+}
+
+func (f *NestedJson) sep() string {
+	if f.separator == "" {
+		return "."
+	}
+	return f.separator
+}
+
+func (f *NestedJson) join(key, name string) string {
+	if key == "" {
+		return name
+	}
+	return key + f.sep() + name
+}
+
+// Returns the base key and the plural category if the member of the object is a plural form:
+// key_one, key_other ... if the object has key_other, or key, key_plural
+func i18nextPlural(object *jsonValue, key string) (base, category string, plural bool) {
+	if strings.HasSuffix(key, i18nextPluralSuffix) {
+		base = strings.TrimSuffix(key, i18nextPluralSuffix)
+		if isJsonString(object.get(base)) {
+			return base, "other", true
+		}
+	}
+	if isJsonString(object.get(key + i18nextPluralSuffix)) {
+		return key, "one", true
+	}
+	for _, category := range PluralCategories {
+		if base = strings.TrimSuffix(key, "_"+category); base != key && isJsonString(object.get(base+"_other")) {
+			return base, category, true
+		}
+	}
+	return "", "", false
+}
+
+// the plural forms of the base key in the object
+func i18nextPlurals(object *jsonValue, base string) map[string]string {
+	plurals := map[string]string{}
+	for _, m := range object.members {
+		if b, category, plural := i18nextPlural(object, m.key); plural && b == base {
+			plurals[category] = m.value.str()
+		}
+	}
+	return plurals
+}
+
+func isJsonString(v *jsonValue) bool {
+	return v != nil && v.kind == 0 && len(v.raw) > 0 && v.raw[0] == '"'
+}
@@ -0,0 +1,121 @@
+package format
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	tu "testutil"
+)
+
+const nestedJsonSource = `{
+  "menu": {
+    "open": "Open",
+    "recent": {
+      "title": "Recent files",
+      "clear": "Clear"
+    }
+  },
+  "days": ["Monday", "Tuesday"],
+  "version": 2,
+  "files_one": "{{count}} file",
+  "files_other": "{{count}} files",
+  "item": "{{count}} item",
+  "item_plural": "{{count}} items"
+}
+`
+
+func Test_NestedJson_Parse(t *testing.T) {
+	c, err := new(NestedJson).Parse([]byte(nestedJsonSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tu.AssertEqualsInt("entries", 7, len(c.Entries), t)
+	tu.AssertEquals("nested", "Open", c.Find("menu.open").Value, t)
+	tu.AssertEquals("deeply nested", "Clear", c.Find("menu.recent.clear").Value, t)
+	tu.AssertEquals("array", "Tuesday", c.Find("days.1").Value, t)
+	if c.Find("version") != nil {
+		t.Errorf("Numbers should not be uploaded")
+	}
+	tu.AssertEquals("v4 one", "{{count}} file", c.Find("files").Plurals["one"], t)
+	tu.AssertEquals("v4 other", "{{count}} files", c.Find("files").Plurals["other"], t)
+	tu.AssertEquals("v3 one", "{{count}} item", c.Find("item").Plurals["one"], t)
+	tu.AssertEquals("v3 other", "{{count}} items", c.Find("item").Plurals["other"], t)
+
+	f := new(NestedJson)
+	f.Init(map[string]interface{}{"separator": "/"})
+	c, err = f.Parse([]byte(nestedJsonSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("separator", "Recent files", c.Find("menu/recent/title").Value, t)
+
+	if _, err = f.Parse([]byte(`["a"]`)); err == nil {
+		t.Errorf("Expected an error for a file that is not an object")
+	}
+}
+
+func Test_NestedJson_Clean(t *testing.T) {
+	cleaned, i18n, err := new(NestedJson).Clean([]byte(nestedJsonSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("i18n type", "STRUCTURED_JSON", i18n, t)
+
+	var data map[string]map[string]string
+	json.Unmarshal(cleaned, &data)
+	tu.AssertEquals("nested", "Recent files", data["menu.recent.title"]["string"], t)
+	tu.AssertEquals("plural", "{cnt, plural, one {{{count}} file} other {{{count}} files}}", data["files"]["string"], t)
+}
+
+func Test_NestedJson_Write(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	ioutil.WriteFile(filepath.Join(tmpDir, "en-messages.json"), []byte(nestedJsonSource), 0644)
+
+	data, _ := json.Marshal(map[string]string{
+		"menu.open":         "Открыть",
+		"menu.recent.title": "Недавние файлы",
+		"days.0":            "Понедельник",
+		"files":             "{cnt, plural, one {{{count}} файл} few {{{count}} файла} many {{{count}} файлов} other {{{count}} файла}}",
+		"item":              "{cnt, plural, one {{{count}} элемент} other {{{count}} элемента}}"})
+
+	f := new(NestedJson)
+	f.Init(map[string]interface{}{})
+	if err := f.Write(tmpDir, "ru", "en", "messages", string(data), FileLocators["LANG-NAME"]); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{
+  "menu": {
+    "open": "Открыть",
+    "recent": {
+      "title": "Недавние файлы"
+    }
+  },
+  "version": 2,
+  "files_one": "{{count}} файл",
+  "files_few": "{{count}} файла",
+  "files_many": "{{count}} файлов",
+  "files_other": "{{count}} файла",
+  "item": "{{count}} элемент",
+  "item_plural": "{{count}} элемента"
+}
+`
+	tu.AssertEquals("", expected, string(mustRead(filepath.Join(tmpDir, "ru-messages.json"))), t)
+
+	// an array is written only with all its strings translated
+	data, _ = json.Marshal(map[string]string{"days.0": "Понедельник", "days.1": "Вторник"})
+	if err := f.Write(tmpDir, "ru", "en", "messages", string(data), FileLocators["LANG-NAME"]); err != nil {
+		t.Fatal(err)
+	}
+	expected = `{
+  "days": [
+    "Понедельник",
+    "Вторник"
+  ],
+  "version": 2
+}
+`
+	tu.AssertEquals("array", expected, string(mustRead(filepath.Join(tmpDir, "ru-messages.json"))), t)
+}
@@ -0,0 +1,499 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// PHP language files returning an array (Laravel resources/lang/<lang>/<name>.php).  The files are parsed, not
+// executed: only the literal subset of PHP used by language files is supported (return of nested [...] or array(...)
+// with string and number keys, single and double quoted strings, numbers, booleans, null and comments).  Nested keys are
+// uploaded joined by dots, the Laravel notation (validation.custom.email.required), the comments before a string are
+// uploaded as its comment.
+//
+// Translations are written with the source file as the template, in its order and with its comments.  Strings without
+// a translation are left out so that Laravel uses the fallback locale.  Use it with the LOC-DIR structure
+type PhpArray struct{}
+
+func (f *PhpArray) Init(initParams map[string]interface{}) {}
+func (f *PhpArray) Ext() string                            { return "php" }
+
+const phpKeySeparator = "."
+
+// a member of a php array
+type phpNode struct {
+	key string
+	// the member has no key in the file (a list item), index is its position
+	implicit bool
+	numeric  bool
+	index    int
+	comments []string
+	// a string value, a number/boolean/null as written or an array
+	isString bool
+	value    string
+	array    *phpArray
+}
+
+type phpArray struct {
+	// [...] or array(...)
+	short bool
+	items []*phpNode
+	// the comments before the end of the array
+	comments []string
+}
+
+type phpFile struct {
+	// the comments before the return
+	comments []string
+	root     *phpArray
+}
+
+func (f *PhpArray) Parse(content []byte) (*Catalog, error) {
+	file, err := parsePhpFile(content)
+	if err != nil {
+		return nil, err
+	}
+	c := NewCatalog("")
+	var flatten func(prefix string, a *phpArray)
+	flatten = func(prefix string, a *phpArray) {
+		for _, n := range a.items {
+			switch {
+			case n.array != nil:
+				flatten(prefix+n.key+phpKeySeparator, n.array)
+			case n.isString:
+				c.Add(&Entry{Key: prefix + n.key, Value: n.value, Comment: phpCommentText(n.comments)})
+			}
+		}
+	}
+	flatten("", file.root)
+	return c, nil
+}
+
+func (f *PhpArray) Clean(content []byte) ([]byte, string, error) {
+	return cleanToStructuredJson(f, content)
+}
+
+func (f *PhpArray) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	file := &phpFile{root: &phpArray{short: true}}
+	if template != nil {
+		var err error
+		if file, err = parsePhpFile(template); err != nil {
+			return nil, err
+		}
+	} else {
+		buildPhpArray(file.root, c)
+	}
+
+	// leave out the strings without a translation and the arrays left empty
+	var translate func(prefix string, a *phpArray)
+	translate = func(prefix string, a *phpArray) {
+		items := []*phpNode{}
+		for _, n := range a.items {
+			switch {
+			case n.array != nil:
+				empty := len(n.array.items) == 0
+				translate(prefix+n.key+phpKeySeparator, n.array)
+				if empty || len(n.array.items) > 0 {
+					items = append(items, n)
+				}
+			case n.isString:
+				if e := c.Find(prefix + n.key); e != nil && e.Value != "" {
+					n.value = e.Value
+					items = append(items, n)
+				}
+			default:
+				items = append(items, n)
+			}
+		}
+		a.items = items
+	}
+	translate("", file.root)
+
+	var out bytes.Buffer
+	out.WriteString("<?php\n\n")
+	for _, comment := range file.comments {
+		out.WriteString(comment + "\n")
+	}
+	if len(file.comments) > 0 {
+		out.WriteString("\n")
+	}
+	out.WriteString("return ")
+	writePhpArray(&out, file.root, "")
+	out.WriteString(";\n")
+	return out.Bytes(), nil
+}
+
+func (f *PhpArray) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	return writeCatalog(f, rootDir, langCode, srcLang, filename, translation, fileLocator)
+}
+
+// the arrays of the dotted keys of the catalog, in the order of the entries
+func buildPhpArray(root *phpArray, c *Catalog) {
+	for _, e := range c.Entries {
+		a := root
+		parts := strings.Split(e.Key, phpKeySeparator)
+		for _, part := range parts[:len(parts)-1] {
+			var child *phpNode
+			for _, n := range a.items {
+				if n.key == part && n.array != nil {
+					child = n
+				}
+			}
+			if child == nil {
+				child = &phpNode{key: part, array: &phpArray{short: true}}
+				a.items = append(a.items, child)
+			}
+			a = child.array
+		}
+		a.items = append(a.items, &phpNode{key: parts[len(parts)-1], isString: true, value: e.Value})
+	}
+}
+
+func writePhpArray(out *bytes.Buffer, a *phpArray, indent string) {
+	open, close := "array(", ")"
+	if a.short {
+		open, close = "[", "]"
+	}
+	if len(a.items) == 0 && len(a.comments) == 0 {
+		out.WriteString(open + close)
+		return
+	}
+
+	out.WriteString(open + "\n")
+	next := 0
+	for _, n := range a.items {
+		for _, comment := range n.comments {
+			out.WriteString(indent + "    " + comment + "\n")
+		}
+		out.WriteString(indent + "    ")
+		// a list item keeps its index when the items before it were left out
+		if !n.implicit || n.index != next {
+			if n.numeric {
+				out.WriteString(n.key)
+			} else {
+				out.WriteString(quotePhp(n.key))
+			}
+			out.WriteString(" => ")
+		}
+		if n.numeric && n.index >= next {
+			next = n.index + 1
+		}
+
+		switch {
+		case n.array != nil:
+			writePhpArray(out, n.array, indent+"    ")
+		case n.isString:
+			out.WriteString(quotePhp(n.value))
+		default:
+			out.WriteString(n.value)
+		}
+		out.WriteString(",\n")
+	}
+	for _, comment := range a.comments {
+		out.WriteString(indent + "    " + comment + "\n")
+	}
+	out.WriteString(indent + close)
+}
+
+// a single quoted php string
+func quotePhp(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// the text of the comments without the comment markers
+func phpCommentText(comments []string) string {
+	lines := []string{}
+	for _, comment := range comments {
+		comment = strings.TrimSuffix(strings.TrimPrefix(comment, "/*"), "*/")
+		for _, line := range strings.Split(comment, "\n") {
+			line = strings.TrimSpace(line)
+			line = strings.TrimLeft(strings.TrimPrefix(line, "//"), "#*")
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+type phpParser struct {
+	content []byte
+	pos     int
+}
+
+func parsePhpFile(content []byte) (*phpFile, error) {
+	p := &phpParser{content: bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))}
+	p.skipSpace()
+	if !p.consume("<?php") {
+		return nil, p.errorf("expected <?php")
+	}
+
+	file := &phpFile{comments: p.skipSpace()}
+	if !p.consumeWord("return") {
+		return nil, p.errorf("expected return")
+	}
+	p.skipSpace()
+	root, err := p.parseArray()
+	if err != nil {
+		return nil, err
+	}
+	file.root = root
+	p.skipSpace()
+	if !p.consume(";") {
+		return nil, p.errorf("expected ;")
+	}
+	p.skipSpace()
+	p.consume("?>")
+	if p.skipSpace(); p.pos < len(p.content) {
+		return nil, p.errorf("expected the end of the file")
+	}
+	return file, nil
+}
+
+func (p *phpParser) parseArray() (*phpArray, error) {
+	a := &phpArray{short: true}
+	close := "]"
+	if !p.consume("[") {
+		if !p.consumeWord("array") {
+			return nil, p.errorf("expected an array")
+		}
+		if p.skipSpace(); !p.consume("(") {
+			return nil, p.errorf("expected (")
+		}
+		a.short, close = false, ")"
+	}
+
+	next := 0
+	for {
+		comments := p.skipSpace()
+		if p.consume(close) {
+			a.comments = comments
+			return a, nil
+		}
+
+		n, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if p.skipSpace(); p.consume("=>") {
+			key := n
+			switch {
+			case key.isString:
+				n.key = key.value
+				if i, err := strconv.Atoi(key.value); err == nil && strconv.Itoa(i) == key.value {
+					// php converts decimal string keys to integers
+					n.numeric, n.index = true, i
+				}
+			case key.array == nil && isPhpInt(key.value):
+				n.key, n.numeric = key.value, true
+				n.index, _ = strconv.Atoi(key.value)
+			default:
+				return nil, p.errorf("unsupported array key")
+			}
+			p.skipSpace()
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			n.isString, n.value, n.array = value.isString, value.value, value.array
+		} else {
+			n.implicit, n.numeric, n.index = true, true, next
+			n.key = strconv.Itoa(next)
+		}
+		if n.numeric && n.index >= next {
+			next = n.index + 1
+		}
+		n.comments = comments
+		a.items = append(a.items, n)
+
+		p.skipSpace()
+		if !p.consume(",") && !bytes.HasPrefix(p.content[p.pos:], []byte(close)) {
+			return nil, p.errorf("expected , or %s", close)
+		}
+	}
+}
+
+func (p *phpParser) parseValue() (*phpNode, error) {
+	if p.pos >= len(p.content) {
+		return nil, p.errorf("unexpected end of file")
+	}
+	switch c := p.content[p.pos]; {
+	case c == '\'':
+		value, err := p.parseSingleQuoted()
+		return &phpNode{isString: true, value: value}, err
+	case c == '"':
+		value, err := p.parseDoubleQuoted()
+		return &phpNode{isString: true, value: value}, err
+	case c == '[' || p.isWord("array"):
+		array, err := p.parseArray()
+		return &phpNode{array: array}, err
+	}
+
+	start := p.pos
+	for p.pos < len(p.content) && strings.IndexByte("+-.0123456789abcdefxABCDEFXlnrstuLNRSTU_", p.content[p.pos]) >= 0 {
+		p.pos++
+	}
+	raw := string(p.content[start:p.pos])
+	switch strings.ToLower(raw) {
+	case "true", "false", "null":
+		return &phpNode{value: raw}, nil
+	}
+	if _, err := strconv.ParseFloat(strings.Replace(raw, "_", "", -1), 64); err == nil && strings.IndexByte("+-.0123456789", raw[0]) >= 0 {
+		return &phpNode{value: raw}, nil
+	}
+	p.pos = start
+	return nil, p.errorf("unsupported value")
+}
+
+func (p *phpParser) parseSingleQuoted() (string, error) {
+	start := p.pos
+	var value bytes.Buffer
+	for p.pos++; p.pos < len(p.content); p.pos++ {
+		switch c := p.content[p.pos]; c {
+		case '\'':
+			p.pos++
+			return value.String(), nil
+		case '\\':
+			if p.pos+1 < len(p.content) && (p.content[p.pos+1] == '\'' || p.content[p.pos+1] == '\\') {
+				p.pos++
+			}
+			value.WriteByte(p.content[p.pos])
+		default:
+			value.WriteByte(c)
+		}
+	}
+	p.pos = start
+	return "", p.errorf("unterminated string")
+}
+
+var phpEscapes = map[byte]string{'n': "\n", 't': "\t", 'r': "\r", 'v': "\v", 'e': "\x1b", 'f': "\f", '\\': "\\", '$': "$", '"': "\""}
+
+func (p *phpParser) parseDoubleQuoted() (string, error) {
+	start := p.pos
+	var value bytes.Buffer
+	for p.pos++; p.pos < len(p.content); p.pos++ {
+		c := p.content[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			return value.String(), nil
+		case c == '$' && p.pos+1 < len(p.content) && isPhpVariableStart(p.content[p.pos+1]),
+			c == '{' && p.pos+1 < len(p.content) && p.content[p.pos+1] == '$':
+			return "", p.errorf("unsupported variable in string")
+		case c != '\\' || p.pos+1 >= len(p.content):
+			value.WriteByte(c)
+		default:
+			p.pos++
+			escape := p.content[p.pos]
+			if s, has := phpEscapes[escape]; has {
+				value.WriteString(s)
+				continue
+			}
+			switch rest := p.content[p.pos:]; {
+			case escape >= '0' && escape <= '7':
+				n := phpDigits(rest, "01234567", 3)
+				code, _ := strconv.ParseUint(string(rest[:n]), 8, 8)
+				value.WriteByte(byte(code))
+				p.pos += n - 1
+			case escape == 'x' && phpDigits(rest[1:], "0123456789abcdefABCDEF", 2) > 0:
+				n := phpDigits(rest[1:], "0123456789abcdefABCDEF", 2)
+				code, _ := strconv.ParseUint(string(rest[1:1+n]), 16, 8)
+				value.WriteByte(byte(code))
+				p.pos += n
+			case escape == 'u' && len(rest) > 1 && rest[1] == '{' && bytes.IndexByte(rest, '}') > 2:
+				end := bytes.IndexByte(rest, '}')
+				code, err := strconv.ParseUint(string(rest[2:end]), 16, 32)
+				if err != nil {
+					return "", p.errorf("invalid unicode escape")
+				}
+				var encoded [utf8.UTFMax]byte
+				value.Write(encoded[:utf8.EncodeRune(encoded[:], rune(code))])
+				p.pos += end
+			default:
+				// unknown escapes are kept as they are
+				value.WriteByte('\\')
+				value.WriteByte(escape)
+			}
+		}
+	}
+	p.pos = start
+	return "", p.errorf("unterminated string")
+}
+
+// Skip whitespace and comments.  Returns the comments
+func (p *phpParser) skipSpace() []string {
+	comments := []string{}
+	for p.pos < len(p.content) {
+		rest := p.content[p.pos:]
+		switch {
+		case strings.IndexByte(" \t\r\n", rest[0]) >= 0:
+			p.pos++
+		case bytes.HasPrefix(rest, []byte("/*")):
+			end := bytes.Index(rest[2:], []byte("*/"))
+			if end < 0 {
+				end = len(rest) - 4
+			}
+			comments = append(comments, string(rest[:end+4]))
+			p.pos += end + 4
+		case rest[0] == '#' && !bytes.HasPrefix(rest, []byte("#[")), bytes.HasPrefix(rest, []byte("//")):
+			end := bytes.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			comments = append(comments, strings.TrimRight(string(rest[:end]), " \t\r"))
+			p.pos += end
+		default:
+			return comments
+		}
+	}
+	return comments
+}
+
+func (p *phpParser) consume(s string) bool {
+	if bytes.HasPrefix(p.content[p.pos:], []byte(s)) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+// true if the next token is the (case insensitive) keyword
+func (p *phpParser) isWord(word string) bool {
+	rest := p.content[p.pos:]
+	return len(rest) >= len(word) && strings.EqualFold(string(rest[:len(word)]), word) &&
+		(len(rest) == len(word) || !isPhpVariableStart(rest[len(word)]) && (rest[len(word)] < '0' || rest[len(word)] > '9'))
+}
+
+func (p *phpParser) consumeWord(word string) bool {
+	if p.isWord(word) {
+		p.pos += len(word)
+		return true
+	}
+	return false
+}
+
+func (p *phpParser) errorf(format string, args ...interface{}) error {
+	line := bytes.Count(p.content[:p.pos], []byte("\n")) + 1
+	return fmt.Errorf("Unsupported php on line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func isPhpVariableStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func isPhpInt(raw string) bool {
+	_, err := strconv.Atoi(raw)
+	return err == nil
+}
+
+// the number of leading bytes of s (at most max) that are digits
+func phpDigits(s []byte, digits string, max int) int {
+	n := 0
+	for n < len(s) && n < max && strings.IndexByte(digits, s[n]) >= 0 {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,135 @@
+package format
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	tu "testutil"
+)
+
+const phpArraySource = `<?php
+
+/*
+ * Validation messages
+ */
+
+return [
+    // shown when the value is missing
+    'required' => 'The :attribute field is required.',
+    "accepted" => "The :attribute must be \"accepted\".\n",
+    'it\'s' => 'It\'s a C:\\path',
+    'min' => array(
+        'numeric' => 'The :attribute must be at least :min.',
+        'string'  => 'The :attribute must be at least :min characters.',
+    ),
+    'custom' => [
+        'email' => [
+            'required' => 'We need your email',
+        ],
+    ],
+    'days' => ['Monday', 'Tuesday', 'Wednesday'],
+    'max_length' => 255,
+    'enabled' => true,
+    'attributes' => [],
+];
+`
+
+func Test_PhpArray_Parse(t *testing.T) {
+	c, err := new(PhpArray).Parse([]byte(phpArraySource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("entries", 9, len(c.Entries), t)
+	tu.AssertEquals("comment", "shown when the value is missing", c.Find("required").Comment, t)
+	tu.AssertEquals("double quoted", "The :attribute must be \"accepted\".\n", c.Find("accepted").Value, t)
+	tu.AssertEquals("single quoted", `It's a C:\path`, c.Find("it's").Value, t)
+	tu.AssertEquals("array()", "The :attribute must be at least :min.", c.Find("min.numeric").Value, t)
+	tu.AssertEquals("nested", "We need your email", c.Find("custom.email.required").Value, t)
+	tu.AssertEquals("list", "Tuesday", c.Find("days.1").Value, t)
+
+	for _, unsafe := range []string{
+		"<?php return ['a' => __('b')];",
+		"<?php return ['a' => 'b' . 'c'];",
+		"<?php return ['a' => \"Hello $name\"];",
+		"<?php echo 'a'; return [];",
+		"<?php return ['a' => 'b'];\nunlink('x');",
+	} {
+		if _, err := new(PhpArray).Parse([]byte(unsafe)); err == nil {
+			t.Errorf("Expected an error for %s", unsafe)
+		}
+	}
+}
+
+func Test_PhpArray_Write(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	os.MkdirAll(filepath.Join(tmpDir, "en"), 0755)
+	ioutil.WriteFile(filepath.Join(tmpDir, "en", "validation.php"), []byte(phpArraySource), 0644)
+
+	data, _ := json.Marshal(map[string]string{
+		"required":              "Le champ :attribute est obligatoire.",
+		"it's":                  "C'est C:\\chemin",
+		"min.string":            "Au moins :min caractères.",
+		"custom.email.required": "",
+		"days.0":                "Lundi",
+		"days.2":                "Mercredi"})
+
+	if err := new(PhpArray).Write(tmpDir, "fr", "en", "validation", string(data), FileLocators["LOC-DIR"]); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?php
+
+/*
+ * Validation messages
+ */
+
+return [
+    // shown when the value is missing
+    'required' => 'Le champ :attribute est obligatoire.',
+    'it\'s' => 'C\'est C:\\chemin',
+    'min' => array(
+        'string' => 'Au moins :min caractères.',
+    ),
+    'days' => [
+        'Lundi',
+        2 => 'Mercredi',
+    ],
+    'max_length' => 255,
+    'enabled' => true,
+    'attributes' => [],
+];
+`
+	written := mustRead(filepath.Join(tmpDir, "fr", "validation.php"))
+	tu.AssertEquals("", expected, string(written), t)
+
+	c, err := new(PhpArray).Parse(written)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("list index", "Mercredi", c.Find("days.2").Value, t)
+}
+
+func Test_PhpArray_Serialize_NoTemplate(t *testing.T) {
+	c := NewCatalog("fr")
+	c.Add(&Entry{Key: "auth.failed", Value: "Identifiants incorrects"})
+	c.Add(&Entry{Key: "title", Value: "Titre"})
+	c.Add(&Entry{Key: "auth.throttle", Value: "Trop d'essais"})
+
+	out, err := new(PhpArray).Serialize(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<?php
+
+return [
+    'auth' => [
+        'failed' => 'Identifiants incorrects',
+        'throttle' => 'Trop d\'essais',
+    ],
+    'title' => 'Titre',
+];
+`
+	tu.AssertEquals("", expected, string(out), t)
+}
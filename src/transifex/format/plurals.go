@@ -0,0 +1,68 @@
+package format
+
+import "strings"
+
+// The plural rule of a language for formats that store plural forms by index (gettext, Qt)
+type pluralRule struct {
+	// the gettext Plural-Forms header value
+	forms string
+	// the CLDR category of each plural index
+	categories []string
+}
+
+var (
+	pluralsNone     = pluralRule{"nplurals=1; plural=0;", []string{"other"}}
+	pluralsOne      = pluralRule{"nplurals=2; plural=(n != 1);", []string{"one", "other"}}
+	pluralsOneOrNil = pluralRule{"nplurals=2; plural=(n > 1);", []string{"one", "other"}}
+	pluralsSlavic   = pluralRule{"nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);", []string{"one", "few", "many"}}
+	pluralsWestSlav = pluralRule{"nplurals=3; plural=(n==1) ? 0 : (n>=2 && n<=4) ? 1 : 2;", []string{"one", "few", "other"}}
+)
+
+var pluralRules = map[string]pluralRule{
+	"ja": pluralsNone, "ko": pluralsNone, "zh": pluralsNone, "vi": pluralsNone, "th": pluralsNone,
+	"id": pluralsNone, "ms": pluralsNone, "lo": pluralsNone, "km": pluralsNone, "my": pluralsNone,
+	"en": pluralsOne, "de": pluralsOne, "nl": pluralsOne, "sv": pluralsOne, "da": pluralsOne,
+	"no": pluralsOne, "nb": pluralsOne, "nn": pluralsOne, "fi": pluralsOne, "et": pluralsOne,
+	"it": pluralsOne, "es": pluralsOne, "pt": pluralsOne, "el": pluralsOne, "hu": pluralsOne,
+	"bg": pluralsOne, "ca": pluralsOne, "eu": pluralsOne, "gl": pluralsOne, "he": pluralsOne,
+	"tr": pluralsOne, "af": pluralsOne, "sq": pluralsOne, "eo": pluralsOne, "hi": pluralsOne,
+	"fr": pluralsOneOrNil, "pt_BR": pluralsOneOrNil, "hy": pluralsOneOrNil, "oc": pluralsOneOrNil,
+	"ru": pluralsSlavic, "uk": pluralsSlavic, "be": pluralsSlavic, "sr": pluralsSlavic, "hr": pluralsSlavic, "bs": pluralsSlavic,
+	"cs": pluralsWestSlav, "sk": pluralsWestSlav,
+	"pl": {"nplurals=3; plural=(n==1 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);", []string{"one", "few", "many"}},
+	"lt": {"nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && (n%100<10 || n%100>=20) ? 1 : 2);", []string{"one", "few", "other"}},
+	"lv": {"nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n != 0 ? 1 : 2);", []string{"one", "other", "zero"}},
+	"ro": {"nplurals=3; plural=(n==1 ? 0 : (n==0 || (n%100 > 0 && n%100 < 20)) ? 1 : 2);", []string{"one", "few", "other"}},
+	"sl": {"nplurals=4; plural=(n%100==1 ? 0 : n%100==2 ? 1 : n%100==3 || n%100==4 ? 2 : 3);", []string{"one", "two", "few", "other"}},
+	"ga": {"nplurals=5; plural=(n==1 ? 0 : n==2 ? 1 : n<7 ? 2 : n<11 ? 3 : 4);", []string{"one", "two", "few", "many", "other"}},
+	"ar": {"nplurals=6; plural=(n==0 ? 0 : n==1 ? 1 : n==2 ? 2 : n%100>=3 && n%100<=10 ? 3 : n%100>=11 ? 4 : 5);", []string{"zero", "one", "two", "few", "many", "other"}}}
+
+// Look up the plural rule of the language.  Regional codes (pt_BR, pt-BR) fall back to the base language
+func lookupPluralRule(lang string) (pluralRule, bool) {
+	lang = strings.Replace(lang, "-", "_", -1)
+	if rule, has := pluralRules[lang]; has {
+		return rule, true
+	}
+	if i := strings.Index(lang, "_"); i > 0 {
+		rule, has := pluralRules[lang[:i]]
+		return rule, has
+	}
+	return pluralRule{}, false
+}
+
+// The CLDR categories of the plural indices of the language.  If the language is unknown the categories
+// are guessed from the number of plural forms
+func pluralCategories(lang string, nplurals int) []string {
+	if rule, has := lookupPluralRule(lang); has && (nplurals <= 0 || nplurals == len(rule.categories)) {
+		return rule.categories
+	}
+	switch {
+	case nplurals == 1:
+		return pluralsNone.categories
+	case nplurals == 3:
+		return pluralsWestSlav.categories
+	case nplurals > 3 && nplurals <= len(PluralCategories):
+		return PluralCategories[len(PluralCategories)-nplurals:]
+	}
+	return pluralsOne.categories
+}
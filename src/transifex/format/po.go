@@ -0,0 +1,479 @@
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Gettext PO (and POT) files.  Contexts, plural forms, comments, references and flags are kept in the catalog.
+//
+// The source strings are uploaded as STRUCTURED_JSON, plural forms are encoded as ICU plural messages.
+// When writing a translation the header of the existing translation file is kept (or the header of the source
+// file for a new translation) with the Language and Plural-Forms fields updated for the language of the translation.
+// The obsolete entries (#~) of the existing translation file are kept at the end of the file and so are its previous
+// msgid comments (#|) of the entries that are still fuzzy.
+//
+// Extra parameters:
+// * ext - the file extension, po by default.  Use pot for template files
+// * mo - if true a compiled .mo file is written next to each translation file
+type Po struct {
+	ext string
+	mo  bool
+}
+
+func (f *Po) Init(initParams map[string]interface{}) {
+	if ext, has := initParams["ext"].(string); has {
+		f.ext = ext
+	}
+	switch mo := initParams["mo"].(type) {
+	case bool:
+		f.mo = mo
+	case string:
+		f.mo = mo == "true"
+	}
+}
+
+func (f *Po) Ext() string {
+	if f.ext == "" {
+		return "po"
+	}
+	return f.ext
+}
+
+// the header fields are written one per line, in the msgstr of the entry with an empty msgid
+const poHeaderKey = ""
+
+type poParser struct {
+	catalog *Catalog
+	entry   *Entry
+	// msgstr, msgstr[n] of the current entry
+	msgstr   map[int]string
+	field    string
+	index    int
+	comments []string
+	nplurals int
+	lineNum  int
+	// the #| comments of the current entry, and of all entries by id
+	previous    []string
+	previousIds map[string][]string
+	// the #~ lines of the obsolete entries, separated by empty lines
+	obsolete []string
+}
+
+func (f *Po) Parse(content []byte) (*Catalog, error) {
+	p, err := parsePo(content)
+	if err != nil {
+		return nil, err
+	}
+	return p.catalog, nil
+}
+
+func parsePo(content []byte) (*poParser, error) {
+	p := &poParser{catalog: NewCatalog(""), previousIds: map[string][]string{}}
+	p.reset()
+
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		p.lineNum++
+		if err := p.line(strings.TrimSpace(scanner.Text())); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	p.finishEntry()
+
+	// the plural indices can only be mapped to categories once the header has been read
+	for _, e := range p.catalog.Entries {
+		if e.IsPlural() {
+			e.Plurals = p.categorize(e.Plurals)
+		}
+	}
+	return p, nil
+}
+
+func (p *poParser) reset() {
+	p.entry = &Entry{}
+	p.msgstr = map[int]string{}
+	p.field = ""
+	p.comments = nil
+	p.previous = nil
+}
+
+func (p *poParser) line(line string) error {
+	switch {
+	case line == "":
+		p.finishEntry()
+		if len(p.obsolete) > 0 && p.obsolete[len(p.obsolete)-1] != "" {
+			p.obsolete = append(p.obsolete, "")
+		}
+	case strings.HasPrefix(line, "#~"):
+		// obsolete entries are not in the catalog, they are only kept by Serialize
+		p.obsolete = append(p.obsolete, line)
+	case strings.HasPrefix(line, "#"):
+		if p.field != "" {
+			p.finishEntry()
+		}
+		p.comment(line)
+	case strings.HasPrefix(line, "\""):
+		value, err := unquotePo(line)
+		if err != nil {
+			return fmt.Errorf("Line %d: %s", p.lineNum, err)
+		}
+		p.appendValue(value)
+	default:
+		keyword, rest := line, ""
+		if i := strings.IndexAny(line, " \t"); i > 0 {
+			keyword, rest = line[:i], strings.TrimSpace(line[i:])
+		}
+		value, err := unquotePo(rest)
+		if err != nil {
+			return fmt.Errorf("Line %d: %s", p.lineNum, err)
+		}
+		// a msgctxt or msgid after a msgstr starts a new entry even without a blank line
+		if (keyword == "msgctxt" || keyword == "msgid") && strings.HasPrefix(p.field, "msgstr") {
+			p.finishEntry()
+		}
+		p.field, p.index = keyword, 0
+		if strings.HasPrefix(keyword, "msgstr[") && strings.HasSuffix(keyword, "]") {
+			p.field = "msgstr"
+			if p.index, err = strconv.Atoi(keyword[len("msgstr[") : len(keyword)-1]); err != nil {
+				return fmt.Errorf("Line %d: invalid plural index %s", p.lineNum, keyword)
+			}
+			p.entry.Plurals = map[string]string{}
+		}
+		switch p.field {
+		case "msgctxt", "msgid", "msgid_plural", "msgstr":
+		default:
+			return fmt.Errorf("Line %d: unknown keyword %s", p.lineNum, keyword)
+		}
+		p.appendValue(value)
+	}
+	return nil
+}
+
+func (p *poParser) comment(line string) {
+	kind, text := line[:1], strings.TrimPrefix(line[1:], " ")
+	if len(line) > 1 {
+		kind = line[:2]
+		text = strings.TrimPrefix(line[2:], " ")
+	}
+	e := p.entry
+	switch kind {
+	case "#.":
+		e.Comment = joinLines(e.Comment, text)
+	case "#:":
+		e.References = append(e.References, strings.Fields(text)...)
+	case "#,":
+		for _, flag := range strings.Split(text, ",") {
+			if flag = strings.TrimSpace(flag); flag != "" {
+				e.Flags = append(e.Flags, flag)
+			}
+		}
+	case "#|":
+		// the previous msgid of fuzzy entries is not in the catalog, it is only kept by Serialize
+		p.previous = append(p.previous, line)
+	default:
+		p.comments = append(p.comments, strings.TrimPrefix(line[1:], " "))
+	}
+}
+
+func (p *poParser) appendValue(value string) {
+	e := p.entry
+	switch p.field {
+	case "msgctxt":
+		e.Context += value
+	case "msgid":
+		e.Key += value
+	case "msgid_plural":
+		e.PluralKey += value
+	case "msgstr":
+		p.msgstr[p.index] += value
+	}
+}
+
+func (p *poParser) finishEntry() {
+	e := p.entry
+	if p.field == "" {
+		// only comments, they belong to the next entry
+		return
+	}
+	e.TranslatorComment = strings.Join(p.comments, "\n")
+	if len(p.previous) > 0 {
+		p.previousIds[e.ID()] = p.previous
+	}
+
+	if e.Key == poHeaderKey && e.Context == "" {
+		p.catalog.Comment = e.TranslatorComment
+		for _, line := range strings.Split(p.msgstr[0], "\n") {
+			if i := strings.Index(line, ":"); i > 0 {
+				p.catalog.SetMeta(strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]))
+			}
+		}
+		if lang, has := p.catalog.GetMeta("Language"); has {
+			p.catalog.Lang = lang
+		}
+		if forms, has := p.catalog.GetMeta("Plural-Forms"); has {
+			p.nplurals = parseNPlurals(forms)
+		}
+	} else if e.Plurals != nil {
+		for i, value := range p.msgstr {
+			e.Plurals[strconv.Itoa(i)] = value
+		}
+		p.catalog.Add(e)
+	} else {
+		e.Value = p.msgstr[0]
+		p.catalog.Add(e)
+	}
+	p.reset()
+}
+
+// convert the plural indices to CLDR categories
+func (p *poParser) categorize(indexed map[string]string) map[string]string {
+	nplurals := p.nplurals
+	if nplurals <= 0 {
+		nplurals = len(indexed)
+	}
+	categories := pluralCategories(p.catalog.Lang, nplurals)
+	plurals := map[string]string{}
+	for i, category := range categories {
+		plurals[category] = indexed[strconv.Itoa(i)]
+	}
+	return plurals
+}
+
+func parseNPlurals(forms string) int {
+	for _, part := range strings.Split(forms, ";") {
+		part = strings.TrimSpace(part)
+		if strings.HasPrefix(part, "nplurals=") {
+			n, _ := strconv.Atoi(strings.TrimSpace(part[len("nplurals="):]))
+			return n
+		}
+	}
+	return 0
+}
+
+// Serialize the catalog.  The template is not required: only its obsolete entries and the previous msgid of its
+// fuzzy entries are written, all other data of the file is in the catalog
+func (f *Po) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	var out bytes.Buffer
+	t := &poParser{}
+	if template != nil {
+		var err error
+		if t, err = parsePo(template); err != nil {
+			return nil, err
+		}
+	}
+
+	writeComments(&out, "#", c.Comment)
+	out.WriteString("msgid \"\"\nmsgstr \"\"\n")
+	for _, m := range c.Meta {
+		out.WriteString(quotePo(m.Key + ": " + m.Value + "\n"))
+		out.WriteString("\n")
+	}
+
+	categories := c.pluralCategories()
+	for _, e := range c.Entries {
+		out.WriteString("\n")
+		writeComments(&out, "#", e.TranslatorComment)
+		writeComments(&out, "#.", e.Comment)
+		if len(e.References) > 0 {
+			out.WriteString("#: " + strings.Join(e.References, " ") + "\n")
+		}
+		if len(e.Flags) > 0 {
+			out.WriteString("#, " + strings.Join(e.Flags, ", ") + "\n")
+		}
+		if e.HasFlag("fuzzy") {
+			for _, line := range t.previousIds[e.ID()] {
+				out.WriteString(line + "\n")
+			}
+		}
+		if e.Context != "" {
+			writePoString(&out, "msgctxt", e.Context)
+		}
+		writePoString(&out, "msgid", e.Key)
+		if e.IsPlural() || e.PluralKey != "" {
+			writePoString(&out, "msgid_plural", e.PluralKey)
+			for i, category := range categories {
+				value, has := e.Plurals[category]
+				if !has {
+					value = e.Plurals["other"]
+				}
+				writePoString(&out, fmt.Sprintf("msgstr[%d]", i), value)
+			}
+		} else {
+			writePoString(&out, "msgstr", e.Value)
+		}
+	}
+	if obsolete := strings.TrimSuffix(strings.Join(t.obsolete, "\n"), "\n"); obsolete != "" {
+		out.WriteString("\n" + obsolete + "\n")
+	}
+	return out.Bytes(), nil
+}
+
+// the categories of the plural indices of the catalog language
+func (c *Catalog) pluralCategories() []string {
+	forms, _ := c.GetMeta("Plural-Forms")
+	return pluralCategories(c.Lang, parseNPlurals(forms))
+}
+
+func (f *Po) Clean(content []byte) ([]byte, string, error) {
+	c, err := f.Parse(content)
+	if err != nil {
+		return nil, "", err
+	}
+	// in the source file (and templates) the msgstr are normally empty, the source text is the msgid
+	for _, e := range c.Entries {
+		if e.IsPlural() {
+			for category, value := range e.Plurals {
+				if value == "" && category == "one" {
+					e.Plurals[category] = e.Key
+				} else if value == "" {
+					e.Plurals[category] = e.PluralKey
+				}
+			}
+		} else if e.Value == "" {
+			e.Value = e.Key
+		}
+		if len(e.References) > 0 {
+			e.Comment = joinLines(e.Comment, "Used in: "+strings.Join(e.References, ", "))
+		}
+	}
+	cleaned, err := EncodeStructuredJson(c)
+	if err != nil {
+		return nil, "", err
+	}
+	return cleaned, "STRUCTURED_JSON", nil
+}
+
+func (f *Po) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	path := fileLocator.Find(rootDir, langCode, filename, f.Ext())
+	fmt.Println("Updating translations file: " + path)
+
+	translated, _, err := translateCatalog(f, rootDir, langCode, srcLang, filename, translation, fileLocator)
+	if err != nil {
+		return err
+	}
+
+	// keep the header and the obsolete entries of the existing translation (translator, team, ...)
+	var template []byte
+	if existing, readErr := ioutil.ReadFile(path); readErr == nil {
+		if existingCatalog, parseErr := f.Parse(existing); parseErr == nil {
+			template = existing
+			if len(existingCatalog.Meta) > 0 {
+				translated.Comment = existingCatalog.Comment
+				translated.Meta = existingCatalog.Meta
+			}
+		}
+	} else if !os.IsNotExist(readErr) {
+		return readErr
+	}
+
+	translated.SetMeta("Language", langCode)
+	if rule, has := lookupPluralRule(langCode); has {
+		translated.SetMeta("Plural-Forms", rule.forms)
+	}
+	for _, e := range translated.Entries {
+		if e.Value != "" || e.IsPlural() && e.Plurals["other"] != "" {
+			e.RemoveFlag("fuzzy")
+		}
+	}
+
+	out, err := f.Serialize(translated, template)
+	if err != nil {
+		return err
+	}
+	if err = writeTranslationFile(path, out); err != nil {
+		return err
+	}
+
+	if f.mo {
+		moPath := strings.TrimSuffix(path, "."+f.Ext()) + ".mo"
+		fmt.Println("Compiling translations file: " + moPath)
+		return writeTranslationFile(moPath, CompileMo(translated))
+	}
+	return nil
+}
+
+func writeComments(out *bytes.Buffer, prefix, comment string) {
+	if comment == "" {
+		return
+	}
+	for _, line := range strings.Split(comment, "\n") {
+		if line == "" {
+			out.WriteString(prefix + "\n")
+		} else {
+			out.WriteString(prefix + " " + line + "\n")
+		}
+	}
+}
+
+// write a keyword and its string.  Multi-line strings are split after each newline
+func writePoString(out *bytes.Buffer, keyword, value string) {
+	lines := strings.SplitAfter(value, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= 1 {
+		out.WriteString(keyword + " " + quotePo(value) + "\n")
+		return
+	}
+	out.WriteString(keyword + " \"\"\n")
+	for _, line := range lines {
+		out.WriteString(quotePo(line) + "\n")
+	}
+}
+
+func quotePo(value string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n", "\t", "\\t", "\r", "\\r")
+	return "\"" + replacer.Replace(value) + "\""
+}
+
+func unquotePo(quoted string) (string, error) {
+	if len(quoted) < 2 || quoted[0] != '"' || quoted[len(quoted)-1] != '"' {
+		return "", fmt.Errorf("expected a quoted string but found: %s", quoted)
+	}
+	quoted = quoted[1 : len(quoted)-1]
+	var out strings.Builder
+	for i := 0; i < len(quoted); i++ {
+		c := quoted[i]
+		if c != '\\' || i == len(quoted)-1 {
+			out.WriteByte(c)
+			continue
+		}
+		i++
+		switch quoted[i] {
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case 'r':
+			out.WriteByte('\r')
+		case 'a':
+			out.WriteByte('\a')
+		case 'b':
+			out.WriteByte('\b')
+		case 'f':
+			out.WriteByte('\f')
+		case 'v':
+			out.WriteByte('\v')
+		default:
+			out.WriteByte(quoted[i])
+		}
+	}
+	return out.String(), nil
+}
+
+func joinLines(text, line string) string {
+	if text == "" {
+		return line
+	}
+	return text + "\n" + line
+}
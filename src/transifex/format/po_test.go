@@ -0,0 +1,238 @@
+package format
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	tu "testutil"
+)
+
+const poSource = `# Messages of the web application.
+# Copyright (C) 2026
+#
+msgid ""
+msgstr ""
+"Project-Id-Version: webapp 1.0\n"
+"Content-Type: text/plain; charset=UTF-8\n"
+"Language: en\n"
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+# translators: keep it short
+#. The title of the main page
+#: src/main.c:12 src/page.c:3
+msgid "Welcome"
+msgstr ""
+
+#, fuzzy, c-format
+msgctxt "menu"
+msgid "Open"
+msgstr "Open"
+
+msgctxt "door"
+msgid "Open"
+msgstr ""
+
+#: src/list.c:40
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] ""
+msgstr[1] ""
+
+msgid ""
+"A long text "
+"on two lines\n"
+"with a newline"
+msgstr ""
+
+#~ msgid "Obsolete"
+#~ msgstr "Obsolete"
+`
+
+func Test_Po_Parse(t *testing.T) {
+	c, err := new(Po).Parse([]byte(poSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tu.AssertEquals("lang", "en", c.Lang, t)
+	tu.AssertEquals("header comment", "Messages of the web application.\nCopyright (C) 2026\n", c.Comment, t)
+	tu.AssertEqualsInt("meta", 4, len(c.Meta), t)
+	version, _ := c.GetMeta("Project-Id-Version")
+	tu.AssertEquals("version", "webapp 1.0", version, t)
+	tu.AssertEqualsInt("entries", 5, len(c.Entries), t)
+
+	welcome := c.Entries[0]
+	tu.AssertEquals("key", "Welcome", welcome.Key, t)
+	tu.AssertEquals("translator comment", "translators: keep it short", welcome.TranslatorComment, t)
+	tu.AssertEquals("extracted comment", "The title of the main page", welcome.Comment, t)
+	tu.AssertEqualsInt("references", 2, len(welcome.References), t)
+
+	menu := c.Find("menu\u0004Open")
+	if menu == nil || !menu.HasFlag("fuzzy") || !menu.HasFlag("c-format") {
+		t.Errorf("Expected the menu entry to have the fuzzy and c-format flags: %v", menu)
+	}
+	if c.Find("door\u0004Open") == nil {
+		t.Errorf("Expected the door entry")
+	}
+
+	files := c.Entries[3]
+	tu.AssertEquals("plural key", "%d files", files.PluralKey, t)
+	if _, has := files.Plurals["one"]; !has {
+		t.Errorf("Expected the one category: %v", files.Plurals)
+	}
+	if _, has := files.Plurals["other"]; !has {
+		t.Errorf("Expected the other category: %v", files.Plurals)
+	}
+
+	tu.AssertEquals("multi-line", "A long text on two lines\nwith a newline", c.Entries[4].Key, t)
+}
+
+func Test_Po_Clean(t *testing.T) {
+	cleaned, i18n, err := new(Po).Clean([]byte(poSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("i18n type", "STRUCTURED_JSON", i18n, t)
+
+	var data map[string]map[string]string
+	json.Unmarshal(cleaned, &data)
+	tu.AssertEqualsInt("entries", 5, len(data), t)
+	tu.AssertEquals("source text", "Welcome", data["Welcome"]["string"], t)
+	tu.AssertEquals("comment", "The title of the main page\nUsed in: src/main.c:12, src/page.c:3", data["Welcome"]["developer_comment"], t)
+	tu.AssertEquals("context", "door", data["door\u0004Open"]["context"], t)
+	tu.AssertEquals("plural", "{cnt, plural, one {%d file} other {%d files}}", data["%d file"]["string"], t)
+}
+
+func Test_Po_Write(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	ioutil.WriteFile(filepath.Join(tmpDir, "en-messages.po"), []byte(poSource), 0644)
+
+	translation := map[string]string{
+		"Welcome":        "Добро пожаловать",
+		"menu\u0004Open": "Открыть",
+		"door\u0004Open": "Открыта",
+		"%d file":        "{cnt, plural, one {%d файл} few {%d файла} many {%d файлов} other {%d файла}}",
+		"A long text on two lines\nwith a newline": "Длинный текст\nв две строки"}
+	data, _ := json.Marshal(translation)
+
+	f := new(Po)
+	if err := f.Write(tmpDir, "ru", "en", "messages", string(data), FileLocators["LANG-NAME"]); err != nil {
+		t.Fatal(err)
+	}
+
+	written, _ := ioutil.ReadFile(filepath.Join(tmpDir, "ru-messages.po"))
+	expected := `# Messages of the web application.
+# Copyright (C) 2026
+#
+msgid ""
+msgstr ""
+"Project-Id-Version: webapp 1.0\n"
+"Content-Type: text/plain; charset=UTF-8\n"
+"Language: ru\n"
+"Plural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"
+
+# translators: keep it short
+#. The title of the main page
+#: src/main.c:12 src/page.c:3
+msgid "Welcome"
+msgstr "Добро пожаловать"
+
+#, c-format
+msgctxt "menu"
+msgid "Open"
+msgstr "Открыть"
+
+msgctxt "door"
+msgid "Open"
+msgstr "Открыта"
+
+#: src/list.c:40
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] "%d файл"
+msgstr[1] "%d файла"
+msgstr[2] "%d файлов"
+
+msgid ""
+"A long text on two lines\n"
+"with a newline"
+msgstr ""
+"Длинный текст\n"
+"в две строки"
+`
+	tu.AssertEquals("", expected, string(written), t)
+
+	// the header of an existing translation is kept
+	existing := "# Russian translation\nmsgid \"\"\nmsgstr \"\"\n\"Last-Translator: Ivan\\n\"\n\"Language: ru\\n\"\n"
+	ioutil.WriteFile(filepath.Join(tmpDir, "ru-messages.po"), []byte(existing), 0644)
+	if err := f.Write(tmpDir, "ru", "en", "messages", string(data), FileLocators["LANG-NAME"]); err != nil {
+		t.Fatal(err)
+	}
+	rewritten, err := f.Parse(mustRead(filepath.Join(tmpDir, "ru-messages.po")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("header comment", "Russian translation", rewritten.Comment, t)
+	translator, _ := rewritten.GetMeta("Last-Translator")
+	tu.AssertEquals("translator", "Ivan", translator, t)
+	tu.AssertEquals("plural few", "%d файла", rewritten.Entries[3].Plurals["few"], t)
+	tu.AssertEquals("plural many", "%d файлов", rewritten.Entries[3].Plurals["many"], t)
+}
+
+func mustRead(path string) []byte {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func Test_Po_Write_Obsolete(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	ioutil.WriteFile(filepath.Join(tmpDir, "en-messages.po"), []byte(poSource), 0644)
+	existing := `msgid ""
+msgstr ""
+"Language: ru\n"
+
+#, fuzzy
+#| msgctxt "menu"
+#| msgid "Open file"
+msgctxt "menu"
+msgid "Open"
+msgstr "Открыть файл"
+
+#~ msgid "Obsolete"
+#~ msgstr "Устаревший"
+
+#~ msgid "Removed"
+#~ msgstr "Удалено"
+`
+	ioutil.WriteFile(filepath.Join(tmpDir, "ru-messages.po"), []byte(existing), 0644)
+
+	f := new(Po)
+	data, _ := json.Marshal(map[string]string{"Welcome": "Добро пожаловать"})
+	if err := f.Write(tmpDir, "ru", "en", "messages", string(data), FileLocators["LANG-NAME"]); err != nil {
+		t.Fatal(err)
+	}
+	written := string(mustRead(filepath.Join(tmpDir, "ru-messages.po")))
+	fuzzy := "#, fuzzy, c-format\n#| msgctxt \"menu\"\n#| msgid \"Open file\"\nmsgctxt \"menu\"\n"
+	if !strings.Contains(written, fuzzy) {
+		t.Errorf("Expected the previous msgid of the fuzzy entry:\n%s", written)
+	}
+	obsolete := "msgstr \"\"\n\n#~ msgid \"Obsolete\"\n#~ msgstr \"Устаревший\"\n\n#~ msgid \"Removed\"\n#~ msgstr \"Удалено\"\n"
+	if !strings.HasSuffix(written, obsolete) {
+		t.Errorf("Expected the obsolete entries at the end:\n%s", written)
+	}
+
+	// the previous msgid is dropped once the entry is translated
+	data, _ = json.Marshal(map[string]string{"menu\u0004Open": "Открыть"})
+	if err := f.Write(tmpDir, "ru", "en", "messages", string(data), FileLocators["LANG-NAME"]); err != nil {
+		t.Fatal(err)
+	}
+	written = string(mustRead(filepath.Join(tmpDir, "ru-messages.po")))
+	if strings.Contains(written, "#|") || !strings.HasSuffix(written, obsolete) {
+		t.Errorf("Expected the obsolete entries without the previous msgid:\n%s", written)
+	}
+}
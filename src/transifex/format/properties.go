@@ -0,0 +1,271 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Java .properties files.  The comment lines directly before a property are uploaded as its comment.
+// Translations are written with the source file as the template so that its comments and layout are kept,
+// properties without a translation are left out so that java falls back to the parent bundle.
+//
+// Extra parameters:
+// * encoding - ISO-8859-1 (the default) or UTF-8.  Characters that cannot be encoded in ISO-8859-1 are written as \uXXXX
+type Properties struct {
+	encoding string
+}
+
+const encodingLatin1 = "ISO-8859-1"
+
+func (f *Properties) Init(initParams map[string]interface{}) {
+	f.encoding = encodingLatin1
+	if encoding, has := initParams["encoding"].(string); has {
+		f.encoding = strings.ToUpper(encoding)
+	}
+}
+
+func (f *Properties) Ext() string { return "properties" }
+
+// a logical line of a properties file: a property, or a comment or blank line
+type propertiesLine struct {
+	// the text of the line, including the continuation lines
+	raw string
+	// the key and value of a property
+	key, value string
+	// the raw key and separator of the property: "key = "
+	prefix     string
+	isProperty bool
+}
+
+func (f *Properties) Parse(content []byte) (*Catalog, error) {
+	lines, err := f.readLines(content)
+	if err != nil {
+		return nil, err
+	}
+	c := NewCatalog("")
+	comments := []string{}
+	for _, line := range lines {
+		switch {
+		case line.isProperty:
+			comment := strings.Join(comments, "\n")
+			comments = comments[:0]
+			if e := c.Find(line.key); e != nil {
+				e.Value = line.value
+			} else {
+				c.Add(&Entry{Key: line.key, Value: line.value, Comment: comment})
+			}
+		case strings.TrimSpace(line.raw) == "":
+			comments = comments[:0]
+		default:
+			comment := strings.TrimLeft(line.raw, " \t\f")
+			comments = append(comments, strings.TrimSpace(comment[1:]))
+		}
+	}
+	return c, nil
+}
+
+func (f *Properties) Clean(content []byte) ([]byte, string, error) {
+	return cleanToStructuredJson(f, content)
+}
+
+// The lines of the template are replayed with the values of the catalog.  Without a template the
+// properties are written with their comments
+func (f *Properties) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	var out bytes.Buffer
+	if template == nil {
+		for _, e := range c.Entries {
+			if e.Comment != "" {
+				out.WriteString("# " + strings.Replace(e.Comment, "\n", "\n# ", -1) + "\n")
+			}
+			out.WriteString(escapeProperty(e.Key, true) + "=" + escapeProperty(e.Value, false) + "\n")
+		}
+		return f.encode(out.String()), nil
+	}
+
+	lines, err := f.readLines(template)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		if !line.isProperty {
+			out.WriteString(line.raw + "\n")
+			continue
+		}
+		if e := c.Find(line.key); e != nil && e.Value != "" {
+			out.WriteString(line.prefix + escapeProperty(e.Value, false) + "\n")
+		}
+	}
+	text := out.String()
+	if !bytes.HasSuffix(template, []byte("\n")) {
+		text = strings.TrimSuffix(text, "\n")
+	}
+	return f.encode(text), nil
+}
+
+func (f *Properties) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	return writeCatalog(f, rootDir, langCode, srcLang, filename, translation, fileLocator)
+}
+
+func (f *Properties) decode(content []byte) (string, error) {
+	if f.encoding == "UTF-8" {
+		content = bytes.TrimPrefix(content, []byte{0xef, 0xbb, 0xbf})
+		if !utf8.Valid(content) {
+			return "", fmt.Errorf("The properties file is not valid UTF-8")
+		}
+		return string(content), nil
+	}
+	runes := make([]rune, len(content))
+	for i, b := range content {
+		runes[i] = rune(b)
+	}
+	return string(runes), nil
+}
+
+// encode the text in the encoding of the format, characters that are not in ISO-8859-1 are escaped
+func (f *Properties) encode(text string) []byte {
+	if f.encoding == "UTF-8" {
+		return []byte(text)
+	}
+	var out bytes.Buffer
+	for _, r := range text {
+		if r < 0x100 {
+			out.WriteByte(byte(r))
+			continue
+		}
+		for _, unit := range utf16.Encode([]rune{r}) {
+			fmt.Fprintf(&out, "\\u%04X", unit)
+		}
+	}
+	return out.Bytes()
+}
+
+// split the content into logical lines, joining the continuation lines of properties
+func (f *Properties) readLines(content []byte) ([]propertiesLine, error) {
+	text, err := f.decode(content)
+	if err != nil {
+		return nil, err
+	}
+	physical := strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")
+	if physical[len(physical)-1] == "" {
+		physical = physical[:len(physical)-1]
+	}
+
+	lines := []propertiesLine{}
+	for i := 0; i < len(physical); i++ {
+		raw := physical[i]
+		trimmed := strings.TrimLeft(raw, " \t\f")
+		if trimmed == "" || trimmed[0] == '#' || trimmed[0] == '!' {
+			lines = append(lines, propertiesLine{raw: raw})
+			continue
+		}
+		indent := raw[:len(raw)-len(trimmed)]
+		logical := trimmed
+		for continues(logical) && i+1 < len(physical) {
+			i++
+			raw += "\n" + physical[i]
+			logical = logical[:len(logical)-1] + strings.TrimLeft(physical[i], " \t\f")
+		}
+		if continues(logical) {
+			logical = logical[:len(logical)-1]
+		}
+		line := propertiesLine{raw: raw, isProperty: true}
+		keyEnd, valueStart := splitProperty(logical)
+		line.key = unescapeProperty(logical[:keyEnd])
+		line.value = unescapeProperty(logical[valueStart:])
+		line.prefix = indent + logical[:valueStart]
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// true if the line ends with an odd number of backslashes
+func continues(line string) bool {
+	backslashes := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		backslashes++
+	}
+	return backslashes%2 == 1
+}
+
+// Returns the end of the key and the start of the value of a property line.  The key ends at the first
+// unescaped =, : or whitespace, the separator is optional whitespace with at most one = or :
+func splitProperty(line string) (int, int) {
+	keyEnd := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+		} else if strings.IndexByte("=: \t\f", line[i]) >= 0 {
+			keyEnd = i
+			break
+		}
+	}
+	i := keyEnd
+	for i < len(line) && strings.IndexByte(" \t\f", line[i]) >= 0 {
+		i++
+	}
+	if i < len(line) && (line[i] == '=' || line[i] == ':') {
+		i++
+		for i < len(line) && strings.IndexByte(" \t\f", line[i]) >= 0 {
+			i++
+		}
+	}
+	return keyEnd, i
+}
+
+func unescapeProperty(s string) string {
+	var out []rune
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '\\' || i == len(runes)-1 {
+			out = append(out, runes[i])
+			continue
+		}
+		i++
+		switch runes[i] {
+		case 't':
+			out = append(out, '\t')
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case 'f':
+			out = append(out, '\f')
+		case 'u':
+			if i+5 <= len(runes) {
+				if code, err := strconv.ParseUint(string(runes[i+1:i+5]), 16, 32); err == nil {
+					i += 4
+					if n := len(out); n > 0 && utf16.IsSurrogate(out[n-1]) {
+						if decoded := utf16.DecodeRune(out[n-1], rune(code)); decoded != utf8.RuneError {
+							out[n-1] = decoded
+							continue
+						}
+					}
+					out = append(out, rune(code))
+					continue
+				}
+			}
+			out = append(out, 'u')
+		default:
+			out = append(out, runes[i])
+		}
+	}
+	return string(out)
+}
+
+var propertiesEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\t", `\t`, "\r", `\r`, "\f", `\f`)
+
+// escape a key or value.  The separators are escaped in keys, a leading space is escaped in values
+func escapeProperty(s string, key bool) string {
+	escaped := propertiesEscaper.Replace(s)
+	if key {
+		return strings.NewReplacer("=", `\=`, ":", `\:`, " ", `\ `, "#", `\#`, "!", `\!`).Replace(escaped)
+	}
+	if strings.HasPrefix(escaped, " ") {
+		return `\` + escaped
+	}
+	return escaped
+}
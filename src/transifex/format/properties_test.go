@@ -0,0 +1,105 @@
+package format
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	tu "testutil"
+)
+
+const propertiesSource = `# Messages of the service
+# Copyright 2026
+
+# The title of the main page
+title = Welcome
+greeting:Hello, {0}!
+	indented   value with spaces
+long = A long \
+       text on \
+       two lines
+escaped\ key\=x = \ leading space\nand newline
+unicode=caf\u00e9 \u20AC \uD83D\uDE00
+! an old style comment
+empty=
+`
+
+func Test_Properties_Parse(t *testing.T) {
+	c, err := new(Properties).Parse([]byte(propertiesSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tu.AssertEqualsInt("entries", 7, len(c.Entries), t)
+	tu.AssertEquals("title", "Welcome", c.Find("title").Value, t)
+	tu.AssertEquals("comment", "The title of the main page", c.Find("title").Comment, t)
+	tu.AssertEquals("colon", "Hello, {0}!", c.Find("greeting").Value, t)
+	tu.AssertEquals("no comment", "", c.Find("greeting").Comment, t)
+	tu.AssertEquals("whitespace separator", "value with spaces", c.Find("indented").Value, t)
+	tu.AssertEquals("continuation", "A long text on two lines", c.Find("long").Value, t)
+	tu.AssertEquals("escaped key", " leading space\nand newline", c.Find("escaped key=x").Value, t)
+	tu.AssertEquals("unicode", "café € \U0001F600", c.Find("unicode").Value, t)
+	tu.AssertEquals("old style comment", "an old style comment", c.Find("empty").Comment, t)
+}
+
+func Test_Properties_Encoding(t *testing.T) {
+	latin1 := []byte("title=caf\xe9\n")
+	c, err := new(Properties).Parse(latin1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("latin1", "café", c.Find("title").Value, t)
+
+	f := new(Properties)
+	f.Init(map[string]interface{}{"encoding": "utf-8"})
+	c, err = f.Parse([]byte("title=café\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("utf-8", "café", c.Find("title").Value, t)
+	if _, err = f.Parse(latin1); err == nil {
+		t.Errorf("Expected an error for content that is not UTF-8")
+	}
+
+	// an encoding that is not a string is ignored
+	f.Init(map[string]interface{}{"encoding": 8})
+	tu.AssertEquals("not a string", encodingLatin1, f.encoding, t)
+}
+
+func Test_Properties_Write(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	ioutil.WriteFile(filepath.Join(tmpDir, "messages.properties"), []byte(propertiesSource), 0644)
+
+	data, _ := json.Marshal(map[string]string{
+		"title":         "Добро пожаловать",
+		"greeting":      "Привет, {0}!",
+		"long":          "Длинный текст",
+		"escaped key=x": " ведущий пробел\nи новая строка",
+		"unicode":       "café"})
+
+	f := new(Properties)
+	f.Init(map[string]interface{}{})
+	if err := f.Write(tmpDir, "ru", "en", "messages", string(data), FileLocators["RESOURCE-BUNDLE"]); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# Messages of the service
+# Copyright 2026
+
+# The title of the main page
+title = \u0414\u043E\u0431\u0440\u043E \u043F\u043E\u0436\u0430\u043B\u043E\u0432\u0430\u0442\u044C
+greeting:\u041F\u0440\u0438\u0432\u0435\u0442, {0}!
+long = \u0414\u043B\u0438\u043D\u043D\u044B\u0439 \u0442\u0435\u043A\u0441\u0442
+escaped\ key\=x = \ \u0432\u0435\u0434\u0443\u0449\u0438\u0439 \u043F\u0440\u043E\u0431\u0435\u043B\n\u0438 \u043D\u043E\u0432\u0430\u044F \u0441\u0442\u0440\u043E\u043A\u0430
+unicode=caf` + "\xe9" + `
+! an old style comment
+`
+	written := mustRead(filepath.Join(tmpDir, "messages_ru.properties"))
+	tu.AssertEquals("", expected, string(written), t)
+
+	c, err := f.Parse(written)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("round trip", " ведущий пробел\nи новая строка", c.Find("escaped key=x").Value, t)
+}
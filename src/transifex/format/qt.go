@@ -0,0 +1,296 @@
+package format
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Qt Linguist translation files (.ts).  Messages are keyed by their source text with the name of their <context> as the
+// context, a disambiguation <comment> is added to the context as context|comment.  The <extracomment> is uploaded as the
+// comment and the locations as references.  Numerus messages are uploaded as plurals, vanished and obsolete messages are
+// not uploaded.
+//
+// The source file is the .ts file of the source language: its finished translations (or the source texts) are uploaded.
+// Translations are written with the source file as the template, translated messages are marked as finished and the
+// messages without a translation are left unfinished.  The numerus forms are written in the plural order of the language
+type Qt struct{}
+
+func (f *Qt) Init(initParams map[string]interface{}) {}
+func (f *Qt) Ext() string                            { return "ts" }
+
+type qtFile struct {
+	Language       string      `xml:"language,attr"`
+	SourceLanguage string      `xml:"sourcelanguage,attr"`
+	Contexts       []qtContext `xml:"context"`
+}
+
+type qtContext struct {
+	Name     string      `xml:"name"`
+	Messages []qtMessage `xml:"message"`
+}
+
+type qtMessage struct {
+	Numerus      string `xml:"numerus,attr"`
+	Source       string `xml:"source"`
+	Comment      string `xml:"comment"`
+	ExtraComment string `xml:"extracomment"`
+	Locations    []struct {
+		Filename string `xml:"filename,attr"`
+		Line     string `xml:"line,attr"`
+	} `xml:"location"`
+	Translation struct {
+		Type  string   `xml:"type,attr"`
+		Text  string   `xml:",chardata"`
+		Forms []string `xml:"numerusform"`
+	} `xml:"translation"`
+}
+
+const qtUnfinished = "unfinished"
+
+func (f *Qt) Parse(content []byte) (*Catalog, error) {
+	var file qtFile
+	if err := xml.Unmarshal(content, &file); err != nil {
+		return nil, err
+	}
+	sourceLang := file.SourceLanguage
+	if sourceLang == "" {
+		sourceLang = "en"
+	}
+	isSource := file.Language == "" || isSameQtLang(file.Language, sourceLang)
+	lang := file.Language
+	if lang == "" {
+		lang = sourceLang
+	}
+
+	c := NewCatalog(file.Language)
+	for _, context := range file.Contexts {
+		for _, m := range context.Messages {
+			translation := m.Translation
+			if translation.Type == "vanished" || translation.Type == "obsolete" {
+				continue
+			}
+			finished := translation.Type != qtUnfinished
+
+			e := &Entry{Key: m.Source, Context: qtContextID(context.Name, m.Comment), Comment: m.ExtraComment}
+			for _, l := range m.Locations {
+				e.References = append(e.References, l.Filename+":"+l.Line)
+			}
+			if m.Numerus == "yes" {
+				forms := translation.Forms
+				if isSource && (!finished || strings.Join(forms, "") == "") {
+					forms = nil
+				}
+				categories := pluralCategories(lang, len(forms))
+				e.Plurals = make(map[string]string, len(categories))
+				for i, category := range categories {
+					switch {
+					case forms == nil && isSource:
+						e.Plurals[category] = m.Source
+					case i < len(forms) && finished:
+						e.Plurals[category] = forms[i]
+					default:
+						e.Plurals[category] = ""
+					}
+				}
+			} else if finished && translation.Text != "" {
+				e.Value = translation.Text
+			} else if isSource {
+				e.Value = m.Source
+			}
+			c.Add(e)
+		}
+	}
+	return c, nil
+}
+
+func (f *Qt) Clean(content []byte) ([]byte, string, error) {
+	return cleanToStructuredJson(f, content)
+}
+
+func (f *Qt) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	if template == nil {
+		return buildQt(c), nil
+	}
+
+	var out bytes.Buffer
+	decoder := xml.NewDecoder(bytes.NewReader(template))
+	last := int64(0)
+	copyTo := func(offset int64) {
+		out.Write(template[last:offset])
+		last = offset
+	}
+
+	var context, source, disambiguation string
+	numerus := false
+	for {
+		before := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		t, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch t.Name.Local {
+		case "TS":
+			copyTo(before)
+			out.WriteString(qtStartTag(t, c.Lang))
+			last = decoder.InputOffset()
+		case "name", "source", "comment":
+			var text string
+			if err := decoder.DecodeElement(&text, &t); err != nil {
+				return nil, err
+			}
+			switch t.Name.Local {
+			case "name":
+				context = text
+			case "source":
+				source = text
+			default:
+				disambiguation = text
+			}
+		case "message":
+			source, disambiguation, numerus = "", "", xmlAttr(t, "numerus") == "yes"
+		case "translation":
+			if translationType := xmlAttr(t, "type"); translationType == "vanished" || translationType == "obsolete" {
+				continue
+			}
+			e := c.Find((&Entry{Key: source, Context: qtContextID(context, disambiguation)}).ID())
+			if e == nil {
+				continue
+			}
+			copyTo(before)
+			writeQtTranslation(&out, e, numerus, c.Lang, lineIndent(template, before))
+			if err := decoder.Skip(); err != nil {
+				return nil, err
+			}
+			last = decoder.InputOffset()
+		}
+	}
+	copyTo(int64(len(template)))
+	return out.Bytes(), nil
+}
+
+func (f *Qt) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	return writeCatalog(f, rootDir, langCode, srcLang, filename, translation, fileLocator)
+}
+
+// The translation element of the message: finished if the message (every numerus form) is translated
+func writeQtTranslation(out *bytes.Buffer, e *Entry, numerus bool, lang, indent string) {
+	if !numerus {
+		out.WriteString("<translation" + qtType(e.Value != "") + ">" + xmlTextEscaper.Replace(e.Value) + "</translation>")
+		return
+	}
+
+	forms := []string{}
+	finished := true
+	for _, category := range pluralCategories(lang, 0) {
+		form, has := e.Plurals[category]
+		if !has {
+			form = e.Plurals["other"]
+		}
+		finished = finished && form != ""
+		forms = append(forms, form)
+	}
+	out.WriteString("<translation" + qtType(finished) + ">\n")
+	for _, form := range forms {
+		out.WriteString(indent + "    <numerusform>" + xmlTextEscaper.Replace(form) + "</numerusform>\n")
+	}
+	out.WriteString(indent + "</translation>")
+}
+
+func qtType(finished bool) string {
+	if finished {
+		return ""
+	}
+	return ` type="` + qtUnfinished + `"`
+}
+
+// The <TS> start tag with the language of the translation
+func qtStartTag(t xml.StartElement, lang string) string {
+	tag := "<TS"
+	hasLang := false
+	for _, attr := range t.Attr {
+		value := attr.Value
+		if attr.Name.Local == "language" {
+			value, hasLang = lang, true
+		}
+		tag += fmt.Sprintf(` %s="%s"`, attr.Name.Local, xmlAttrEscaper.Replace(value))
+	}
+	if !hasLang {
+		tag += ` language="` + xmlAttrEscaper.Replace(lang) + `"`
+	}
+	return tag + ">"
+}
+
+// A translation file written without a source file
+func buildQt(c *Catalog) []byte {
+	var out bytes.Buffer
+	out.WriteString("<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<!DOCTYPE TS>\n")
+	out.WriteString(`<TS version="2.1" language="` + xmlAttrEscaper.Replace(c.Lang) + "\">\n")
+
+	contexts := []string{}
+	messages := map[string][]*Entry{}
+	for _, e := range c.Entries {
+		name := e.Context
+		if i := strings.Index(name, "|"); i >= 0 {
+			name = name[:i]
+		}
+		if _, has := messages[name]; !has {
+			contexts = append(contexts, name)
+		}
+		messages[name] = append(messages[name], e)
+	}
+
+	for _, name := range contexts {
+		out.WriteString("<context>\n    <name>" + xmlTextEscaper.Replace(name) + "</name>\n")
+		for _, e := range messages[name] {
+			if e.IsPlural() {
+				out.WriteString("    <message numerus=\"yes\">\n")
+			} else {
+				out.WriteString("    <message>\n")
+			}
+			out.WriteString("        <source>" + xmlTextEscaper.Replace(e.Key) + "</source>\n")
+			if i := strings.Index(e.Context, "|"); i >= 0 {
+				out.WriteString("        <comment>" + xmlTextEscaper.Replace(e.Context[i+1:]) + "</comment>\n")
+			}
+			if e.Comment != "" {
+				out.WriteString("        <extracomment>" + xmlTextEscaper.Replace(e.Comment) + "</extracomment>\n")
+			}
+			out.WriteString("        ")
+			writeQtTranslation(&out, e, e.IsPlural(), c.Lang, "        ")
+			out.WriteString("\n    </message>\n")
+		}
+		out.WriteString("</context>\n")
+	}
+	out.WriteString("</TS>\n")
+	return out.Bytes()
+}
+
+// the context of the entries of the messages: the context name and the disambiguation comment
+func qtContextID(name, disambiguation string) string {
+	if disambiguation == "" {
+		return name
+	}
+	return name + "|" + disambiguation
+}
+
+// true if the languages are the same or one is a region of the other (en, en_US)
+func isSameQtLang(a, b string) bool {
+	a, b = strings.Replace(a, "-", "_", -1), strings.Replace(b, "-", "_", -1)
+	return a == b || strings.HasPrefix(a, b+"_") || strings.HasPrefix(b, a+"_")
+}
+
+// the whitespace at the start of the line of the offset
+func lineIndent(content []byte, offset int64) string {
+	start := bytes.LastIndexByte(content[:offset], '\n') + 1
+	line := content[start:offset]
+	return string(line[:len(line)-len(bytes.TrimLeft(line, " \t"))])
+}
@@ -0,0 +1,140 @@
+package format
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	tu "testutil"
+)
+
+const qtSource = `<?xml version="1.0" encoding="utf-8"?>
+<!DOCTYPE TS>
+<TS version="2.1" language="en_US">
+<context>
+    <name>MainWindow</name>
+    <message>
+        <location filename="../mainwindow.cpp" line="12"/>
+        <source>&amp;Open</source>
+        <extracomment>The file menu</extracomment>
+        <translation type="unfinished"></translation>
+    </message>
+    <message>
+        <source>Open</source>
+        <comment>verb</comment>
+        <translation>Open file</translation>
+    </message>
+    <message numerus="yes">
+        <source>%n file(s)</source>
+        <translation type="unfinished">
+            <numerusform></numerusform>
+            <numerusform></numerusform>
+        </translation>
+    </message>
+    <message>
+        <source>Removed</source>
+        <translation type="vanished">Removed</translation>
+    </message>
+</context>
+<context>
+    <name>Dialog</name>
+    <message>
+        <source>Open</source>
+        <translation type="unfinished"></translation>
+    </message>
+</context>
+</TS>
+`
+
+func Test_Qt_Parse(t *testing.T) {
+	c, err := new(Qt).Parse([]byte(qtSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("entries", 4, len(c.Entries), t)
+	open := c.Find("MainWindow" + contextSeparator + "&Open")
+	tu.AssertEquals("source text", "&Open", open.Value, t)
+	tu.AssertEquals("extracomment", "The file menu", open.Comment, t)
+	tu.AssertEquals("location", "../mainwindow.cpp:12", open.References[0], t)
+	tu.AssertEquals("disambiguation", "Open file", c.Find("MainWindow|verb"+contextSeparator+"Open").Value, t)
+	tu.AssertEquals("numerus", "%n file(s)", c.Find("MainWindow" + contextSeparator + "%n file(s)").Plurals["one"], t)
+	tu.AssertEquals("other context", "Open", c.Find("Dialog"+contextSeparator+"Open").Value, t)
+
+	// the unfinished translations of a translation file are not translated
+	c, err = new(Qt).Parse([]byte(`<TS version="2.1" language="fr" sourcelanguage="en"><context><name>A</name>
+		<message><source>Yes</source><translation>Oui</translation></message>
+		<message><source>No</source><translation type="unfinished">Non</translation></message>
+		</context></TS>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("finished", "Oui", c.Find("A"+contextSeparator+"Yes").Value, t)
+	tu.AssertEquals("unfinished", "", c.Find("A"+contextSeparator+"No").Value, t)
+}
+
+func Test_Qt_Write(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	ioutil.WriteFile(filepath.Join(tmpDir, "app_en.ts"), []byte(qtSource), 0644)
+
+	data, _ := json.Marshal(map[string]string{
+		"MainWindow" + contextSeparator + "&Open":      "&Ouvrir",
+		"MainWindow" + contextSeparator + "%n file(s)": "{cnt, plural, one {%n fichier} other {%n fichiers}}",
+		"Dialog" + contextSeparator + "Open":           ""})
+
+	locator := FileLocators["RESOURCE-BUNDLE"]
+	if err := new(Qt).Write(tmpDir, "fr", "en", "app", string(data), locator); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?xml version="1.0" encoding="utf-8"?>
+<!DOCTYPE TS>
+<TS version="2.1" language="fr">
+<context>
+    <name>MainWindow</name>
+    <message>
+        <location filename="../mainwindow.cpp" line="12"/>
+        <source>&amp;Open</source>
+        <extracomment>The file menu</extracomment>
+        <translation>&amp;Ouvrir</translation>
+    </message>
+    <message>
+        <source>Open</source>
+        <comment>verb</comment>
+        <translation type="unfinished"></translation>
+    </message>
+    <message numerus="yes">
+        <source>%n file(s)</source>
+        <translation>
+            <numerusform>%n fichier</numerusform>
+            <numerusform>%n fichiers</numerusform>
+        </translation>
+    </message>
+    <message>
+        <source>Removed</source>
+        <translation type="vanished">Removed</translation>
+    </message>
+</context>
+<context>
+    <name>Dialog</name>
+    <message>
+        <source>Open</source>
+        <translation type="unfinished"></translation>
+    </message>
+</context>
+</TS>
+`
+	tu.AssertEquals("", expected, string(mustRead(filepath.Join(tmpDir, "app_fr.ts"))), t)
+
+	// a language with three plural forms
+	data, _ = json.Marshal(map[string]string{
+		"MainWindow" + contextSeparator + "%n file(s)": "{cnt, plural, one {%n plik} few {%n pliki} many {%n plików}}"})
+	if err := new(Qt).Write(tmpDir, "pl", "en", "app", string(data), locator); err != nil {
+		t.Fatal(err)
+	}
+	c, err := new(Qt).Parse(mustRead(filepath.Join(tmpDir, "app_pl.ts")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("few", "%n pliki", c.Find("MainWindow" + contextSeparator + "%n file(s)").Plurals["few"], t)
+	tu.AssertEquals("untranslated", "", c.Find("MainWindow"+contextSeparator+"&Open").Value, t)
+}
@@ -0,0 +1,188 @@
+package format
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// .NET resources (.resx).  The string <data> elements are keyed by their name and their <comment> is uploaded as the
+// comment.  Data of other types (images, icons ...) and the >> metadata of the Windows Forms designer are not uploaded.
+//
+// Translations are written with the source file as the template: the resheaders, the schema and everything but the
+// data elements are kept as they are, strings without a translation and the data that is not a string are left out
+// so that .NET falls back to the neutral resources.  Use it with the NAME-LANG structure for the Strings.resx,
+// Strings.fr.resx ... files, the neutral file is never overwritten by a download
+type Resx struct{}
+
+func (f *Resx) Init(initParams map[string]interface{}) {}
+func (f *Resx) Ext() string                            { return "resx" }
+
+func (f *Resx) Parse(content []byte) (*Catalog, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	c := NewCatalog("")
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return c, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.EndElement:
+			depth--
+		case xml.StartElement:
+			if depth++; depth != 2 || t.Name.Local != "data" {
+				continue
+			}
+			depth--
+			var data struct {
+				Value   string `xml:"value"`
+				Comment string `xml:"comment"`
+			}
+			if err := decoder.DecodeElement(&data, &t); err != nil {
+				return nil, err
+			}
+			if isResxString(t) {
+				c.Add(&Entry{Key: xmlAttr(t, "name"), Value: data.Value, Comment: data.Comment})
+			}
+		}
+	}
+}
+
+func (f *Resx) Clean(content []byte) ([]byte, string, error) {
+	return cleanToStructuredJson(f, content)
+}
+
+func (f *Resx) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	var out bytes.Buffer
+	if template == nil {
+		out.WriteString(resxHeaders)
+		for _, e := range c.Entries {
+			if e.Value != "" {
+				out.WriteString(resxData(e))
+			}
+		}
+		out.WriteString("</root>\n")
+		return out.Bytes(), nil
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(template))
+	last := int64(0)
+	copyTo := func(offset int64) {
+		out.Write(template[last:offset])
+		last = offset
+	}
+	// replace the content of the <value> of the current data element
+	writeValue := func(value string) error {
+		for {
+			before := decoder.InputOffset()
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			switch t := token.(type) {
+			case xml.EndElement:
+				// the end of the data element
+				return nil
+			case xml.StartElement:
+				switch {
+				case t.Name.Local != "value":
+					err = decoder.Skip()
+				case isSelfClosing(template, decoder.InputOffset()):
+					copyTo(before)
+					out.WriteString("<value>" + xmlTextEscaper.Replace(value) + "</value>")
+					err = decoder.Skip()
+					last = decoder.InputOffset()
+				default:
+					copyTo(decoder.InputOffset())
+					out.WriteString(xmlTextEscaper.Replace(value))
+					last, err = skipXmlContent(decoder)
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	depth := 0
+	for {
+		before := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.EndElement:
+			depth--
+		case xml.StartElement:
+			if depth++; depth != 2 || t.Name.Local != "data" {
+				continue
+			}
+			depth--
+			e := c.Find(xmlAttr(t, "name"))
+			if !isResxString(t) || e == nil || e.Value == "" {
+				// leave out the element, including the indentation of its line
+				copyTo(before)
+				trimmed := bytes.TrimRight(out.Bytes(), " \t")
+				out.Truncate(len(bytes.TrimSuffix(trimmed, []byte("\n"))))
+				decoder.Skip()
+				last = decoder.InputOffset()
+				continue
+			}
+			if err := writeValue(e.Value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	copyTo(int64(len(template)))
+	return out.Bytes(), nil
+}
+
+// The neutral file is the source file, it is never overwritten
+func (f *Resx) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	if langCode == srcLang {
+		return nil
+	}
+	return writeCatalog(f, rootDir, langCode, srcLang, filename, translation, fileLocator)
+}
+
+// a data element with a string value that is not designer metadata
+func isResxString(t xml.StartElement) bool {
+	dataType := xmlAttr(t, "type")
+	return xmlAttr(t, "mimetype") == "" && (dataType == "" || strings.HasPrefix(dataType, "System.String")) &&
+		!strings.HasPrefix(xmlAttr(t, "name"), ">>")
+}
+
+func resxData(e *Entry) string {
+	data := `  <data name="` + xmlAttrEscaper.Replace(e.Key) + `" xml:space="preserve">` + "\n"
+	data += "    <value>" + xmlTextEscaper.Replace(e.Value) + "</value>\n"
+	if e.Comment != "" {
+		data += "    <comment>" + xmlTextEscaper.Replace(e.Comment) + "</comment>\n"
+	}
+	return data + "  </data>\n"
+}
+
+// the start of a file without a source file
+const resxHeaders = `<?xml version="1.0" encoding="utf-8"?>
+<root>
+  <resheader name="resmimetype">
+    <value>text/microsoft-resx</value>
+  </resheader>
+  <resheader name="version">
+    <value>2.0</value>
+  </resheader>
+  <resheader name="reader">
+    <value>System.Resources.ResXResourceReader, System.Windows.Forms, Version=4.0.0.0, Culture=neutral, PublicKeyToken=b77a5c561934e089</value>
+  </resheader>
+  <resheader name="writer">
+    <value>System.Resources.ResXResourceWriter, System.Windows.Forms, Version=4.0.0.0, Culture=neutral, PublicKeyToken=b77a5c561934e089</value>
+  </resheader>
+`
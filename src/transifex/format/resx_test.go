@@ -0,0 +1,98 @@
+package format
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	tu "testutil"
+)
+
+const resxSource = `<?xml version="1.0" encoding="utf-8"?>
+<root>
+  <xsd:schema id="root" xmlns="" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:msdata="urn:schemas-microsoft-com:xml-msdata">
+    <xsd:element name="root" msdata:IsDataSet="true" />
+  </xsd:schema>
+  <resheader name="resmimetype">
+    <value>text/microsoft-resx</value>
+  </resheader>
+  <data name="Title" xml:space="preserve">
+    <value>Welcome &amp; hello</value>
+    <comment>The title of the main window</comment>
+  </data>
+  <data name="Empty" xml:space="preserve">
+    <value />
+  </data>
+  <data name="Save" xml:space="preserve">
+    <value>Save</value>
+  </data>
+  <data name="Logo" type="System.Drawing.Bitmap, System.Drawing" mimetype="application/x-microsoft.net.object.bytearray.base64">
+    <value>iVBORw0KGgo=</value>
+  </data>
+  <data name="&gt;&gt;button1.Name" xml:space="preserve">
+    <value>button1</value>
+  </data>
+  <data name="Label" type="System.String, mscorlib">
+    <value>Name</value>
+  </data>
+</root>
+`
+
+func Test_Resx_Parse(t *testing.T) {
+	c, err := new(Resx).Parse([]byte(resxSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("entries", 4, len(c.Entries), t)
+	tu.AssertEquals("value", "Welcome & hello", c.Find("Title").Value, t)
+	tu.AssertEquals("comment", "The title of the main window", c.Find("Title").Comment, t)
+	tu.AssertEquals("empty", "", c.Find("Empty").Value, t)
+	tu.AssertEquals("string type", "Name", c.Find("Label").Value, t)
+	if c.Find("Logo") != nil || c.Find(">>button1.Name") != nil {
+		t.Errorf("Non string data and designer metadata should not be uploaded")
+	}
+}
+
+func Test_Resx_Write(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	ioutil.WriteFile(filepath.Join(tmpDir, "Strings.resx"), []byte(resxSource), 0644)
+
+	data, _ := json.Marshal(map[string]string{
+		"Title": "Bem-vindo <olá>",
+		"Empty": "Vazio",
+		"Label": "Nome"})
+
+	f := new(Resx)
+	locator := FileLocators["NAME-LANG"]
+	if err := f.Write(tmpDir, "pt_BR", "en", "Strings", string(data), locator); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?xml version="1.0" encoding="utf-8"?>
+<root>
+  <xsd:schema id="root" xmlns="" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:msdata="urn:schemas-microsoft-com:xml-msdata">
+    <xsd:element name="root" msdata:IsDataSet="true" />
+  </xsd:schema>
+  <resheader name="resmimetype">
+    <value>text/microsoft-resx</value>
+  </resheader>
+  <data name="Title" xml:space="preserve">
+    <value>Bem-vindo &lt;olá&gt;</value>
+    <comment>The title of the main window</comment>
+  </data>
+  <data name="Empty" xml:space="preserve">
+    <value>Vazio</value>
+  </data>
+  <data name="Label" type="System.String, mscorlib">
+    <value>Nome</value>
+  </data>
+</root>
+`
+	tu.AssertEquals("", expected, string(mustRead(filepath.Join(tmpDir, "Strings.pt-BR.resx"))), t)
+
+	// the neutral file is never overwritten
+	if err := f.Write(tmpDir, "en", "en", "Strings", string(data), locator); err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("neutral", resxSource, string(mustRead(filepath.Join(tmpDir, "Strings.resx"))), t)
+}
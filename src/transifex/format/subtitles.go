@@ -0,0 +1,179 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Subtitles: SubRip (.srt) and WebVTT (.vtt) files.  The text of each cue is uploaded, keyed by the index of the cue
+// in srt files and by the identifier of the cue in vtt files (or its number for a cue without an identifier).  The
+// NOTE before a vtt cue is uploaded as its comment.
+//
+// Translations are written with the source file as the template: the timing, the settings of the cues and the vtt
+// header, STYLE and REGION blocks are kept, the text of each cue is replaced by its translation.  A translation must
+// have every cue of the source file, cues with an empty translation keep the source text
+type Subtitles struct {
+	vtt bool
+}
+
+const (
+	srt = false
+	vtt = true
+)
+
+func (f *Subtitles) Init(initParams map[string]interface{}) {}
+
+func (f *Subtitles) Ext() string {
+	if f.vtt {
+		return "vtt"
+	}
+	return "srt"
+}
+
+// A block of lines separated by blank lines: a cue or a vtt header, NOTE, STYLE or REGION block
+type subtitleBlock struct {
+	// the lines before the text of a cue: the index or identifier and the timing.  All the lines of other blocks
+	header []string
+	text   []string
+	isCue  bool
+	key    string
+	// the NOTE before the cue
+	comment string
+}
+
+func (f *Subtitles) Parse(content []byte) (*Catalog, error) {
+	blocks, err := f.parseBlocks(content)
+	if err != nil {
+		return nil, err
+	}
+	c := NewCatalog("")
+	for _, b := range blocks {
+		if b.isCue {
+			c.Add(&Entry{Key: b.key, Value: strings.Join(b.text, "\n"), Comment: b.comment})
+		}
+	}
+	return c, nil
+}
+
+func (f *Subtitles) Clean(content []byte) ([]byte, string, error) {
+	return cleanToStructuredJson(f, content)
+}
+
+func (f *Subtitles) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	if template == nil {
+		return nil, fmt.Errorf("Subtitles can only be written with the timing of the source file")
+	}
+	blocks, err := f.parseBlocks(template)
+	if err != nil {
+		return nil, err
+	}
+
+	newline := "\n"
+	if bytes.Contains(template, []byte("\r\n")) {
+		newline = "\r\n"
+	}
+	var out bytes.Buffer
+	if bytes.HasPrefix(template, utf8BOM) {
+		out.Write(utf8BOM)
+	}
+	for i, b := range blocks {
+		if i > 0 {
+			out.WriteString(newline)
+		}
+		lines := b.header
+		if b.isCue {
+			text := b.text
+			if e := c.Find(b.key); e != nil && e.Value != "" {
+				// a blank line would end the cue
+				text = []string{}
+				for _, line := range strings.Split(e.Value, "\n") {
+					if strings.TrimSpace(line) != "" {
+						text = append(text, strings.TrimRight(line, "\r"))
+					}
+				}
+			}
+			lines = append(append([]string{}, b.header...), text...)
+		}
+		for _, line := range lines {
+			out.WriteString(line + newline)
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// The translation must have a translation for every cue of the source file
+func (f *Subtitles) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	path := fileLocator.Find(rootDir, langCode, filename, f.Ext())
+	fmt.Println("Updating translations file: " + path)
+
+	translated, template, err := translateCatalog(f, rootDir, langCode, srcLang, filename, translation, fileLocator)
+	if err != nil {
+		return err
+	}
+	translations, err := DecodeTranslation([]byte(translation), langCode)
+	if err != nil {
+		return err
+	}
+	if len(translations.Entries) != len(translated.Entries) {
+		return fmt.Errorf("The translation has %d cues but the source file has %d cues", len(translations.Entries), len(translated.Entries))
+	}
+
+	out, err := f.Serialize(translated, template)
+	if err != nil {
+		return err
+	}
+	return writeTranslationFile(path, out)
+}
+
+func (f *Subtitles) parseBlocks(content []byte) ([]*subtitleBlock, error) {
+	text := strings.Replace(string(bytes.TrimPrefix(content, utf8BOM)), "\r\n", "\n", -1)
+	blocks := []*subtitleBlock{}
+	comment := ""
+	cues := 0
+	var block []string
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			block = append(block, line)
+			if i < len(lines)-1 {
+				continue
+			}
+		}
+		if len(block) == 0 {
+			continue
+		}
+
+		b := &subtitleBlock{header: block}
+		first := strings.TrimSpace(block[0])
+		switch {
+		case f.vtt && len(blocks) == 0:
+			if !strings.HasPrefix(first, "WEBVTT") {
+				return nil, fmt.Errorf("Not a valid vtt file: it does not start with WEBVTT")
+			}
+		case f.vtt && (first == "NOTE" || strings.HasPrefix(first, "NOTE ") || strings.HasPrefix(first, "NOTE\t")):
+			note := append([]string{strings.TrimSpace(strings.TrimPrefix(first, "NOTE"))}, block[1:]...)
+			comment = strings.TrimSpace(strings.Join(note, "\n"))
+		case f.vtt && (first == "STYLE" || first == "REGION"):
+		default:
+			cues++
+			timing := 0
+			if !strings.Contains(first, "-->") {
+				timing = 1
+			}
+			if timing >= len(block) || !strings.Contains(block[timing], "-->") {
+				return nil, fmt.Errorf("Not a valid %s file: cue %d has no timing line", f.Ext(), cues)
+			}
+			b.isCue, b.header, b.text, b.comment = true, block[:timing+1], block[timing+1:], comment
+			b.key = strconv.Itoa(cues)
+			if timing == 1 {
+				b.key = first
+			}
+			comment = ""
+		}
+		blocks = append(blocks, b)
+		block = nil
+	}
+	return blocks, nil
+}
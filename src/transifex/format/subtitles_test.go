@@ -0,0 +1,85 @@
+package format
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	tu "testutil"
+)
+
+const srtSource = "1\r\n00:00:01,000 --> 00:00:04,000\r\nWelcome to the <i>tour</i>\r\n\r\n2\r\n00:00:05,500 --> 00:00:08,000 X1:40 X2:600\r\nClick Save\r\nto continue\r\n\r\n"
+
+const vttSource = `WEBVTT - Product tour
+
+STYLE
+::cue { color: yellow }
+
+NOTE The first screen
+
+intro
+00:00:01.000 --> 00:00:04.000 align:start
+Welcome to the tour
+
+00:00:05.500 --> 00:00:08.000
+Click Save
+`
+
+func Test_Subtitles_Parse(t *testing.T) {
+	c, err := Formats["SRT"]().Parse([]byte(srtSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("srt cues", 2, len(c.Entries), t)
+	tu.AssertEquals("index", "Welcome to the <i>tour</i>", c.Find("1").Value, t)
+	tu.AssertEquals("lines", "Click Save\nto continue", c.Find("2").Value, t)
+
+	c, err = Formats["VTT"]().Parse([]byte(vttSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("vtt cues", 2, len(c.Entries), t)
+	tu.AssertEquals("identifier", "Welcome to the tour", c.Find("intro").Value, t)
+	tu.AssertEquals("note", "The first screen", c.Find("intro").Comment, t)
+	tu.AssertEquals("number", "Click Save", c.Find("2").Value, t)
+
+	if _, err = Formats["VTT"]().Parse([]byte(srtSource)); err == nil {
+		t.Errorf("Expected an error for a vtt file without WEBVTT")
+	}
+	if _, err = Formats["SRT"]().Parse([]byte("1\nno timing\n")); err == nil {
+		t.Errorf("Expected an error for a cue without timing")
+	}
+}
+
+func Test_Subtitles_Write(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	ioutil.WriteFile(filepath.Join(tmpDir, "en-tour.srt"), []byte(srtSource), 0644)
+	ioutil.WriteFile(filepath.Join(tmpDir, "en-tour.vtt"), []byte(vttSource), 0644)
+
+	data, _ := json.Marshal(map[string]string{
+		"1": "Bienvenue dans la <i>visite</i>",
+		"2": "Cliquez sur Enregistrer\n\npour continuer"})
+	if err := Formats["SRT"]().Write(tmpDir, "fr", "en", "tour", string(data), FileLocators["LANG-NAME"]); err != nil {
+		t.Fatal(err)
+	}
+	expected := "1\r\n00:00:01,000 --> 00:00:04,000\r\nBienvenue dans la <i>visite</i>\r\n\r\n2\r\n00:00:05,500 --> 00:00:08,000 X1:40 X2:600\r\nCliquez sur Enregistrer\r\npour continuer\r\n"
+	tu.AssertEquals("srt", expected, string(mustRead(filepath.Join(tmpDir, "fr-tour.srt"))), t)
+
+	data, _ = json.Marshal(map[string]string{"intro": "Bienvenue", "2": ""})
+	if err := Formats["VTT"]().Write(tmpDir, "fr", "en", "tour", string(data), FileLocators["LANG-NAME"]); err != nil {
+		t.Fatal(err)
+	}
+	expected = strings.Replace(vttSource, "Welcome to the tour", "Bienvenue", 1)
+	tu.AssertEquals("vtt", expected, string(mustRead(filepath.Join(tmpDir, "fr-tour.vtt"))), t)
+
+	// every cue must be in the translation
+	data, _ = json.Marshal(map[string]string{"intro": "Bienvenue"})
+	if err := Formats["VTT"]().Write(tmpDir, "de", "en", "tour", string(data), FileLocators["LANG-NAME"]); err == nil {
+		t.Errorf("Expected an error for a translation with a missing cue")
+	}
+	data, _ = json.Marshal(map[string]string{"intro": "Bienvenue", "2": "Speichern", "3": "Extra"})
+	if err := Formats["VTT"]().Write(tmpDir, "de", "en", "tour", string(data), FileLocators["LANG-NAME"]); err == nil {
+		t.Errorf("Expected an error for a translation with an unknown cue")
+	}
+}
@@ -0,0 +1,169 @@
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The columns of the strings of a spreadsheet (CSV, XLSX).  A column is given by its name in the header row
+// or by its number, 1 for the first column.
+//
+// Extra parameters:
+// * key, value, comment - the columns of the keys, the strings and the comments: key, value and comment by
+// default.  The comment column is optional
+// * header - false if the first row is not a header row (the columns must be given by number)
+type tableColumns struct {
+	key, value, comment interface{}
+	header              bool
+}
+
+func (t *tableColumns) init(initParams map[string]interface{}) {
+	t.key, t.value, t.comment, t.header = "key", "value", "comment", true
+	for name, column := range map[string]*interface{}{"key": &t.key, "value": &t.value, "comment": &t.comment} {
+		if param, has := initParams[name]; has {
+			*column = param
+		}
+	}
+	switch header := initParams["header"].(type) {
+	case bool:
+		t.header = header
+	case string:
+		t.header = header != "false"
+	}
+}
+
+// The index of the column in the rows, -1 if there is no such column
+func (t *tableColumns) index(rows [][]string, column interface{}) int {
+	switch column := column.(type) {
+	case float64:
+		return int(column) - 1
+	case int:
+		return column - 1
+	case string:
+		if n, err := strconv.Atoi(column); err == nil {
+			return n - 1
+		}
+		if t.header && len(rows) > 0 {
+			for i, name := range rows[0] {
+				if strings.EqualFold(strings.TrimSpace(name), column) {
+					return i
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// The indices of the key and value columns.  Returns an error if the file does not have them
+func (t *tableColumns) find(rows [][]string, value interface{}) (int, int, error) {
+	key, valueIndex := t.index(rows, t.key), t.index(rows, value)
+	if key < 0 {
+		return 0, 0, fmt.Errorf("The file has no %v column", t.key)
+	}
+	if valueIndex < 0 {
+		return 0, 0, fmt.Errorf("The file has no %v column", value)
+	}
+	return key, valueIndex, nil
+}
+
+// The first row of the strings
+func (t *tableColumns) first() int {
+	if t.header {
+		return 1
+	}
+	return 0
+}
+
+// The strings of the value column.  Rows without a key are skipped, empty strings are left out if skipEmpty
+func (t *tableColumns) catalog(rows [][]string, value interface{}, skipEmpty bool) (*Catalog, error) {
+	key, valueIndex, err := t.find(rows, value)
+	if err != nil {
+		return nil, err
+	}
+	comment := t.index(rows, t.comment)
+
+	c := NewCatalog("")
+	for i := t.first(); i < len(rows); i++ {
+		e := &Entry{Key: tableCell(rows[i], key), Value: tableCell(rows[i], valueIndex), Comment: tableCell(rows[i], comment)}
+		if e.Key != "" && (e.Value != "" || !skipEmpty) {
+			c.Add(e)
+		}
+	}
+	return c, nil
+}
+
+// Set the value column of the rows to the translations of their keys, empty for the strings without a translation
+func (t *tableColumns) translate(rows [][]string, c *Catalog, value interface{}) error {
+	key, valueIndex, err := t.find(rows, value)
+	if err != nil {
+		return err
+	}
+	for i := t.first(); i < len(rows); i++ {
+		if tableCell(rows[i], key) == "" {
+			continue
+		}
+		translation := ""
+		if e := c.Find(rows[i][key]); e != nil {
+			translation = e.Value
+		}
+		for len(rows[i]) <= valueIndex {
+			rows[i] = append(rows[i], "")
+		}
+		rows[i][valueIndex] = translation
+	}
+	return nil
+}
+
+// The rows of a file written without a source file: the header row and the key and comment of each string.
+// The columns given by name are written in the order key, value, comment
+func (t *tableColumns) newRows(c *Catalog, value interface{}) [][]string {
+	columns := []interface{}{t.key, value, t.comment}
+	indices := make([]int, len(columns))
+	width := 0
+	for i, column := range columns {
+		if indices[i] = t.index(nil, column); indices[i] < 0 {
+			indices[i] = i
+		}
+		if indices[i] >= width {
+			width = indices[i] + 1
+		}
+	}
+
+	rows := [][]string{}
+	if t.header {
+		header := make([]string, width)
+		for i, column := range columns {
+			header[indices[i]] = fmt.Sprint(column)
+		}
+		rows = append(rows, header)
+	}
+	for _, e := range c.Entries {
+		row := make([]string, width)
+		row[indices[0]], row[indices[2]] = e.Key, e.Comment
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// the languages of the columns of the header row that are not the key or comment column, pt-BR is pt_BR
+func (t *tableColumns) languages(rows [][]string) map[string]int {
+	langs := map[string]int{}
+	if len(rows) == 0 {
+		return langs
+	}
+	key, comment := t.index(rows, t.key), t.index(rows, t.comment)
+	for i, name := range rows[0] {
+		if name = strings.TrimSpace(name); name != "" && i != key && i != comment {
+			langs[strings.Replace(name, "-", "_", -1)] = i
+		}
+	}
+	return langs
+}
+
+func tableCell(row []string, index int) string {
+	if index < 0 || index >= len(row) {
+		return ""
+	}
+	return row[index]
+}
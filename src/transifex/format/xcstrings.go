@@ -0,0 +1,283 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Xcode string catalogs (.xcstrings): one json file with the strings of all languages.  Use it with the
+// SINGLE-FILE structure, the languages of the file are read from the catalog.
+//
+// The translations are uploaded from and downloaded into the same file, everything but the localizations of the
+// downloaded language (comments, extractionState, the source strings ...) is kept as it is.
+// Plural variations are uploaded as plural strings, device variations are keyed by key[device] and
+// the substitutions of a string by key:substitution.  Strings marked shouldTranslate false are not uploaded
+type XcStrings struct{}
+
+func (f *XcStrings) Init(initParams map[string]interface{}) {}
+func (f *XcStrings) Ext() string                            { return "xcstrings" }
+
+func (f *XcStrings) Languages(content []byte) ([]string, error) {
+	doc, err := parseXcStrings(content)
+	if err != nil {
+		return nil, err
+	}
+	found := map[string]bool{doc.get("sourceLanguage").str(): true}
+	for _, def := range jsonMembers(doc.get("strings")) {
+		for _, loc := range jsonMembers(def.value.get("localizations")) {
+			found[loc.key] = true
+		}
+	}
+	langs := []string{}
+	for lang := range found {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs, nil
+}
+
+// The strings of the source language of the catalog
+func (f *XcStrings) Parse(content []byte) (*Catalog, error) {
+	doc, err := parseXcStrings(content)
+	if err != nil {
+		return nil, err
+	}
+	return parseXcStringsLang(doc, doc.get("sourceLanguage").str()), nil
+}
+
+func (f *XcStrings) Clean(content []byte) ([]byte, string, error) {
+	return cleanToStructuredJson(f, content)
+}
+
+// Clean the strings of one language.  The strings without a translation are left out of the translations
+func (f *XcStrings) CleanLang(content []byte, lang string) ([]byte, string, error) {
+	doc, err := parseXcStrings(content)
+	if err != nil {
+		return nil, "", err
+	}
+	c := parseXcStringsLang(doc, lang)
+	if lang != doc.get("sourceLanguage").str() {
+		translated := NewCatalog(lang)
+		for _, e := range c.Entries {
+			if e.Value != "" || hasPluralTranslation(e) {
+				translated.Add(e)
+			}
+		}
+		c = translated
+	}
+	cleaned, err := EncodeStructuredJson(c)
+	if err != nil {
+		return nil, "", err
+	}
+	return cleaned, "STRUCTURED_JSON", nil
+}
+
+// Merge the strings of the catalog into the template (the current content of the string catalog)
+// as the localizations of the language of the catalog
+func (f *XcStrings) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	doc, err := parseXcStrings(template)
+	if err != nil {
+		return nil, err
+	}
+	source := doc.get("sourceLanguage").str()
+	for _, def := range jsonMembers(doc.get("strings")) {
+		if def.value.get("shouldTranslate").isFalse() {
+			continue
+		}
+		locs := def.value.get("localizations")
+		loc := buildXcLocalization(c, def.key, xcSourceShape(def.value, source))
+		if loc == nil {
+			if locs != nil {
+				locs.remove(c.Lang)
+			}
+			continue
+		}
+		if locs == nil {
+			locs = newJsonObject()
+			def.value.setSorted("localizations", locs)
+		}
+		locs.setSorted(c.Lang, loc)
+	}
+
+	var out bytes.Buffer
+	doc.write(&out, jsonIndent(template), "", jsonColon(template))
+	out.WriteString("\n")
+	return out.Bytes(), nil
+}
+
+// The translation is merged into the string catalog.  The source language is not written,
+// its strings are maintained by Xcode
+func (f *XcStrings) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	if langCode == srcLang {
+		return nil
+	}
+	path := fileLocator.Find(rootDir, langCode, filename, f.Ext())
+	fmt.Println("Updating translations file: " + path)
+
+	translations, err := DecodeTranslation([]byte(translation), langCode)
+	if err != nil {
+		return err
+	}
+	template, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	source, err := f.Parse(template)
+	if err != nil {
+		return err
+	}
+	translated, unknown := source.Translate(translations)
+	if len(unknown) > 0 {
+		return fmt.Errorf("One or more translations did not have a matching key in the source file: [%s]", strings.Join(unknown, ", "))
+	}
+
+	out, err := f.Serialize(translated, template)
+	if err != nil {
+		return err
+	}
+	return writeTranslationFile(path, out)
+}
+
+func parseXcStrings(content []byte) (*jsonValue, error) {
+	doc, err := parseJsonValue(content)
+	if err != nil {
+		return nil, err
+	}
+	if doc.kind != '{' || doc.get("sourceLanguage").str() == "" {
+		return nil, fmt.Errorf("Not a string catalog: the sourceLanguage is missing")
+	}
+	return doc, nil
+}
+
+func parseXcStringsLang(doc *jsonValue, lang string) *Catalog {
+	c := NewCatalog(lang)
+	source := doc.get("sourceLanguage").str()
+	for _, def := range jsonMembers(doc.get("strings")) {
+		if def.value.get("shouldTranslate").isFalse() {
+			continue
+		}
+		comment := def.value.get("comment").str()
+		loc := def.value.get("localizations").get(lang)
+		if lang == source && loc == nil {
+			// the key is the source string
+			c.Add(&Entry{Key: def.key, Value: def.key, Comment: comment})
+			continue
+		}
+		addXcLocalization(c, def.key, comment, xcSourceShape(def.value, source), loc)
+	}
+	return c
+}
+
+// The localization of the source language, which gives the shape of the localizations of all languages.
+// A string without a source localization has the key as its source string
+func xcSourceShape(def *jsonValue, source string) *jsonValue {
+	if shape := def.get("localizations").get(source); shape != nil {
+		return shape
+	}
+	shape := newJsonObject()
+	shape.set("stringUnit", xcStringUnit(""))
+	return shape
+}
+
+// add the entries of the localization of a string, loc may be nil if the string is not translated
+func addXcLocalization(c *Catalog, key, comment string, shape, loc *jsonValue) {
+	if shape.get("stringUnit") != nil {
+		c.Add(&Entry{Key: key, Value: loc.get("stringUnit").get("value").str(), Comment: comment})
+	}
+	for _, s := range jsonMembers(shape.get("substitutions")) {
+		addXcLocalization(c, key+":"+s.key, comment, s.value, loc.get("substitutions").get(s.key))
+	}
+	variations := shape.get("variations")
+	if plural := variations.get("plural"); plural != nil {
+		plurals := map[string]string{}
+		for _, form := range append(jsonMembers(plural), jsonMembers(loc.get("variations").get("plural"))...) {
+			plurals[form.key] = loc.get("variations").get("plural").get(form.key).get("stringUnit").get("value").str()
+		}
+		c.Add(&Entry{Key: key, Plurals: plurals, Comment: comment})
+	}
+	for _, device := range jsonMembers(variations.get("device")) {
+		addXcLocalization(c, key+"["+device.key+"]", comment, device.value, loc.get("variations").get("device").get(device.key))
+	}
+}
+
+// Build the localization of a string with the shape of the source localization.  Returns nil if the string is not translated
+func buildXcLocalization(c *Catalog, key string, shape *jsonValue) *jsonValue {
+	loc := newJsonObject()
+	// substitutions keep their argNum and formatSpecifier
+	for _, m := range shape.members {
+		if m.key != "stringUnit" && m.key != "substitutions" && m.key != "variations" {
+			loc.set(m.key, m.value)
+		}
+	}
+
+	if shape.get("stringUnit") != nil {
+		e := c.Find(key)
+		if e == nil || e.Value == "" {
+			return nil
+		}
+		loc.set("stringUnit", xcStringUnit(e.Value))
+	}
+
+	if substitutions := shape.get("substitutions"); substitutions != nil {
+		translated := newJsonObject()
+		for _, s := range substitutions.members {
+			sub := buildXcLocalization(c, key+":"+s.key, s.value)
+			if sub == nil {
+				return nil
+			}
+			translated.set(s.key, sub)
+		}
+		loc.set("substitutions", translated)
+	}
+
+	if variations := shape.get("variations"); variations != nil {
+		translated := newJsonObject()
+		if variations.get("plural") != nil {
+			e := c.Find(key)
+			if e == nil || !hasPluralTranslation(e) {
+				return nil
+			}
+			plural := newJsonObject()
+			for _, category := range e.PluralForms() {
+				if e.Plurals[category] != "" {
+					form := newJsonObject()
+					form.set("stringUnit", xcStringUnit(e.Plurals[category]))
+					plural.set(category, form)
+				}
+			}
+			translated.set("plural", plural)
+		}
+		if devices := variations.get("device"); devices != nil {
+			device := newJsonObject()
+			for _, d := range devices.members {
+				if v := buildXcLocalization(c, key+"["+d.key+"]", d.value); v != nil {
+					device.set(d.key, v)
+				}
+			}
+			if len(device.members) == 0 {
+				return nil
+			}
+			translated.set("device", device)
+		}
+		loc.set("variations", translated)
+	}
+	return loc
+}
+
+func xcStringUnit(value string) *jsonValue {
+	unit := newJsonObject()
+	unit.set("state", newJsonString("translated"))
+	unit.set("value", newJsonString(value))
+	return unit
+}
+
+// the members of an object, nil for other values
+func jsonMembers(v *jsonValue) []jsonMember {
+	if v == nil {
+		return nil
+	}
+	return v.members
+}
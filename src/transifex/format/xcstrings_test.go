@@ -0,0 +1,174 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	tu "testutil"
+)
+
+const xcStringsSource = `{
+  "sourceLanguage" : "en",
+  "strings" : {
+    "%lld files" : {
+      "comment" : "The number of files",
+      "extractionState" : "manual",
+      "localizations" : {
+        "en" : {
+          "variations" : {
+            "plural" : {
+              "one" : {
+                "stringUnit" : {
+                  "state" : "translated",
+                  "value" : "%lld file"
+                }
+              },
+              "other" : {
+                "stringUnit" : {
+                  "state" : "translated",
+                  "value" : "%lld files"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "Brand" : {
+      "shouldTranslate" : false
+    },
+    "Hello" : {
+      "comment" : "The greeting",
+      "localizations" : {
+        "de" : {
+          "stringUnit" : {
+            "state" : "translated",
+            "value" : "Hallo"
+          }
+        }
+      }
+    },
+    "tap" : {
+      "extractionState" : "manual",
+      "localizations" : {
+        "en" : {
+          "variations" : {
+            "device" : {
+              "iphone" : {
+                "stringUnit" : {
+                  "state" : "translated",
+                  "value" : "Tap"
+                }
+              },
+              "mac" : {
+                "stringUnit" : {
+                  "state" : "translated",
+                  "value" : "Click"
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  },
+  "version" : "1.0"
+}
+`
+
+func Test_XcStrings_Parse(t *testing.T) {
+	f := new(XcStrings)
+	langs, err := f.Languages([]byte(xcStringsSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("languages", "[de en]", fmt.Sprint(langs), t)
+
+	c, err := f.Parse([]byte(xcStringsSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("entries", 4, len(c.Entries), t)
+	tu.AssertEquals("plural", "%lld file", c.Find("%lld files").Plurals["one"], t)
+	tu.AssertEquals("comment", "The number of files", c.Find("%lld files").Comment, t)
+	tu.AssertEquals("key is the source", "Hello", c.Find("Hello").Value, t)
+	tu.AssertEquals("device", "Click", c.Find("tap[mac]").Value, t)
+	if c.Find("Brand") != nil {
+		t.Errorf("Strings that should not be translated must be skipped")
+	}
+
+	cleaned, _, err := f.CleanLang([]byte(xcStringsSource), "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data map[string]map[string]string
+	json.Unmarshal(cleaned, &data)
+	tu.AssertEqualsInt("translated", 1, len(data), t)
+	tu.AssertEquals("translation", "Hallo", data["Hello"]["string"], t)
+}
+
+func Test_XcStrings_Write(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	path := filepath.Join(tmpDir, "Localizable.xcstrings")
+	ioutil.WriteFile(path, []byte(xcStringsSource), 0644)
+
+	f := new(XcStrings)
+	locator := FileLocators["SINGLE-FILE"]
+	translations := map[string]map[string]string{
+		"fr": {"%lld files": "{cnt, plural, one {%lld fichier} other {%lld fichiers}}", "Hello": "Bonjour", "tap[iphone]": "Touchez"},
+		// the translation of de was removed in transifex
+		"de": {"tap[mac]": "Klicken"}}
+	for _, lang := range []string{"fr", "de"} {
+		data, _ := json.Marshal(translations[lang])
+		if err := f.Write(tmpDir, lang, "en", "Localizable", string(data), locator); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	written := mustRead(path)
+	doc, err := parseJsonValue(written)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := doc.get("strings").get("%lld files")
+	tu.AssertEquals("extraction state", "manual", files.get("extractionState").str(), t)
+	tu.AssertEquals("comment", "The number of files", files.get("comment").str(), t)
+	tu.AssertEquals("plural", "%lld fichiers", files.get("localizations").get("fr").get("variations").get("plural").get("other").get("stringUnit").get("value").str(), t)
+	tu.AssertEquals("source", "%lld files", files.get("localizations").get("en").get("variations").get("plural").get("other").get("stringUnit").get("value").str(), t)
+
+	hello := doc.get("strings").get("Hello").get("localizations")
+	tu.AssertEquals("sorted languages", "fr", hello.members[0].key, t)
+	tu.AssertEqualsInt("removed translation", 1, len(hello.members), t)
+
+	tap := doc.get("strings").get("tap").get("localizations")
+	tu.AssertEquals("device", "Touchez", tap.get("fr").get("variations").get("device").get("iphone").get("stringUnit").get("value").str(), t)
+	if tap.get("fr").get("variations").get("device").get("mac") != nil {
+		t.Errorf("Untranslated devices must not be written")
+	}
+	tu.AssertEquals("languages", "de,en,fr", tap.members[0].key+","+tap.members[1].key+","+tap.members[2].key, t)
+	if doc.get("strings").get("Brand").get("localizations") != nil {
+		t.Errorf("Strings that should not be translated must not be written")
+	}
+
+	// the layout of Xcode is kept
+	expected := `    "Brand" : {
+      "shouldTranslate" : false
+    },
+    "Hello" : {
+      "comment" : "The greeting",
+      "localizations" : {
+        "fr" : {
+          "stringUnit" : {
+            "state" : "translated",
+            "value" : "Bonjour"
+          }
+        }
+      }
+    },`
+	if !strings.Contains(string(written), expected) {
+		t.Errorf("Expected the layout of the file to be kept:\n%s", written)
+	}
+}
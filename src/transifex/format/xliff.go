@@ -0,0 +1,303 @@
+package format
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// XLIFF 1.2 and 2.0 files.  The strings are keyed by the resname (1.2) or name (2.0) of the units, or their id.
+// A file with a target language is a translation: its targets are uploaded and the units without a
+// target are left out.  Otherwise the sources of the file are uploaded.
+//
+// Translations are written as bilingual files with the sources of the source file, plural strings are written as
+// ICU plural messages.  EncodeBilingual and DecodeXliff are used by the export-xliff and import-xliff commands
+// to exchange the strings of the other formats with translation vendors
+type Xliff struct {
+	version string
+}
+
+const (
+	xliff12 = "1.2"
+	xliff20 = "2.0"
+)
+
+func (f *Xliff) Init(initParams map[string]interface{}) {}
+func (f *Xliff) Ext() string                            { return "xlf" }
+
+func (f *Xliff) Parse(content []byte) (*Catalog, error) {
+	_, source, target, err := DecodeXliff(content)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return source, nil
+	}
+	translated := NewCatalog(target.Lang)
+	for _, e := range target.Entries {
+		if e.Value != "" || hasPluralTranslation(e) {
+			translated.Add(e)
+		}
+	}
+	return translated, nil
+}
+
+func (f *Xliff) Clean(content []byte) ([]byte, string, error) {
+	return cleanToStructuredJson(f, content)
+}
+
+// The catalog is written as the translation of the sources of the template.  Without a template the
+// strings of the catalog are written as the sources
+func (f *Xliff) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	if template == nil {
+		return f.encode("", c, nil), nil
+	}
+	original, source, _, err := DecodeXliff(template)
+	if err != nil {
+		return nil, err
+	}
+	return f.EncodeBilingual(original, source, c), nil
+}
+
+func (f *Xliff) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	return writeCatalog(f, rootDir, langCode, srcLang, filename, translation, fileLocator)
+}
+
+// Encode the strings of the source catalog with their translations in the target catalog.
+// original is the name of the file of the strings
+func (f *Xliff) EncodeBilingual(original string, source, target *Catalog) []byte {
+	return f.encode(original, source, target)
+}
+
+type xliffUnit struct {
+	source, target string
+	translated     bool
+	e              *Entry
+}
+
+func (f *Xliff) encode(original string, source, target *Catalog) []byte {
+	units := []xliffUnit{}
+	for _, e := range source.Entries {
+		unit := xliffUnit{source: e.FlatValue(), e: e}
+		if target != nil {
+			if t := target.Find(e.ID()); t != nil && (t.Value != "" || hasPluralTranslation(t)) {
+				unit.target, unit.translated = t.FlatValue(), true
+			}
+		}
+		units = append(units, unit)
+	}
+
+	var out bytes.Buffer
+	out.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	srcLang := xliffLang(source.Lang)
+	if f.version == xliff20 {
+		fmt.Fprintf(&out, `<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="2.0" srcLang="%s"`, xmlAttrEscaper.Replace(srcLang))
+		if target != nil {
+			fmt.Fprintf(&out, ` trgLang="%s"`, xmlAttrEscaper.Replace(xliffLang(target.Lang)))
+		}
+		fmt.Fprintf(&out, ">\n  <file id=\"f1\" original=\"%s\">\n", xmlAttrEscaper.Replace(original))
+		for i, unit := range units {
+			writeXliff20Unit(&out, i+1, unit)
+		}
+		out.WriteString("  </file>\n</xliff>\n")
+		return out.Bytes()
+	}
+
+	out.WriteString(`<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">` + "\n")
+	fmt.Fprintf(&out, `  <file original="%s" source-language="%s"`, xmlAttrEscaper.Replace(original), xmlAttrEscaper.Replace(srcLang))
+	if target != nil {
+		fmt.Fprintf(&out, ` target-language="%s"`, xmlAttrEscaper.Replace(xliffLang(target.Lang)))
+	}
+	out.WriteString(" datatype=\"plaintext\">\n    <body>\n")
+	for i, unit := range units {
+		writeXliff12Unit(&out, i+1, unit)
+	}
+	out.WriteString("    </body>\n  </file>\n</xliff>\n")
+	return out.Bytes()
+}
+
+func writeXliff12Unit(out *bytes.Buffer, id int, unit xliffUnit) {
+	fmt.Fprintf(out, "      <trans-unit id=\"%d\" resname=\"%s\" xml:space=\"preserve\">\n", id, xmlAttrEscaper.Replace(unit.e.Key))
+	fmt.Fprintf(out, "        <source>%s</source>\n", xmlTextEscaper.Replace(unit.source))
+	if unit.translated {
+		fmt.Fprintf(out, "        <target state=\"translated\">%s</target>\n", xmlTextEscaper.Replace(unit.target))
+	}
+	if unit.e.Context != "" {
+		fmt.Fprintf(out, "        <context-group purpose=\"information\">\n          <context context-type=\"x-context\">%s</context>\n        </context-group>\n",
+			xmlTextEscaper.Replace(unit.e.Context))
+	}
+	if unit.e.Comment != "" {
+		fmt.Fprintf(out, "        <note>%s</note>\n", xmlTextEscaper.Replace(unit.e.Comment))
+	}
+	out.WriteString("      </trans-unit>\n")
+}
+
+func writeXliff20Unit(out *bytes.Buffer, id int, unit xliffUnit) {
+	fmt.Fprintf(out, "    <unit id=\"u%d\" name=\"%s\">\n", id, xmlAttrEscaper.Replace(unit.e.Key))
+	if unit.e.Context != "" || unit.e.Comment != "" {
+		out.WriteString("      <notes>\n")
+		if unit.e.Context != "" {
+			fmt.Fprintf(out, "        <note category=\"context\">%s</note>\n", xmlTextEscaper.Replace(unit.e.Context))
+		}
+		if unit.e.Comment != "" {
+			fmt.Fprintf(out, "        <note>%s</note>\n", xmlTextEscaper.Replace(unit.e.Comment))
+		}
+		out.WriteString("      </notes>\n")
+	}
+	if unit.translated {
+		out.WriteString("      <segment state=\"translated\">\n")
+	} else {
+		out.WriteString("      <segment>\n")
+	}
+	fmt.Fprintf(out, "        <source xml:space=\"preserve\">%s</source>\n", xmlTextEscaper.Replace(unit.source))
+	if unit.translated {
+		fmt.Fprintf(out, "        <target xml:space=\"preserve\">%s</target>\n", xmlTextEscaper.Replace(unit.target))
+	}
+	out.WriteString("      </segment>\n    </unit>\n")
+}
+
+// Decode an XLIFF 1.2 or 2.0 file.  Returns the original name of the (first) file of the XLIFF, the catalog of the sources
+// and the catalog of the targets, nil if the file has no target language.  The units without a target have an empty target value.
+// The inline elements of the sources and targets are removed, only their text is kept
+func DecodeXliff(content []byte) (original string, source, target *Catalog, err error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	var srcLang, trgLang string
+	var units []*Entry
+	var targets []*Entry
+	var unit, unitTarget *Entry
+	// the state of the current 2.0 segment
+	segmentState := ""
+	files := 0
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("Not valid xliff: %s", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "xliff":
+				srcLang, trgLang = xmlAttr(t, "srcLang"), xmlAttr(t, "trgLang")
+			case "file":
+				if files++; files == 1 {
+					original = xmlAttr(t, "original")
+					if lang := xmlAttr(t, "source-language"); lang != "" {
+						srcLang = lang
+					}
+					if lang := xmlAttr(t, "target-language"); lang != "" {
+						trgLang = lang
+					}
+				}
+			case "trans-unit", "unit":
+				key := xmlAttr(t, "resname")
+				if key == "" {
+					key = xmlAttr(t, "name")
+				}
+				if key == "" {
+					key = xmlAttr(t, "id")
+				}
+				unit, unitTarget = &Entry{Key: key}, &Entry{Key: key}
+				units, targets = append(units, unit), append(targets, unitTarget)
+			case "segment":
+				segmentState = xmlAttr(t, "state")
+			case "source", "target", "note", "context":
+				if unit == nil {
+					decoder.Skip()
+					continue
+				}
+				text, err := readXliffText(decoder)
+				if err != nil {
+					return "", nil, nil, fmt.Errorf("Not valid xliff: %s", err)
+				}
+				switch {
+				case t.Name.Local == "source":
+					unit.Value += text
+				case t.Name.Local == "target":
+					state := xmlAttr(t, "state")
+					if state != "new" && state != "needs-translation" && segmentState != "initial" {
+						unitTarget.Value += text
+					}
+				case t.Name.Local == "note" && xmlAttr(t, "category") == "context",
+					t.Name.Local == "context" && xmlAttr(t, "context-type") == "x-context":
+					unit.Context = text
+				case t.Name.Local == "note":
+					if unit.Comment != "" {
+						unit.Comment += "\n"
+					}
+					unit.Comment += text
+				}
+			case "alt-trans", "seg-source", "originalData", "bin-unit":
+				// alternative translations, duplicated sources and the data of placeholders are not strings of the file
+				decoder.Skip()
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "trans-unit", "unit":
+				unit, unitTarget = nil, nil
+			case "segment":
+				segmentState = ""
+			}
+		}
+	}
+	if files == 0 {
+		return "", nil, nil, fmt.Errorf("Not valid xliff: the file has no file element")
+	}
+
+	source = NewCatalog(transifexLang(srcLang))
+	for _, e := range units {
+		source.Add(xliffPlural(e))
+	}
+	if trgLang != "" {
+		target = NewCatalog(transifexLang(trgLang))
+		for i, e := range targets {
+			e.Context, e.Comment = units[i].Context, units[i].Comment
+			target.Add(xliffPlural(e))
+		}
+	}
+	return original, source, target, nil
+}
+
+// the text of the element, the tags of inline elements are removed
+func readXliffText(decoder *xml.Decoder) (string, error) {
+	var text strings.Builder
+	for depth := 1; depth > 0; {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			text.Write(t)
+		}
+	}
+	return text.String(), nil
+}
+
+// an ICU plural message is decoded into the plural forms of the entry
+func xliffPlural(e *Entry) *Entry {
+	if plurals, ok := decodeICUPlural(e.Value); ok {
+		e.Plurals, e.Value = plurals, ""
+	}
+	return e
+}
+
+// XLIFF uses BCP 47 language tags (pt-BR), transifex pt_BR
+func xliffLang(lang string) string {
+	return strings.Replace(lang, "_", "-", -1)
+}
+
+func transifexLang(lang string) string {
+	return strings.Replace(lang, "-", "_", -1)
+}
+
+var xmlAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "\n", "&#xA;", "\t", "&#x9;")
@@ -0,0 +1,160 @@
+package format
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	tu "testutil"
+)
+
+const xliff12Source = `<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">
+  <file original="app/core" source-language="en" datatype="plaintext">
+    <header><note>Not a string</note></header>
+    <body>
+      <trans-unit id="1" resname="title">
+        <source>Welcome &amp; hello</source>
+        <note>The title of the page</note>
+      </trans-unit>
+      <trans-unit id="greeting">
+        <source>Hello <g id="1">%s</g>!</source>
+        <alt-trans><target>Salut</target></alt-trans>
+      </trans-unit>
+      <trans-unit id="3" resname="files">
+        <source>{cnt, plural, one {%d file} other {%d files}}</source>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>
+`
+
+const xliff20Translation = `<?xml version="1.0" encoding="UTF-8"?>
+<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="2.0" srcLang="en" trgLang="pt-BR">
+  <file id="f1" original="app/core">
+    <unit id="u1" name="title">
+      <notes><note category="context">menu</note></notes>
+      <segment state="translated">
+        <source>Welcome</source>
+        <target>Bem-vindo</target>
+      </segment>
+      <ignorable><source> </source><target> </target></ignorable>
+      <segment state="translated">
+        <source>home</source>
+        <target>casa</target>
+      </segment>
+    </unit>
+    <unit id="u2" name="greeting">
+      <segment state="initial">
+        <source>Hello</source>
+        <target>Hello</target>
+      </segment>
+    </unit>
+  </file>
+</xliff>
+`
+
+func Test_Xliff_Parse(t *testing.T) {
+	c, err := new(Xliff).Parse([]byte(xliff12Source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("entries", 3, len(c.Entries), t)
+	tu.AssertEquals("resname", "Welcome & hello", c.Find("title").Value, t)
+	tu.AssertEquals("note", "The title of the page", c.Find("title").Comment, t)
+	tu.AssertEquals("id and inline elements", "Hello %s!", c.Find("greeting").Value, t)
+	tu.AssertEquals("plural", "%d files", c.Find("files").Plurals["other"], t)
+
+	c, err = new(Xliff).Parse([]byte(xliff20Translation))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("lang", "pt_BR", c.Lang, t)
+	tu.AssertEqualsInt("translated entries", 1, len(c.Entries), t)
+	tu.AssertEquals("segments", "Bem-vindo casa", c.Find("menu"+contextSeparator+"title").Value, t)
+
+	if _, err = new(Xliff).Parse([]byte(`<xliff version="1.2"></xliff>`)); err == nil {
+		t.Errorf("Expected an error for an xliff without a file")
+	}
+}
+
+func Test_Xliff_Write(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	ioutil.WriteFile(filepath.Join(tmpDir, "en-core.xlf"), []byte(xliff12Source), 0644)
+
+	data, _ := json.Marshal(map[string]string{
+		"title": "Bienvenue & bonjour",
+		"files": "{cnt, plural, one {%d fichier} other {%d fichiers}}"})
+
+	f := Formats["XLIFF12"]()
+	if err := f.Write(tmpDir, "fr", "en", "core", string(data), FileLocators["LANG-NAME"]); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">
+  <file original="app/core" source-language="en" target-language="fr" datatype="plaintext">
+    <body>
+      <trans-unit id="1" resname="title" xml:space="preserve">
+        <source>Welcome &amp; hello</source>
+        <target state="translated">Bienvenue &amp; bonjour</target>
+        <note>The title of the page</note>
+      </trans-unit>
+      <trans-unit id="2" resname="greeting" xml:space="preserve">
+        <source>Hello %s!</source>
+      </trans-unit>
+      <trans-unit id="3" resname="files" xml:space="preserve">
+        <source>{cnt, plural, one {%d file} other {%d files}}</source>
+        <target state="translated">{cnt, plural, one {%d fichier} other {%d fichiers}}</target>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>
+`
+	tu.AssertEquals("", expected, string(mustRead(filepath.Join(tmpDir, "fr-core.xlf"))), t)
+}
+
+func Test_Xliff_EncodeBilingual(t *testing.T) {
+	source := NewCatalog("en")
+	source.Add(&Entry{Key: "open", Value: "Open", Context: "menu", Comment: "A \"verb\""})
+	source.Add(&Entry{Key: "close", Value: "Close"})
+	target := NewCatalog("pt_BR")
+	target.Add(&Entry{Key: "open", Value: "Abrir", Context: "menu"})
+
+	out := Formats["XLIFF20"]().(*Xliff).EncodeBilingual("app/core", source, target)
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="2.0" srcLang="en" trgLang="pt-BR">
+  <file id="f1" original="app/core">
+    <unit id="u1" name="open">
+      <notes>
+        <note category="context">menu</note>
+        <note>A "verb"</note>
+      </notes>
+      <segment state="translated">
+        <source xml:space="preserve">Open</source>
+        <target xml:space="preserve">Abrir</target>
+      </segment>
+    </unit>
+    <unit id="u2" name="close">
+      <segment>
+        <source xml:space="preserve">Close</source>
+      </segment>
+    </unit>
+  </file>
+</xliff>
+`
+	tu.AssertEquals("", expected, string(out), t)
+
+	// the 1.2 file decodes to the same catalogs
+	original, decodedSource, decodedTarget, err := DecodeXliff(Formats["XLIFF12"]().(*Xliff).EncodeBilingual("app/core", source, target))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("original", "app/core", original, t)
+	tu.AssertEquals("source lang", "en", decodedSource.Lang, t)
+	tu.AssertEquals("context", "Open", decodedSource.Find("menu"+contextSeparator+"open").Value, t)
+	tu.AssertEquals("comment", `A "verb"`, decodedSource.Find("menu"+contextSeparator+"open").Comment, t)
+	tu.AssertEquals("target lang", "pt_BR", decodedTarget.Lang, t)
+	tu.AssertEquals("target", "Abrir", decodedTarget.Find("menu"+contextSeparator+"open").Value, t)
+	tu.AssertEquals("untranslated", "", decodedTarget.Find("close").Value, t)
+}
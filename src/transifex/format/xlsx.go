@@ -0,0 +1,596 @@
+package format
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Excel workbooks (.xlsx) with a key, a value and an optional comment column in one sheet (see tableColumns for
+// the extra parameters of the columns).  The strings of the value column are uploaded with the comment column as
+// their comment.
+//
+// Translations are written with the source workbook as the template: the value column is replaced by the translations,
+// empty for the strings without a translation.  The other sheets, cells and their styles are kept as they are.
+//
+// Extra parameters:
+// * sheet - the name of the sheet of the strings, the first sheet by default
+type Xlsx struct {
+	sheet   string
+	columns tableColumns
+}
+
+func (f *Xlsx) Init(initParams map[string]interface{}) {
+	if sheet, has := initParams["sheet"].(string); has {
+		f.sheet = sheet
+	}
+	f.columns.init(initParams)
+}
+
+func (f *Xlsx) Ext() string { return "xlsx" }
+
+func (f *Xlsx) Parse(content []byte) (*Catalog, error) {
+	book, err := readXlsx(content, f.sheet)
+	if err != nil {
+		return nil, err
+	}
+	return f.columns.catalog(book.rows, f.columns.value, false)
+}
+
+func (f *Xlsx) Clean(content []byte) ([]byte, string, error) {
+	return cleanToStructuredJson(f, content)
+}
+
+func (f *Xlsx) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	book, err := f.template(c, template, f.columns.value)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.columns.translate(book.rows, c, f.columns.value); err != nil {
+		return nil, err
+	}
+	return book.write()
+}
+
+func (f *Xlsx) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	return writeCatalog(f, rootDir, langCode, srcLang, filename, translation, fileLocator)
+}
+
+// the workbook of the template, or a new workbook with the strings of the catalog
+func (f *Xlsx) template(c *Catalog, template []byte, value interface{}) (*xlsxBook, error) {
+	if template != nil {
+		return readXlsx(template, f.sheet)
+	}
+	sheet := f.sheet
+	if sheet == "" {
+		sheet = "Sheet1"
+	}
+	return newXlsx(sheet, f.columns.newRows(c, value)), nil
+}
+
+// Excel workbooks with the strings of all languages in one sheet: a key column, an optional comment column and one
+// column per language named by the language code (fr, pt_BR ...) in the header row.  Use it with the SINGLE-FILE
+// structure.  The source language is the first language column unless the source extra parameter is set.
+//
+// Downloaded translations are written to the column of their language in the same workbook, a column is added
+// for a new language
+type XlsxLangs struct {
+	Xlsx
+	source string
+}
+
+func (f *XlsxLangs) Init(initParams map[string]interface{}) {
+	f.Xlsx.Init(initParams)
+	f.columns.header = true
+	if source, has := initParams["source"].(string); has {
+		f.source = source
+	}
+}
+
+func (f *XlsxLangs) Languages(content []byte) ([]string, error) {
+	book, err := readXlsx(content, f.sheet)
+	if err != nil {
+		return nil, err
+	}
+	langs := []string{}
+	for lang := range f.columns.languages(book.rows) {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs, nil
+}
+
+// The strings of the source language
+func (f *XlsxLangs) Parse(content []byte) (*Catalog, error) {
+	book, err := readXlsx(content, f.sheet)
+	if err != nil {
+		return nil, err
+	}
+	lang := f.sourceLang(book.rows)
+	c, err := f.columns.catalog(book.rows, f.langColumn(book.rows, lang), false)
+	if err != nil {
+		return nil, err
+	}
+	c.Lang = lang
+	return c, nil
+}
+
+func (f *XlsxLangs) Clean(content []byte) ([]byte, string, error) {
+	return cleanToStructuredJson(f, content)
+}
+
+// Clean the strings of one language.  The strings without a translation are left out of the translations
+func (f *XlsxLangs) CleanLang(content []byte, lang string) ([]byte, string, error) {
+	book, err := readXlsx(content, f.sheet)
+	if err != nil {
+		return nil, "", err
+	}
+	c, err := f.columns.catalog(book.rows, f.langColumn(book.rows, lang), lang != f.sourceLang(book.rows))
+	if err != nil {
+		return nil, "", err
+	}
+	cleaned, err := EncodeStructuredJson(c)
+	if err != nil {
+		return nil, "", err
+	}
+	return cleaned, "STRUCTURED_JSON", nil
+}
+
+func (f *XlsxLangs) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	book, err := f.template(c, template, c.Lang)
+	if err != nil {
+		return nil, err
+	}
+	if _, has := f.columns.languages(book.rows)[c.Lang]; !has && len(book.rows) > 0 {
+		book.rows[0] = append(book.rows[0], c.Lang)
+	}
+	if err := f.columns.translate(book.rows, c, f.langColumn(book.rows, c.Lang)); err != nil {
+		return nil, err
+	}
+	return book.write()
+}
+
+// The translations are written to the column of the language in the workbook
+func (f *XlsxLangs) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	if langCode == srcLang {
+		return nil
+	}
+	path := fileLocator.Find(rootDir, langCode, filename, f.Ext())
+	fmt.Println("Updating translations file: " + path)
+
+	translations, err := DecodeTranslation([]byte(translation), langCode)
+	if err != nil {
+		return err
+	}
+	template, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	source, err := f.Parse(template)
+	if err != nil {
+		return err
+	}
+	translated, unknown := source.Translate(translations)
+	if len(unknown) > 0 {
+		return fmt.Errorf("One or more translations did not have a matching key in the source file: [%s]", strings.Join(unknown, ", "))
+	}
+
+	out, err := f.Serialize(translated, template)
+	if err != nil {
+		return err
+	}
+	return writeTranslationFile(path, out)
+}
+
+func (f *XlsxLangs) sourceLang(rows [][]string) string {
+	if f.source != "" {
+		return f.source
+	}
+	langs := f.columns.languages(rows)
+	first := ""
+	for lang, i := range langs {
+		if first == "" || i < langs[first] {
+			first = lang
+		}
+	}
+	return first
+}
+
+// the column of the language, by number so that pt-BR is the column of pt_BR
+func (f *XlsxLangs) langColumn(rows [][]string, lang string) interface{} {
+	if i, has := f.columns.languages(rows)[lang]; has {
+		return i + 1
+	}
+	return lang
+}
+
+// A workbook and the cells of the sheet of the strings
+type xlsxBook struct {
+	// the files of the workbook, nil for a new workbook
+	files *zip.Reader
+	// the name of the sheet of a new workbook
+	sheetName string
+	sheetPath string
+	sheet     []byte
+	// the values of the cells, rows[0][0] is A1
+	rows [][]string
+	// the cells of the sheet by row and column number
+	cells map[[2]int]xlsxCell
+	// the attributes of the row elements by row number
+	rowAttrs map[int][]xml.Attr
+}
+
+type xlsxCell struct {
+	raw, value, style string
+}
+
+func readXlsx(content []byte, sheetName string) (*xlsxBook, error) {
+	files, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("Not a valid xlsx file: %s", err)
+	}
+	book := &xlsxBook{files: files, cells: map[[2]int]xlsxCell{}, rowAttrs: map[int][]xml.Attr{}}
+	if book.sheetPath, err = findXlsxSheet(files, sheetName); err != nil {
+		return nil, err
+	}
+	shared, err := readXlsxSharedStrings(files)
+	if err != nil {
+		return nil, err
+	}
+	if book.sheet, err = readZipFile(files, book.sheetPath); err != nil {
+		return nil, err
+	}
+	return book, book.parseSheet(shared)
+}
+
+// A workbook with one sheet
+func newXlsx(sheetName string, rows [][]string) *xlsxBook {
+	return &xlsxBook{
+		sheetName: sheetName,
+		sheetPath: "xl/worksheets/sheet1.xml",
+		sheet:     []byte(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/></worksheet>`),
+		rows:      rows,
+		cells:     map[[2]int]xlsxCell{},
+		rowAttrs:  map[int][]xml.Attr{},
+	}
+}
+
+// the path of the sheet in the zip file
+func findXlsxSheet(files *zip.Reader, sheetName string) (string, error) {
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := unmarshalZipFile(files, "xl/workbook.xml", &workbook); err != nil {
+		return "", err
+	}
+	if err := unmarshalZipFile(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+
+	for _, sheet := range workbook.Sheets {
+		if sheetName != "" && sheet.Name != sheetName {
+			continue
+		}
+		for _, rel := range rels.Relationships {
+			if rel.ID != sheet.ID {
+				continue
+			}
+			if strings.HasPrefix(rel.Target, "/") {
+				return rel.Target[1:], nil
+			}
+			return "xl/" + rel.Target, nil
+		}
+	}
+	return "", fmt.Errorf("The xlsx file has no sheet %q", sheetName)
+}
+
+func readXlsxSharedStrings(files *zip.Reader) ([]string, error) {
+	var sst struct {
+		Items []struct {
+			Text string `xml:"t"`
+			Runs []struct {
+				Text string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"si"`
+	}
+	for _, f := range files.File {
+		if f.Name == "xl/sharedStrings.xml" {
+			if err := unmarshalZipFile(files, f.Name, &sst); err != nil {
+				return nil, err
+			}
+		}
+	}
+	shared := make([]string, len(sst.Items))
+	for i, item := range sst.Items {
+		shared[i] = item.Text
+		for _, run := range item.Runs {
+			shared[i] += run.Text
+		}
+	}
+	return shared, nil
+}
+
+// Read the rows and cells of the sheet.  Raw tokens keep the namespace prefixes of the attributes
+func (b *xlsxBook) parseSheet(shared []string) error {
+	decoder := xml.NewDecoder(bytes.NewReader(b.sheet))
+	row, col := 0, 0
+	for {
+		before := decoder.InputOffset()
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		t, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch t.Name.Local {
+		case "row":
+			if r, err := strconv.Atoi(xmlAttr(t, "r")); err == nil {
+				row = r
+			} else {
+				row++
+			}
+			col = 0
+			b.rowAttrs[row] = t.Attr
+			b.set(row, 0, "")
+		case "c":
+			if ref := xmlAttr(t, "r"); ref != "" {
+				col = xlsxColumn(ref)
+			} else {
+				col++
+			}
+			cell, err := readXlsxCell(decoder, t, shared)
+			if err != nil {
+				return err
+			}
+			cell.raw = string(b.sheet[before:decoder.InputOffset()])
+			b.cells[[2]int{row, col}] = cell
+			b.set(row, col, cell.value)
+		}
+	}
+}
+
+func readXlsxCell(decoder *xml.Decoder, start xml.StartElement, shared []string) (xlsxCell, error) {
+	cell := xlsxCell{style: xmlAttr(start, "s")}
+	var value, inline bytes.Buffer
+	text, phonetic := "", false
+	for {
+		token, err := decoder.RawToken()
+		if err != nil {
+			return cell, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			text = t.Name.Local
+			phonetic = phonetic || text == "rPh"
+		case xml.CharData:
+			switch {
+			case text == "v":
+				value.Write(t)
+			case text == "t" && !phonetic:
+				inline.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "c":
+				switch xmlAttr(start, "t") {
+				case "s":
+					if i, err := strconv.Atoi(value.String()); err == nil && i >= 0 && i < len(shared) {
+						cell.value = shared[i]
+					}
+				case "inlineStr":
+					cell.value = inline.String()
+				default:
+					cell.value = value.String()
+				}
+				return cell, nil
+			case "rPh":
+				phonetic = false
+			}
+			text = ""
+		}
+	}
+}
+
+// Set the value of the cell, the row and column numbers start at 1.  Column 0 only adds the row
+func (b *xlsxBook) set(row, col int, value string) {
+	for len(b.rows) < row {
+		b.rows = append(b.rows, []string{})
+	}
+	for len(b.rows[row-1]) < col {
+		b.rows[row-1] = append(b.rows[row-1], "")
+	}
+	if col > 0 {
+		b.rows[row-1][col-1] = value
+	}
+}
+
+var xlsxSheetData = regexp.MustCompile(`(?s)<sheetData\s*/>|<sheetData>.*</sheetData>`)
+var xlsxDimension = regexp.MustCompile(`<dimension ref="[^"]*"\s*/>`)
+
+// The workbook with the sheet updated with the rows.  The cells that were not changed are written as they are,
+// changed cells are written as inline strings with their style
+func (b *xlsxBook) write() ([]byte, error) {
+	var data bytes.Buffer
+	data.WriteString("<sheetData>")
+	width := 0
+	for i, values := range b.rows {
+		row := i + 1
+		var cells bytes.Buffer
+		for j, value := range values {
+			cells.WriteString(b.cellXml(row, j+1, value))
+		}
+		attrs, hasAttrs := b.rowAttrs[row]
+		if !hasAttrs && cells.Len() == 0 {
+			continue
+		}
+		if len(values) > width {
+			width = len(values)
+		}
+		fmt.Fprintf(&data, `<row r="%d"`, row)
+		for _, attr := range attrs {
+			if name := attr.Name.Local; attr.Name.Space == "" && (name == "r" || name == "spans") {
+				continue
+			}
+			fmt.Fprintf(&data, ` %s="%s"`, xmlAttrName(attr.Name), xmlAttrEscaper.Replace(attr.Value))
+		}
+		data.WriteString(">")
+		data.Write(cells.Bytes())
+		data.WriteString("</row>")
+	}
+	data.WriteString("</sheetData>")
+
+	if !xlsxSheetData.Match(b.sheet) {
+		return nil, fmt.Errorf("Unsupported xlsx sheet %s: no sheetData", b.sheetPath)
+	}
+	sheet := xlsxSheetData.ReplaceAllLiteral(b.sheet, data.Bytes())
+	if width > 0 {
+		sheet = xlsxDimension.ReplaceAllLiteral(sheet, []byte(fmt.Sprintf(`<dimension ref="A1:%s"/>`, xlsxRef(width, len(b.rows)))))
+	}
+
+	var out bytes.Buffer
+	writer := zip.NewWriter(&out)
+	writeFile := func(name string, content []byte) error {
+		w, err := writer.Create(name)
+		if err == nil {
+			_, err = w.Write(content)
+		}
+		return err
+	}
+	if b.files == nil {
+		for _, f := range xlsxNewFiles(b.sheetName) {
+			if err := writeFile(f[0], []byte(xml.Header+f[1])); err != nil {
+				return nil, err
+			}
+		}
+		if err := writeFile(b.sheetPath, sheet); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, f := range b.files.File {
+			content := sheet
+			if f.Name != b.sheetPath {
+				var err error
+				if content, err = readZipFile(b.files, f.Name); err != nil {
+					return nil, err
+				}
+			}
+			if err := writeFile(f.Name, content); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func (b *xlsxBook) cellXml(row, col int, value string) string {
+	cell, has := b.cells[[2]int{row, col}]
+	if has && cell.value == value {
+		return cell.raw
+	}
+	attrs := fmt.Sprintf(`r="%s"`, xlsxRef(col, row))
+	if cell.style != "" {
+		attrs += fmt.Sprintf(` s="%s"`, cell.style)
+	}
+	if value == "" {
+		if cell.style == "" {
+			return ""
+		}
+		return "<c " + attrs + "/>"
+	}
+	return "<c " + attrs + ` t="inlineStr"><is><t xml:space="preserve">` + xmlTextEscaper.Replace(value) + "</t></is></c>"
+}
+
+// the parts of a new workbook besides the sheet
+func xlsxNewFiles(sheetName string) [][2]string {
+	return [][2]string{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="` + xmlAttrEscaper.Replace(sheetName) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`</Relationships>`}}
+}
+
+// the column number of a cell reference: 1 for A1, 28 for AB3
+func xlsxColumn(ref string) int {
+	col := 0
+	for _, c := range strings.ToUpper(ref) {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = col*26 + int(c-'A'+1)
+	}
+	return col
+}
+
+// the reference of the cell: A1, AB3
+func xlsxRef(col, row int) string {
+	letters := ""
+	for ; col > 0; col = (col - 1) / 26 {
+		letters = string(rune('A'+(col-1)%26)) + letters
+	}
+	return letters + strconv.Itoa(row)
+}
+
+// the name of an attribute read with RawToken, where the space is the prefix
+func xmlAttrName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+func readZipFile(files *zip.Reader, name string) ([]byte, error) {
+	for _, f := range files.File {
+		if f.Name != name {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	}
+	return nil, fmt.Errorf("Not a valid xlsx file: %s is missing", name)
+}
+
+func unmarshalZipFile(files *zip.Reader, name string, v interface{}) error {
+	content, err := readZipFile(files, name)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(content, v)
+}
@@ -0,0 +1,171 @@
+package format
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	tu "testutil"
+)
+
+const xlsxTestSheet = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:x14ac="http://schemas.microsoft.com/office/spreadsheetml/2009/9/ac"><dimension ref="A1:D4"/><cols><col min="1" max="1" width="20"/></cols><sheetData>` +
+	`<row r="1" spans="1:4" x14ac:dyDescent="0.25"><c r="A1" s="1" t="s"><v>0</v></c><c r="B1" s="1" t="s"><v>1</v></c><c r="C1" s="1" t="s"><v>2</v></c><c r="D1" s="1" t="s"><v>3</v></c></row>` +
+	`<row r="2"><c r="A2" t="s"><v>4</v></c><c r="B2" t="s"><v>5</v></c><c r="C2" t="inlineStr"><is><t>Welcome &amp; hello</t></is></c><c r="D2" s="2"/></row>` +
+	`<row r="3"><c r="A3" t="s"><v>6</v></c><c r="C3" t="s"><v>7</v></c><c r="D3" s="2" t="s"><v>8</v></c></row>` +
+	`<row r="4"><c r="A4"><v>42</v></c><c r="C4"><f>LEN(C3)</f><v>6</v></c></row>` +
+	`</sheetData><pageMargins left="0.7" right="0.7" top="0.75" bottom="0.75" header="0.3" footer="0.3"/></worksheet>`
+
+var xlsxTestStrings = []string{"key", "comment", "en", "fr", "title", "The page title", "close", "Close", "Fermer"}
+
+// A workbook with the strings sheet and a second sheet
+func xlsxTestBook() []byte {
+	sst := `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`
+	for i, s := range xlsxTestStrings {
+		if i == 5 {
+			// rich text with a phonetic run
+			sst += `<si><r><t>The page </t></r><r><rPr><b/></rPr><t>title</t></r><rPh sb="0" eb="1"><t>X</t></rPh></si>`
+			continue
+		}
+		sst += "<si><t>" + s + "</t></si>"
+	}
+	sst += "</sst>"
+
+	files := [][2]string{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`},
+		{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Notes" sheetId="2" r:id="rId2"/><sheet name="Strings" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="/xl/worksheets/sheet2.xml"/></Relationships>`},
+		{"xl/sharedStrings.xml", sst},
+		{"xl/worksheets/sheet1.xml", xlsxTestSheet},
+		{"xl/worksheets/sheet2.xml", `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>notes</t></is></c></row></sheetData></worksheet>`}}
+	var out bytes.Buffer
+	w := zip.NewWriter(&out)
+	for _, f := range files {
+		fw, _ := w.Create(f[0])
+		fw.Write([]byte(f[1]))
+	}
+	w.Close()
+	return out.Bytes()
+}
+
+func readTestZipFile(t *testing.T, content []byte, name string) string {
+	files, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := readZipFile(files, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func Test_Xlsx_Parse(t *testing.T) {
+	f := new(Xlsx)
+	f.Init(map[string]interface{}{"sheet": "Strings", "value": "en"})
+	c, err := f.Parse(xlsxTestBook())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("entries", 3, len(c.Entries), t)
+	tu.AssertEquals("inline string", "Welcome & hello", c.Find("title").Value, t)
+	tu.AssertEquals("rich text comment", "The page title", c.Find("title").Comment, t)
+	tu.AssertEquals("number key", "6", c.Find("42").Value, t)
+
+	f = new(Xlsx)
+	f.Init(map[string]interface{}{})
+	if _, err = f.Parse(xlsxTestBook()); err == nil {
+		t.Errorf("Expected an error for the first sheet, which has no key column")
+	}
+	if _, err = f.Parse([]byte("not a zip")); err == nil {
+		t.Errorf("Expected an error for a file that is not a workbook")
+	}
+}
+
+func Test_Xlsx_Write(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	ioutil.WriteFile(filepath.Join(tmpDir, "en-copy.xlsx"), xlsxTestBook(), 0644)
+
+	data, _ := json.Marshal(map[string]string{"title": "Bienvenue <b>"})
+
+	f := new(Xlsx)
+	f.Init(map[string]interface{}{"sheet": "Strings", "value": 3})
+	if err := f.Write(tmpDir, "fr", "en", "copy", string(data), FileLocators["LANG-NAME"]); err != nil {
+		t.Fatal(err)
+	}
+
+	written := mustRead(filepath.Join(tmpDir, "fr-copy.xlsx"))
+	expected := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:x14ac="http://schemas.microsoft.com/office/spreadsheetml/2009/9/ac"><dimension ref="A1:D4"/><cols><col min="1" max="1" width="20"/></cols><sheetData>` +
+		`<row r="1" x14ac:dyDescent="0.25"><c r="A1" s="1" t="s"><v>0</v></c><c r="B1" s="1" t="s"><v>1</v></c><c r="C1" s="1" t="s"><v>2</v></c><c r="D1" s="1" t="s"><v>3</v></c></row>` +
+		`<row r="2"><c r="A2" t="s"><v>4</v></c><c r="B2" t="s"><v>5</v></c><c r="C2" t="inlineStr"><is><t xml:space="preserve">Bienvenue &lt;b&gt;</t></is></c><c r="D2" s="2"/></row>` +
+		`<row r="3"><c r="A3" t="s"><v>6</v></c><c r="D3" s="2" t="s"><v>8</v></c></row>` +
+		`<row r="4"><c r="A4"><v>42</v></c></row>` +
+		`</sheetData><pageMargins left="0.7" right="0.7" top="0.75" bottom="0.75" header="0.3" footer="0.3"/></worksheet>`
+	tu.AssertEquals("sheet", expected, readTestZipFile(t, written, "xl/worksheets/sheet1.xml"), t)
+	tu.AssertEquals("other sheets", readTestZipFile(t, xlsxTestBook(), "xl/worksheets/sheet2.xml"), readTestZipFile(t, written, "xl/worksheets/sheet2.xml"), t)
+
+	c, err := f.Parse(written)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("translated", "Bienvenue <b>", c.Find("title").Value, t)
+}
+
+func Test_XlsxLangs(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	ioutil.WriteFile(filepath.Join(tmpDir, "copy.xlsx"), xlsxTestBook(), 0644)
+
+	f := new(XlsxLangs)
+	f.Init(map[string]interface{}{"sheet": "Strings"})
+	langs, err := f.Languages(xlsxTestBook())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("languages", "en fr", strings.Join(langs, " "), t)
+
+	c, err := f.Parse(xlsxTestBook())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("source lang", "en", c.Lang, t)
+
+	cleaned, _, err := f.CleanLang(xlsxTestBook(), "fr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("translations", `{"close":{"string":"Fermer"}}`, string(cleaned), t)
+
+	data, _ := json.Marshal(map[string]string{"title": "Bem-vindo", "close": "Fechar"})
+	if err := f.Write(tmpDir, "pt_BR", "en", "copy", string(data), FileLocators["SINGLE-FILE"]); err != nil {
+		t.Fatal(err)
+	}
+	written := mustRead(filepath.Join(tmpDir, "copy.xlsx"))
+	langs, _ = f.Languages(written)
+	tu.AssertEquals("new language", "en fr pt_BR", strings.Join(langs, " "), t)
+	cleaned, _, _ = f.CleanLang(written, "pt_BR")
+	tu.AssertEquals("written", `{"close":{"string":"Fechar"},"title":{"string":"Bem-vindo","developer_comment":"The page title"}}`, string(cleaned), t)
+	cleaned, _, _ = f.CleanLang(written, "fr")
+	tu.AssertEquals("other language", `{"close":{"string":"Fermer"}}`, string(cleaned), t)
+}
+
+func Test_Xlsx_Serialize_NoTemplate(t *testing.T) {
+	c := NewCatalog("fr")
+	c.Add(&Entry{Key: "title", Value: "Titre", Comment: "Page title"})
+
+	f := new(Xlsx)
+	f.Init(map[string]interface{}{"sheet": "Copy"})
+	out, err := f.Serialize(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := f.Parse(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("value", "Titre", parsed.Find("title").Value, t)
+	tu.AssertEquals("comment", "Page title", parsed.Find("title").Comment, t)
+}
@@ -0,0 +1,41 @@
+package format
+
+import "encoding/xml"
+
+// the xml helpers shared by the formats
+
+// skip the content of the current element.  Returns the offset of its end tag
+func skipXmlContent(decoder *xml.Decoder) (int64, error) {
+	depth := 0
+	for {
+		before := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err != nil {
+			return 0, err
+		}
+		switch token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			if depth == 0 {
+				return before, nil
+			}
+			depth--
+		}
+	}
+}
+
+// true if the start tag ending at offset is an empty element tag: <string name="x"/>
+func isSelfClosing(content []byte, offset int64) bool {
+	return offset >= 2 && string(content[offset-2:offset]) == "/>"
+}
+
+// the value of the attribute with the local name, empty if the element does not have it
+func xmlAttr(t xml.StartElement, name string) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,835 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Rails i18n YAML files: the root key is the language and the strings are nested below it.  The nested keys are
+// joined with . for the upload without the root key: fr: {menu: {open: Ouvrir}} is uploaded as menu.open.
+// Mappings with only plural keys (one, other ...) are uploaded as plural strings, the elements of sequences are keyed by
+// their index and the comment lines directly before a key are uploaded as its comment.
+//
+// Translations are written with the source file as the template and the language of the translation as the root key.
+// Comments, anchors, aliases and the values that are not strings are kept as they are, strings without a translation
+// are left out so that Rails falls back to the default locale.  Only the block and flow styles used by locale files are
+// supported: mappings, sequences of scalars, [flow, sequences] and scalars.  Other values are kept without being uploaded
+type Yaml struct{}
+
+func (f *Yaml) Init(initParams map[string]interface{}) {}
+func (f *Yaml) Ext() string                            { return "yml" }
+
+func (f *Yaml) Parse(content []byte) (*Catalog, error) {
+	doc, err := parseYaml(content)
+	if err != nil {
+		return nil, err
+	}
+	c := NewCatalog(doc.root.key)
+	f.addEntries(c, "", doc.root)
+	return c, nil
+}
+
+func (f *Yaml) addEntries(c *Catalog, key string, n *yamlNode) {
+	for _, child := range n.children {
+		path := joinYamlKey(key, child.key)
+		switch {
+		case isYamlPlural(child):
+			plurals := map[string]string{}
+			for _, form := range child.children {
+				plurals[form.key] = form.value
+			}
+			c.Add(&Entry{Key: path, Plurals: plurals, Comment: yamlComment(child.comments)})
+		case child.kind == yamlScalar:
+			c.Add(&Entry{Key: path, Value: child.value, Comment: yamlComment(child.comments)})
+		default:
+			f.addEntries(c, path, child)
+		}
+	}
+}
+
+func (f *Yaml) Clean(content []byte) ([]byte, string, error) {
+	return cleanToStructuredJson(f, content)
+}
+
+// The template is rewritten with the translations of its strings.  Without a template the nesting is
+// created from the keys of the catalog
+func (f *Yaml) Serialize(c *Catalog, template []byte) ([]byte, error) {
+	// Rails uses pt-BR, not pt_BR
+	lang := strings.Replace(c.Lang, "_", "-", -1)
+	var doc *yamlDoc
+	if template == nil {
+		doc = buildYaml(c)
+	} else {
+		var err error
+		if doc, err = parseYaml(template); err != nil {
+			return nil, err
+		}
+	}
+	doc.root.lines[0] = strings.Replace(doc.root.lines[0], doc.root.key, lang, 1)
+
+	text, translated := f.translateNode(c, "", doc.root, false)
+	if !translated {
+		text = yamlCommentLines(doc.root) + lang + ": {}"
+	}
+	var out bytes.Buffer
+	out.WriteString(text + "\n")
+	for _, line := range doc.trailing {
+		out.WriteString(line + "\n")
+	}
+	return out.Bytes(), nil
+}
+
+func (f *Yaml) Write(rootDir, langCode, srcLang, filename, translation string, fileLocator FileLocator) error {
+	return writeCatalog(f, rootDir, langCode, srcLang, filename, translation, fileLocator)
+}
+
+// Returns the text of the node with the translations of its strings, false if nothing in the node is translated.
+// The strings of anchored nodes keep their source value when they are not translated so that the aliases of the anchor stay valid
+func (f *Yaml) translateNode(c *Catalog, path string, n *yamlNode, keep bool) (string, bool) {
+	keep = keep || n.anchored
+	text := ""
+	switch {
+	case n.kind == yamlRaw:
+		text = strings.Join(n.lines, "\n")
+	case n.kind == yamlScalar:
+		if e := c.Find(path); e != nil && e.Value != "" {
+			text = n.prefix + renderYamlScalar(n, e.Value, false)
+		} else if keep {
+			text = n.source()
+		} else {
+			return "", false
+		}
+	case isYamlPlural(n):
+		e := c.Find(path)
+		if e == nil || !hasPluralTranslation(e) {
+			if !keep {
+				return "", false
+			}
+			text = n.source()
+			break
+		}
+		style := *n.children[0]
+		for _, form := range n.children {
+			if form.key == "other" {
+				style = *form
+			}
+		}
+		style.suffix = ""
+		lines := []string{n.lines[0]}
+		for _, category := range e.PluralForms() {
+			if e.Plurals[category] != "" {
+				lines = append(lines, style.indent+category+": "+renderYamlScalar(&style, e.Plurals[category], false))
+			}
+		}
+		text = strings.Join(lines, "\n")
+	case n.flow:
+		items := make([]string, len(n.children))
+		translated := false
+		for i, item := range n.children {
+			e := c.Find(joinYamlKey(path, item.key))
+			switch {
+			case item.kind == yamlScalar && e != nil && e.Value != "":
+				items[i] = renderYamlScalar(item, e.Value, true)
+				translated = true
+			case item.kind == yamlRaw || keep:
+				items[i] = item.lines[0]
+			default:
+				items[i] = `""`
+			}
+		}
+		if !translated && !keep {
+			return "", false
+		}
+		text = n.prefix + "[" + strings.Join(items, ", ") + "]" + n.suffix
+	case n.kind == yamlSeq:
+		// the untranslated items are written empty to keep the index of the others
+		lines := []string{n.lines[0]}
+		translated := false
+		for _, item := range n.children {
+			itemText, ok := f.translateNode(c, joinYamlKey(path, item.key), item, keep)
+			if !ok {
+				itemText = yamlCommentLines(item) + item.prefix + `""`
+			} else if item.kind != yamlRaw {
+				translated = true
+			}
+			lines = append(lines, itemText)
+		}
+		if !translated && !keep {
+			return "", false
+		}
+		text = strings.Join(lines, "\n")
+	default:
+		lines := []string{n.lines[0]}
+		for _, child := range n.children {
+			if childText, ok := f.translateNode(c, joinYamlKey(path, child.key), child, keep); ok {
+				lines = append(lines, childText)
+			}
+		}
+		if len(lines) == 1 {
+			return "", false
+		}
+		text = strings.Join(lines, "\n")
+	}
+	return yamlCommentLines(n) + text, true
+}
+
+const (
+	yamlScalar = iota
+	yamlMap
+	yamlSeq
+	// a value that is kept as it is: aliases, null, numbers, flow mappings ...
+	yamlRaw
+)
+
+// A value of a yaml file with the lines it was parsed from
+type yamlNode struct {
+	kind int
+	// the key of the value in its mapping, or the index in its sequence
+	key string
+	// the comment and blank lines before the node
+	comments []string
+	// the lines of the node.  Only the first line for mappings and block sequences, the children have their own lines
+	lines []string
+	// the start of the first line up to the value: `  title: &title ` or `  - `
+	prefix string
+	// the comment after a scalar or the header of a block scalar
+	suffix string
+	// the indentation of the line of the node and of the content of block scalars
+	indent, blockIndent string
+	// the value of a scalar
+	value string
+	// the style of a scalar: 0 (plain), ', ", | or >
+	style    byte
+	anchored bool
+	// [a, b] sequences
+	flow     bool
+	children []*yamlNode
+}
+
+type yamlDoc struct {
+	root *yamlNode
+	// the comment lines at the end of the file
+	trailing []string
+}
+
+func parseYaml(content []byte) (*yamlDoc, error) {
+	text := strings.Replace(string(bytes.TrimPrefix(content, []byte("\ufeff"))), "\r\n", "\n", -1)
+	lines := strings.Split(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	p := &yamlParser{lines: lines}
+	nodes, err := p.parseMapping(0)
+	if err != nil {
+		return nil, err
+	}
+	trailing := p.comments()
+	if p.pos < len(p.lines) {
+		return nil, p.errorf("expected a key at the start of the line")
+	}
+	if len(nodes) != 1 || (nodes[0].kind != yamlMap && nodes[0].kind != yamlRaw) {
+		return nil, fmt.Errorf("The yaml file must have the language as its only root key")
+	}
+	return &yamlDoc{nodes[0], trailing}, nil
+}
+
+// The nodes of the keys of the catalog: the template of a file without a source file
+func buildYaml(c *Catalog) *yamlDoc {
+	root := &yamlNode{kind: yamlMap, key: c.Lang, lines: []string{c.Lang + ":"}}
+	for _, e := range c.Entries {
+		path := strings.Split(e.Key, ".")
+		parent, indent := root, "  "
+		for _, name := range path[:len(path)-1] {
+			var child *yamlNode
+			for _, n := range parent.children {
+				if n.key == name && n.kind == yamlMap {
+					child = n
+				}
+			}
+			if child == nil {
+				child = &yamlNode{kind: yamlMap, key: name, lines: []string{indent + yamlKey(name) + ":"}}
+				parent.children = append(parent.children, child)
+			}
+			parent, indent = child, indent+"  "
+		}
+		name := path[len(path)-1]
+		n := &yamlNode{kind: yamlScalar, key: name, prefix: indent + yamlKey(name) + ": ", indent: indent, blockIndent: indent + "  "}
+		if e.IsPlural() {
+			other := &yamlNode{kind: yamlScalar, key: "other", indent: indent + "  ", blockIndent: indent + "    "}
+			n = &yamlNode{kind: yamlMap, key: name, lines: []string{indent + yamlKey(name) + ":"}, children: []*yamlNode{other}}
+		}
+		parent.children = append(parent.children, n)
+	}
+	return &yamlDoc{root: root}
+}
+
+type yamlParser struct {
+	lines []string
+	pos   int
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Unable to parse the yaml file, line %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+// the comment and blank lines at the position
+func (p *yamlParser) comments() []string {
+	start := p.pos
+	for p.pos < len(p.lines) && isYamlComment(p.lines[p.pos]) {
+		p.pos++
+	}
+	return p.lines[start:p.pos]
+}
+
+// The following lines that are more indented than indent, with the blank lines between them.
+// Comment lines end the lines unless they are part of the value
+func (p *yamlParser) more(indent int, withComments bool) []string {
+	end := p.pos
+	for i := p.pos; i < len(p.lines); i++ {
+		trimmed := strings.TrimSpace(p.lines[i])
+		if trimmed == "" {
+			continue
+		}
+		if indentOf(p.lines[i]) <= indent || !withComments && trimmed[0] == '#' {
+			break
+		}
+		end = i + 1
+	}
+	lines := p.lines[p.pos:end]
+	p.pos = end
+	return lines
+}
+
+func (p *yamlParser) parseMapping(indent int) ([]*yamlNode, error) {
+	nodes := []*yamlNode{}
+	for {
+		start := p.pos
+		comments := p.comments()
+		if p.pos == len(p.lines) {
+			p.pos = start
+			return nodes, nil
+		}
+		line := p.lines[p.pos]
+		lineIndent := indentOf(line)
+		if lineIndent < indent || lineIndent == indent && isYamlSeqItem(line) {
+			// the comments belong to the next node of the parent
+			p.pos = start
+			return nodes, nil
+		}
+		if lineIndent > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+		key, end, ok := splitYamlKey(line)
+		if !ok {
+			return nil, p.errorf("expected a key")
+		}
+		p.pos++
+		n := &yamlNode{key: key, comments: comments, indent: line[:lineIndent]}
+		if err := p.parseValue(n, line, end, indent, true); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+}
+
+func (p *yamlParser) parseSequence(indent int) ([]*yamlNode, error) {
+	nodes := []*yamlNode{}
+	for {
+		start := p.pos
+		comments := p.comments()
+		if p.pos == len(p.lines) || indentOf(p.lines[p.pos]) != indent || !isYamlSeqItem(p.lines[p.pos]) {
+			p.pos = start
+			return nodes, nil
+		}
+		line := p.lines[p.pos]
+		p.pos++
+		n := &yamlNode{key: strconv.Itoa(len(nodes)), comments: comments, indent: line[:indent]}
+		item := strings.TrimSpace(line[indent+1:])
+		if _, _, isMap := splitYamlKey(line[indent+1:]); isMap || item == "" || isYamlSeqItem(item) {
+			// nested mappings and sequences are kept as they are
+			n.kind = yamlRaw
+			n.lines = append([]string{line}, p.more(indent, true)...)
+		} else if err := p.parseValue(n, line, indent+1, indent, false); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+}
+
+// Parse the value that starts at start of the line.  indent is the indentation of the key or sequence item of the value
+func (p *yamlParser) parseValue(n *yamlNode, line string, start, indent int, isKey bool) error {
+	valueStart := start
+	for valueStart < len(line) && line[valueStart] == ' ' {
+		valueStart++
+	}
+	// the anchor and tag of the value
+	for valueStart < len(line) && (line[valueStart] == '&' || line[valueStart] == '!') {
+		n.anchored = n.anchored || line[valueStart] == '&'
+		for valueStart < len(line) && line[valueStart] != ' ' {
+			valueStart++
+		}
+		for valueStart < len(line) && line[valueStart] == ' ' {
+			valueStart++
+		}
+	}
+	n.prefix = line[:valueStart]
+	n.lines = []string{line}
+	n.blockIndent = strings.Repeat(" ", indent+2)
+	rest := line[valueStart:]
+
+	switch {
+	case rest == "" || rest[0] == '#':
+		// a mapping or sequence on the next lines, or null
+		n.suffix = rest
+		start := p.pos
+		p.comments()
+		var err error
+		if p.pos < len(p.lines) {
+			next := p.lines[p.pos]
+			nextIndent := indentOf(next)
+			if isYamlSeqItem(next) && (nextIndent > indent || nextIndent == indent && isKey) {
+				p.pos = start
+				n.kind = yamlSeq
+				n.children, err = p.parseSequence(nextIndent)
+				return err
+			}
+			if nextIndent > indent {
+				p.pos = start
+				n.kind = yamlMap
+				n.children, err = p.parseMapping(nextIndent)
+				return err
+			}
+		}
+		p.pos = start
+		n.kind = yamlRaw
+	case rest[0] == '|' || rest[0] == '>':
+		return p.parseBlockScalar(n, rest, indent)
+	case rest[0] == '"' || rest[0] == '\'':
+		return p.parseQuoted(n, rest)
+	case rest[0] == '[':
+		p.parseFlowSequence(n, rest, indent)
+	case rest[0] == '*' || rest[0] == '{':
+		n.kind = yamlRaw
+		n.lines = append(n.lines, p.more(indent, true)...)
+	default:
+		value := rest
+		if i := strings.Index(rest, " #"); i >= 0 {
+			value, n.suffix = rest[:i], rest[i:]
+		}
+		value = strings.TrimRight(value, " ")
+		lines := []string{value}
+		for _, line := range p.more(indent, false) {
+			n.lines = append(n.lines, line)
+			lines = append(lines, strings.TrimSpace(line))
+		}
+		n.kind = yamlScalar
+		n.value = foldYamlLines(lines, false)
+		if len(lines) == 1 && yamlNonString.MatchString(value) {
+			n.kind = yamlRaw
+		}
+	}
+	return nil
+}
+
+// | and > scalars
+func (p *yamlParser) parseBlockScalar(n *yamlNode, header string, indent int) error {
+	if i := strings.Index(header, " #"); i >= 0 {
+		header, n.suffix = header[:i], header[i:]
+	}
+	header = strings.TrimRight(header, " ")
+	n.kind = yamlScalar
+	n.style = header[0]
+	chomp, explicitIndent := byte(0), 0
+	for _, ch := range header[1:] {
+		switch {
+		case ch == '-' || ch == '+':
+			chomp = byte(ch)
+		case ch >= '1' && ch <= '9':
+			explicitIndent = int(ch - '0')
+		default:
+			return p.errorf("invalid block scalar header %s", header)
+		}
+	}
+
+	lines := p.more(indent, true)
+	n.lines = append(n.lines, lines...)
+	blockIndent := indent + explicitIndent
+	if explicitIndent == 0 {
+		for _, line := range lines {
+			if strings.TrimSpace(line) != "" {
+				blockIndent = indentOf(line)
+				break
+			}
+		}
+	}
+	if len(lines) > 0 {
+		n.blockIndent = strings.Repeat(" ", blockIndent)
+	}
+	content := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) > blockIndent {
+			content[i] = line[blockIndent:]
+		}
+	}
+
+	if n.style == '|' {
+		n.value = strings.Join(content, "\n")
+	} else {
+		n.value = foldYamlLines(content, true)
+	}
+	if chomp != '-' && n.value != "" {
+		n.value += "\n"
+	}
+	if chomp == '+' {
+		for p.pos < len(p.lines) && strings.TrimSpace(p.lines[p.pos]) == "" {
+			n.lines = append(n.lines, p.lines[p.pos])
+			n.value += "\n"
+			p.pos++
+		}
+	}
+	return nil
+}
+
+// Single and double quoted scalars, they may continue on the next lines
+func (p *yamlParser) parseQuoted(n *yamlNode, text string) error {
+	quote := text[0]
+	for closingYamlQuote(text, quote) < 0 {
+		if p.pos == len(p.lines) {
+			return p.errorf("unterminated quoted string")
+		}
+		text += "\n" + p.lines[p.pos]
+		n.lines = append(n.lines, p.lines[p.pos])
+		p.pos++
+	}
+	end := closingYamlQuote(text, quote)
+	n.kind = yamlScalar
+	n.style = quote
+	n.value = decodeYamlQuoted(text[1:end], quote)
+	n.suffix = text[end+1:]
+	return nil
+}
+
+// [a, b] sequences of scalars.  Other flow collections are kept as they are
+func (p *yamlParser) parseFlowSequence(n *yamlNode, text string, indent int) {
+	items := []string{}
+	start, end := 1, -1
+	for i := 1; i < len(text) && end < 0; i++ {
+		switch text[i] {
+		case '"', '\'':
+			closing := closingYamlQuote(text[i:], text[i])
+			if closing < 0 {
+				i = len(text)
+			} else {
+				i += closing
+			}
+		case '[', '{':
+			i = len(text)
+		case ',':
+			items = append(items, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		case ']':
+			items = append(items, strings.TrimSpace(text[start:i]))
+			end = i
+		}
+	}
+	if end < 0 || !isYamlComment(text[end+1:]) {
+		n.kind = yamlRaw
+		n.lines = append(n.lines, p.more(indent, true)...)
+		return
+	}
+
+	n.kind = yamlSeq
+	n.flow = true
+	n.suffix = text[end+1:]
+	if items[len(items)-1] == "" {
+		// [] or a trailing comma
+		items = items[:len(items)-1]
+	}
+	for i, item := range items {
+		child := &yamlNode{kind: yamlScalar, key: strconv.Itoa(i), lines: []string{item}}
+		switch {
+		case item != "" && (item[0] == '"' || item[0] == '\''):
+			if closingYamlQuote(item, item[0]) == len(item)-1 {
+				child.style = item[0]
+				child.value = decodeYamlQuoted(item[1:len(item)-1], item[0])
+			} else {
+				child.kind = yamlRaw
+			}
+		case item == "" || yamlNonString.MatchString(item):
+			child.kind = yamlRaw
+		default:
+			child.value = item
+		}
+		n.children = append(n.children, child)
+	}
+}
+
+// the plain scalars that are not strings: null, booleans (including the yes, no, on and off of YAML 1.1), numbers and dates
+var yamlNonString = regexp.MustCompile(`^(~|null|Null|NULL|true|True|TRUE|false|False|FALSE|yes|Yes|YES|no|No|NO|on|On|ON|off|Off|OFF|` +
+	`[-+]?(\.[0-9]+|[0-9][0-9_]*(\.[0-9_]*)?)([eE][-+]?[0-9]+)?|[-+]?\.(inf|Inf|INF)|\.(nan|NaN|NAN)|0x[0-9a-fA-F_]+|0o[0-7_]+|` +
+	`[-+]?[0-9][0-9_]*(:[0-5]?[0-9])+(\.[0-9_]*)?|[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}([Tt ].*)?)$`)
+
+// Split a line of a mapping into the key and the position after the colon.  Returns false if the line is not key: value
+func splitYamlKey(line string) (string, int, bool) {
+	start := indentOf(line)
+	s := line[start:]
+	if s == "" || s[0] == '#' {
+		return "", 0, false
+	}
+	if s[0] == '"' || s[0] == '\'' {
+		closing := closingYamlQuote(s, s[0])
+		if closing < 0 {
+			return "", 0, false
+		}
+		rest := strings.TrimLeft(s[closing+1:], " ")
+		if !strings.HasPrefix(rest, ":") || len(rest) > 1 && rest[1] != ' ' {
+			return "", 0, false
+		}
+		return decodeYamlQuoted(s[1:closing], s[0]), len(line) - len(rest) + 1, true
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '#' && i > 0 && s[i-1] == ' ' {
+			return "", 0, false
+		}
+		if s[i] == ':' && (i+1 == len(s) || s[i+1] == ' ') {
+			return strings.TrimRight(s[:i], " "), start + i + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+// the index of the quote that closes the quoted string at the start of s, -1 if it is not closed
+func closingYamlQuote(s string, quote byte) int {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case quote == '\'' && s[i] == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+func decodeYamlQuoted(s string, quote byte) string {
+	lines := strings.Split(s, "\n")
+	if quote == '"' {
+		// an escaped line break joins the lines without a space
+		for i := 0; i < len(lines)-1; i++ {
+			if continues(strings.TrimRight(lines[i], " \t")) {
+				lines[i] = strings.TrimSuffix(strings.TrimRight(lines[i], " \t"), `\`) + strings.TrimLeft(lines[i+1], " \t")
+				lines = append(lines[:i+1], lines[i+2:]...)
+				i--
+			}
+		}
+	}
+	for i := range lines {
+		if i > 0 {
+			lines[i] = strings.TrimLeft(lines[i], " \t")
+		}
+		if i < len(lines)-1 {
+			lines[i] = strings.TrimRight(lines[i], " \t")
+		}
+	}
+	folded := foldYamlLines(lines, false)
+	if quote == '\'' {
+		return strings.Replace(folded, "''", "'", -1)
+	}
+	return unescapeYamlDouble(folded)
+}
+
+var yamlEscapes = map[rune]string{'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v", 'f': "\f",
+	'r': "\r", 'e': "\x1b", ' ': " ", '"': `"`, '/': "/", '\\': `\`, 'N': "\u0085", '_': "\u00a0", 'L': "\u2028", 'P': "\u2029"}
+
+func unescapeYamlDouble(s string) string {
+	var out strings.Builder
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '\\' || i == len(runes)-1 {
+			out.WriteRune(runes[i])
+			continue
+		}
+		i++
+		if escaped, ok := yamlEscapes[runes[i]]; ok {
+			out.WriteString(escaped)
+			continue
+		}
+		digits := map[rune]int{'x': 2, 'u': 4, 'U': 8}[runes[i]]
+		if digits > 0 && i+digits < len(runes) {
+			if code, err := strconv.ParseUint(string(runes[i+1:i+1+digits]), 16, 32); err == nil {
+				out.WriteRune(rune(code))
+				i += digits
+				continue
+			}
+		}
+		out.WriteRune('\\')
+		out.WriteRune(runes[i])
+	}
+	return out.String()
+}
+
+// Join the lines of a multi-line scalar: a line break is a space and each empty line is a line break.
+// The line breaks around more indented lines of folded block scalars are kept
+func foldYamlLines(lines []string, moreIndented bool) string {
+	var out strings.Builder
+	empty, prev := 0, ""
+	for _, line := range lines {
+		if line == "" {
+			empty++
+			continue
+		}
+		switch {
+		case prev == "":
+			out.WriteString(strings.Repeat("\n", empty))
+		case moreIndented && (prev[0] == ' ' || line[0] == ' '):
+			out.WriteString(strings.Repeat("\n", empty+1))
+		case empty > 0:
+			out.WriteString(strings.Repeat("\n", empty))
+		default:
+			out.WriteString(" ")
+		}
+		out.WriteString(line)
+		prev, empty = line, 0
+	}
+	return out.String()
+}
+
+// The value written after the prefix of the node, in the style of the node if the value can be written in it
+func renderYamlScalar(n *yamlNode, value string, flow bool) string {
+	content := strings.TrimRight(value, "\n")
+	switch {
+	case !flow && (n.style == '|' || n.style == '>') && strings.TrimSpace(value) != "" && !strings.HasPrefix(strings.TrimLeft(value, "\n"), " "):
+		style := n.style
+		if strings.Contains(content, "\n") {
+			style = '|'
+		}
+		// the chomping indicator keeps the line breaks at the end of the value
+		header := string(style)
+		switch len(value) - len(content) {
+		case 0:
+			header += "-"
+		case 1:
+		default:
+			header += "+"
+		}
+		lines := strings.Split(content, "\n")
+		for i, line := range lines {
+			if line != "" {
+				lines[i] = n.blockIndent + line
+			}
+		}
+		for i := 1; i < len(value)-len(content); i++ {
+			lines = append(lines, "")
+		}
+		return header + n.suffix + "\n" + strings.Join(lines, "\n")
+	case n.style == '\'' && isYamlPrintable(value):
+		return "'" + strings.Replace(value, "'", "''", -1) + "'" + n.suffix
+	case n.style == 0 && isYamlPlainSafe(value, flow):
+		return value + n.suffix
+	}
+	return strconv.Quote(value) + n.suffix
+}
+
+// true if the string can be written without quotes
+func isYamlPlainSafe(s string, flow bool) bool {
+	if s == "" || s != strings.TrimSpace(s) || !isYamlPrintable(s) || yamlNonString.MatchString(s) {
+		return false
+	}
+	if strings.IndexByte("-?:,[]{}#&*!|>'\"%@`", s[0]) >= 0 {
+		return false
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return false
+	}
+	return !flow || !strings.ContainsAny(s, ",[]{}")
+}
+
+func isYamlPrintable(s string) bool {
+	for _, r := range s {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func yamlKey(key string) string {
+	if isYamlPlainSafe(key, false) {
+		return key
+	}
+	return strconv.Quote(key)
+}
+
+// a mapping with only plural categories as keys, one of them other
+func isYamlPlural(n *yamlNode) bool {
+	if n.kind != yamlMap || len(n.children) == 0 {
+		return false
+	}
+	hasOther := false
+	for _, child := range n.children {
+		if child.kind != yamlScalar || !isPluralCategory(child.key) {
+			return false
+		}
+		hasOther = hasOther || child.key == "other"
+	}
+	return hasOther
+}
+
+func isYamlComment(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || trimmed[0] == '#' || trimmed == "---" || trimmed == "..."
+}
+
+func isYamlSeqItem(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	return trimmed == "-" || strings.HasPrefix(trimmed, "- ")
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// the comment lines directly before a key
+func yamlComment(lines []string) string {
+	comment := []string{}
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed[0] != '#' {
+			comment = comment[:0]
+			continue
+		}
+		comment = append(comment, strings.TrimSpace(trimmed[1:]))
+	}
+	return strings.Join(comment, "\n")
+}
+
+// the lines of the node and its children as they are in the file
+func (n *yamlNode) source() string {
+	lines := append([]string{}, n.lines...)
+	if !n.flow {
+		for _, child := range n.children {
+			lines = append(lines, yamlCommentLines(child)+child.source())
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func yamlCommentLines(n *yamlNode) string {
+	if len(n.comments) == 0 {
+		return ""
+	}
+	return strings.Join(n.comments, "\n") + "\n"
+}
+
+func joinYamlKey(key, name string) string {
+	if key == "" {
+		return name
+	}
+	return key + "." + name
+}
@@ -0,0 +1,169 @@
+package format
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	tu "testutil"
+)
+
+const yamlSource = `# Strings of the web application
+---
+en:
+  # The title of the home page
+  title: Welcome
+  defaults: &defaults
+    ok: "OK"
+    cancel: Cancel
+  dialog:
+    <<: *defaults
+    close: 'Don''t close'
+  menu:
+    open: Open # the open item
+    quit: "Quit \"now\"\t"
+  description: |
+    First line
+    second line
+  folded: >-
+    A long
+    text
+  messages:
+    one: "%{count} message"
+    other: "%{count} messages"
+  days: [Sunday, "Monday", ~]
+  months:
+    - January
+    - February
+  precision: 3
+  enabled: true
+  empty:
+# the end
+`
+
+func Test_Yaml_Parse(t *testing.T) {
+	c, err := new(Yaml).Parse([]byte(yamlSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tu.AssertEquals("lang", "en", c.Lang, t)
+	tu.AssertEqualsInt("entries", 13, len(c.Entries), t)
+	tu.AssertEquals("title", "Welcome", c.Find("title").Value, t)
+	tu.AssertEquals("comment", "The title of the home page", c.Find("title").Comment, t)
+	tu.AssertEquals("anchor", "Cancel", c.Find("defaults.cancel").Value, t)
+	tu.AssertEquals("single quoted", "Don't close", c.Find("dialog.close").Value, t)
+	tu.AssertEquals("trailing comment", "Open", c.Find("menu.open").Value, t)
+	tu.AssertEquals("double quoted", "Quit \"now\"\t", c.Find("menu.quit").Value, t)
+	tu.AssertEquals("literal", "First line\nsecond line\n", c.Find("description").Value, t)
+	tu.AssertEquals("folded", "A long text", c.Find("folded").Value, t)
+	tu.AssertEquals("plural one", "%{count} message", c.Find("messages").Plurals["one"], t)
+	tu.AssertEquals("plural other", "%{count} messages", c.Find("messages").Plurals["other"], t)
+	tu.AssertEquals("flow sequence", "Monday", c.Find("days.1").Value, t)
+	tu.AssertEquals("sequence", "February", c.Find("months.1").Value, t)
+	for _, key := range []string{"dialog.<<", "days.2", "precision", "enabled", "empty"} {
+		if c.Find(key) != nil {
+			t.Errorf("%s is not a string and should not be uploaded", key)
+		}
+	}
+
+	if _, err = new(Yaml).Parse([]byte("en:\n  a: b\nfr:\n  a: c\n")); err == nil {
+		t.Errorf("Expected an error for a file with two languages")
+	}
+	if _, err = new(Yaml).Parse([]byte("en:\n  a: \"b\n")); err == nil {
+		t.Errorf("Expected an error for an unterminated string")
+	}
+}
+
+func Test_Yaml_Clean(t *testing.T) {
+	cleaned, i18n, err := new(Yaml).Clean([]byte(yamlSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("i18n type", "STRUCTURED_JSON", i18n, t)
+
+	var data map[string]map[string]string
+	json.Unmarshal(cleaned, &data)
+	tu.AssertEquals("nested", "Open", data["menu.open"]["string"], t)
+	tu.AssertEquals("plural", "{cnt, plural, one {%{count} message} other {%{count} messages}}", data["messages"]["string"], t)
+}
+
+func Test_Yaml_Write(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "test")
+	ioutil.WriteFile(filepath.Join(tmpDir, "en-app.yml"), []byte(yamlSource), 0644)
+
+	data, _ := json.Marshal(map[string]string{
+		"title":        "Bem-vindo",
+		"defaults.ok":  "OK",
+		"dialog.close": "Não feche",
+		"menu.open":    "Abrir: arquivo",
+		"description":  "Primeira linha\nsegunda linha\n",
+		"folded":       "Um texto longo",
+		"messages":     "{cnt, plural, one {%{count} mensagem} other {%{count} mensagens}}",
+		"days.0":       "Domingo",
+		"months.1":     "Fevereiro"})
+
+	if err := new(Yaml).Write(tmpDir, "pt_BR", "en", "app", string(data), FileLocators["LANG-NAME"]); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# Strings of the web application
+---
+pt-BR:
+  # The title of the home page
+  title: Bem-vindo
+  defaults: &defaults
+    ok: "OK"
+    cancel: Cancel
+  dialog:
+    <<: *defaults
+    close: 'Não feche'
+  menu:
+    open: "Abrir: arquivo" # the open item
+  description: |
+    Primeira linha
+    segunda linha
+  folded: >-
+    Um texto longo
+  messages:
+    one: "%{count} mensagem"
+    other: "%{count} mensagens"
+  days: [Domingo, "", ~]
+  months:
+    - ""
+    - Fevereiro
+  precision: 3
+  enabled: true
+  empty:
+# the end
+`
+	written := mustRead(filepath.Join(tmpDir, "pt_BR-app.yml"))
+	tu.AssertEquals("", expected, string(written), t)
+
+	c, err := new(Yaml).Parse(written)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEquals("round trip", "Primeira linha\nsegunda linha\n", c.Find("description").Value, t)
+}
+
+func Test_Yaml_Serialize_NoTemplate(t *testing.T) {
+	c := NewCatalog("fr")
+	c.Add(&Entry{Key: "menu.open", Value: "Ouvrir"})
+	c.Add(&Entry{Key: "menu.yes", Value: "Oui"})
+	c.Add(&Entry{Key: "files", Plurals: map[string]string{"one": "%{count} fichier", "other": "%{count} fichiers"}})
+
+	out, err := new(Yaml).Serialize(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `fr:
+  menu:
+    open: Ouvrir
+    "yes": Oui
+  files:
+    one: "%{count} fichier"
+    other: "%{count} fichiers"
+`
+	tu.AssertEquals("", expected, string(out), t)
+}
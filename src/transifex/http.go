@@ -0,0 +1,85 @@
+package transifex
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// Network settings used when creating the http client for communicating with transifex.
+// All fields are optional, an empty HTTPSettings results in a client equivalent to http.DefaultClient
+type HTTPSettings struct {
+	// url of the proxy server.  If empty the HTTP_PROXY/HTTPS_PROXY environment variables are used
+	Proxy string `json:"proxy"`
+	// path to a PEM file of CA certificates to trust in addition to the system certificates
+	CACert string `json:"caCert"`
+	// paths to the PEM encoded client certificate and key used for mutual TLS.  Both or neither must be set
+	ClientCert string `json:"clientCert"`
+	ClientKey  string `json:"clientKey"`
+}
+
+// Returns a copy of the settings where each non-empty field of override replaces the field of s
+func (s HTTPSettings) Merge(override HTTPSettings) HTTPSettings {
+	if override.Proxy != "" {
+		s.Proxy = override.Proxy
+	}
+	if override.CACert != "" {
+		s.CACert = override.CACert
+	}
+	if override.ClientCert != "" {
+		s.ClientCert = override.ClientCert
+	}
+	if override.ClientKey != "" {
+		s.ClientKey = override.ClientKey
+	}
+	return s
+}
+
+func NewHTTPClient(settings HTTPSettings) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if settings.Proxy != "" {
+		proxyUrl, err := url.Parse(settings.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid proxy url %q: %s", settings.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+
+	if settings.CACert == "" && settings.ClientCert == "" && settings.ClientKey == "" {
+		return &http.Client{Transport: transport}, nil
+	}
+
+	tlsConfig := &tls.Config{}
+	if settings.CACert != "" {
+		pem, err := ioutil.ReadFile(settings.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read CA certificates %q: %s", settings.CACert, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No PEM encoded certificates found in %q", settings.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if settings.ClientCert != "" || settings.ClientKey != "" {
+		if settings.ClientCert == "" || settings.ClientKey == "" {
+			return nil, fmt.Errorf("Both the client certificate and client key are required for client authentication")
+		}
+		cert, err := tls.LoadX509KeyPair(settings.ClientCert, settings.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to load client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
@@ -0,0 +1,107 @@
+package transifex
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type countingTransport struct {
+	requests int
+}
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.requests++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func Test_WithTransport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "[]")
+	}))
+	defer ts.Close()
+
+	transport := &countingTransport{}
+	transifexAPI := NewTransifexAPI("project", "", "", WithTransport(transport))
+	transifexAPI.ApiUrl = ts.URL
+	if _, err := transifexAPI.ListResources(); err != nil {
+		t.Error("Failed to list sources", err)
+	}
+
+	if transport.requests != 1 {
+		t.Errorf("Expected the custom transport to be used once but was used %d times", transport.requests)
+	}
+}
+
+func Test_NewHTTPClient_Proxy(t *testing.T) {
+	var proxiedUrl string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedUrl = r.URL.String()
+		fmt.Fprintln(w, "[]")
+	}))
+	defer proxy.Close()
+
+	client, err := NewHTTPClient(HTTPSettings{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transifexAPI := NewTransifexAPI("project", "", "", WithHTTPClient(client))
+	transifexAPI.ApiUrl = "http://transifex.invalid/api/2"
+	if _, err := transifexAPI.ListResources(); err != nil {
+		t.Error("Failed to list sources", err)
+	}
+
+	if proxiedUrl != "http://transifex.invalid/api/2/project/project/resources/?limit=100&offset=0" {
+		t.Errorf("Request was not sent through the proxy: %q", proxiedUrl)
+	}
+}
+
+func Test_NewHTTPClient_CACert(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "[]")
+	}))
+	defer ts.Close()
+
+	transifexAPI := NewTransifexAPI("project", "", "")
+	transifexAPI.ApiUrl = ts.URL
+	if _, err := transifexAPI.ListResources(); err == nil {
+		t.Error("Expected the default client to reject the self signed certificate")
+	}
+
+	dir, _ := ioutil.TempDir("", "cacert")
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, caData, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewHTTPClient(HTTPSettings{CACert: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transifexAPI = NewTransifexAPI("project", "", "", WithHTTPClient(client))
+	transifexAPI.ApiUrl = ts.URL
+	if _, err := transifexAPI.ListResources(); err != nil {
+		t.Error("Failed to list sources with the custom CA", err)
+	}
+}
+
+func Test_NewHTTPClient_InvalidSettings(t *testing.T) {
+	if _, err := NewHTTPClient(HTTPSettings{CACert: "/does/not/exist.pem"}); err == nil {
+		t.Error("Expected an error for a missing CA file")
+	}
+	if _, err := NewHTTPClient(HTTPSettings{ClientCert: "cert.pem"}); err == nil {
+		t.Error("Expected an error when the client key is missing")
+	}
+	if _, err := NewHTTPClient(HTTPSettings{Proxy: "://bad"}); err == nil {
+		t.Error("Expected an error for an invalid proxy url")
+	}
+}
@@ -0,0 +1,167 @@
+package transifex
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const DefaultPageSize = 100
+
+// Sets the number of resources requested per page when listing resources
+func WithPageSize(pageSize int) Option {
+	return func(t *TransifexAPI) {
+		t.PageSize = pageSize
+	}
+}
+
+// a page of resources and the url of the next page, or "" if it is the last page
+type resourcePage struct {
+	resources []Resource
+	next      string
+}
+
+// resource list response in a json:api document, paginated with a cursor in links.next
+type resourcePageJsonApi struct {
+	Data []struct {
+		Attributes struct {
+			Slug       string   `json:"slug"`
+			Name       string   `json:"name"`
+			Priority   string   `json:"priority"`
+			Categories []string `json:"categories"`
+		} `json:"attributes"`
+		Relationships struct {
+			I18nFormat struct {
+				Data struct {
+					Id string `json:"id"`
+				} `json:"data"`
+			} `json:"i18n_format"`
+		} `json:"relationships"`
+	} `json:"data"`
+	Links struct {
+		Next string `json:"next"`
+	} `json:"links"`
+}
+
+// Call fn for each resource of the project, requesting the resources one page at a time so only a single page
+// is kept in memory.  Iteration stops at the first error returned by fn, which is returned by EachResource.
+//
+// The resources are requested from the resources url of ApiUrl.  When the response is a json array pages are
+// requested with the offset and limit parameters until a page with fewer than PageSize resources is returned or the
+// server returns the previous page again because it ignores the offset.  When the response is a json:api document
+// the links.next url (a cursor) is followed until it is empty.  The next url must be on the api server since the
+// credentials are sent with the request
+func (t TransifexAPI) EachResource(fn func(Resource) error) error {
+	pageSize := t.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	offset := 0
+	var previous []string
+	for pageUrl := t.resourcesPageUrl(offset, pageSize); pageUrl != ""; {
+		page, err := t.readResourcePage(pageUrl, offset, pageSize)
+		if err != nil {
+			return err
+		}
+
+		// a server that ignores the offset parameter returns the first page again: all resources have been
+		// listed if it is the same page, a page that only starts like the previous one is an error
+		slugs := make([]string, len(page.resources))
+		for i, r := range page.resources {
+			slugs[i] = r.Slug
+		}
+		if len(slugs) > 0 && len(previous) > 0 && slugs[0] == previous[0] {
+			if strings.Join(slugs, "\x00") == strings.Join(previous, "\x00") {
+				return nil
+			}
+			return fmt.Errorf("The server returned resources for offset %d that start like the previous page.  Pagination does not appear to be supported, try a PageSize larger than the number of resources", offset)
+		}
+		previous = slugs
+
+		for _, r := range page.resources {
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+
+		offset += len(page.resources)
+		if page.next != "" {
+			if err := t.checkPageUrl(page.next); err != nil {
+				return err
+			}
+		}
+		pageUrl = page.next
+	}
+	return nil
+}
+
+// Returns an error if the url is not on the api server (the scheme and host of ApiUrl)
+func (t TransifexAPI) checkPageUrl(pageUrl string) error {
+	api, err := url.Parse(t.ApiUrl)
+	if err != nil {
+		return err
+	}
+	next, err := url.Parse(pageUrl)
+	if err != nil || !strings.EqualFold(next.Scheme, api.Scheme) || !strings.EqualFold(next.Host, api.Host) {
+		return fmt.Errorf("The next page %q is not on the api server %s", pageUrl, t.ApiUrl)
+	}
+	return nil
+}
+
+func (t TransifexAPI) resourcesPageUrl(offset, pageSize int) string {
+	params := url.Values{}
+	params.Set("offset", strconv.Itoa(offset))
+	params.Set("limit", strconv.Itoa(pageSize))
+	return t.resourcesUrl(true) + "?" + params.Encode()
+}
+
+func (t TransifexAPI) readResourcePage(pageUrl string, offset, pageSize int) (resourcePage, error) {
+	resp, err := t.execRequest("GET", pageUrl, nil)
+	if err != nil {
+		return resourcePage{}, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resourcePage{}, err
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		return decodeResourcePageJsonApi(data)
+	}
+
+	var page resourcePage
+	if err := json.Unmarshal(data, &page.resources); err != nil {
+		return resourcePage{}, err
+	}
+	// only a full page can be followed by another page.  A larger page means the server ignored
+	// the limit and returned all resources at once
+	if len(page.resources) == pageSize {
+		page.next = t.resourcesPageUrl(offset+len(page.resources), pageSize)
+	}
+	return page, nil
+}
+
+func decodeResourcePageJsonApi(data []byte) (resourcePage, error) {
+	var doc resourcePageJsonApi
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return resourcePage{}, err
+	}
+
+	page := resourcePage{next: doc.Links.Next}
+	for _, d := range doc.Data {
+		r := Resource{}
+		r.Slug = d.Attributes.Slug
+		r.Name = d.Attributes.Name
+		r.Priority = d.Attributes.Priority
+		r.Category = strings.Join(d.Attributes.Categories, " ")
+		r.I18nType = d.Relationships.I18nFormat.Data.Id
+		page.resources = append(page.resources, r)
+	}
+	return page, nil
+}
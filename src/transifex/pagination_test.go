@@ -0,0 +1,163 @@
+package transifex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	tu "testutil"
+)
+
+func resourcesServer(total int, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		page := []Resource{}
+		for i := offset; i < total && i < offset+limit; i++ {
+			page = append(page, Resource{BaseResource: BaseResource{Slug: fmt.Sprintf("resource-%d", i)}})
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+}
+
+func Test_EachResource_OffsetPagination(t *testing.T) {
+	requests := 0
+	ts := resourcesServer(250, &requests)
+	defer ts.Close()
+
+	transifexAPI := NewTransifexAPI("project", "", "", WithPageSize(100))
+	transifexAPI.ApiUrl = ts.URL
+
+	resources, err := transifexAPI.ListResources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("resources", 250, len(resources), t)
+	tu.AssertEqualsInt("requests", 3, requests, t)
+	tu.AssertEquals("last resource", "resource-249", resources[249].Slug, t)
+
+	// an exactly full last page requires one extra request that returns an empty page
+	requests = 0
+	ts2 := resourcesServer(200, &requests)
+	defer ts2.Close()
+	transifexAPI.ApiUrl = ts2.URL
+	resources, err = transifexAPI.ListResources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("resources", 200, len(resources), t)
+	tu.AssertEqualsInt("requests", 3, requests, t)
+}
+
+func Test_EachResource_StopsOnError(t *testing.T) {
+	requests := 0
+	ts := resourcesServer(250, &requests)
+	defer ts.Close()
+
+	transifexAPI := NewTransifexAPI("project", "", "", WithPageSize(10))
+	transifexAPI.ApiUrl = ts.URL
+
+	stop := errors.New("stop")
+	visited := 0
+	err := transifexAPI.EachResource(func(r Resource) error {
+		visited++
+		if visited == 15 {
+			return stop
+		}
+		return nil
+	})
+
+	if err != stop {
+		t.Errorf("Expected the callback error but got %v", err)
+	}
+	tu.AssertEqualsInt("requests", 2, requests, t)
+}
+
+func Test_EachResource_OffsetIgnored(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintln(w, `[{"slug": "a"}, {"slug": "b"}]`)
+	}))
+	defer ts.Close()
+
+	transifexAPI := NewTransifexAPI("project", "", "", WithPageSize(2))
+	transifexAPI.ApiUrl = ts.URL
+
+	// the same page again ends the listing
+	resources, err := transifexAPI.ListResources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("resources", 2, len(resources), t)
+	tu.AssertEqualsInt("requests", 2, requests, t)
+
+	ts2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("offset") == "0" {
+			fmt.Fprintln(w, `[{"slug": "a"}, {"slug": "b"}]`)
+		} else {
+			fmt.Fprintln(w, `[{"slug": "a"}, {"slug": "c"}]`)
+		}
+	}))
+	defer ts2.Close()
+	transifexAPI.ApiUrl = ts2.URL
+	if _, err := transifexAPI.ListResources(); err == nil {
+		t.Errorf("Expected an error because the pages are inconsistent")
+	}
+}
+
+func Test_EachResource_CursorPagination(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page[cursor]") {
+		case "":
+			fmt.Fprintf(w, `{
+  "data": [
+    {"attributes": {"slug": "core", "name": "Core", "priority": "normal", "categories": ["ui", "web"]},
+     "relationships": {"i18n_format": {"data": {"id": "KEYVALUEJSON", "type": "i18n_formats"}}}}
+  ],
+  "links": {"self": "%[1]s/resources", "next": "%[1]s/resources?page[cursor]=abc"}
+}`, ts.URL)
+		case "abc":
+			fmt.Fprintln(w, `{"data": [{"attributes": {"slug": "admin"}}], "links": {"next": null}}`)
+		default:
+			t.Errorf("Unexpected cursor %s", r.URL.RawQuery)
+		}
+	}))
+	defer ts.Close()
+
+	transifexAPI := NewTransifexAPI("project", "", "")
+	transifexAPI.ApiUrl = ts.URL
+
+	resources, err := transifexAPI.ListResources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.AssertEqualsInt("resources", 2, len(resources), t)
+	tu.AssertEquals("slug", "core", resources[0].Slug, t)
+	tu.AssertEquals("i18n type", "KEYVALUEJSON", resources[0].I18nType, t)
+	tu.AssertEquals("category", "ui web", resources[0].Category, t)
+	tu.AssertEquals("second page", "admin", resources[1].Slug, t)
+}
+
+func Test_EachResource_NextOnOtherHost(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("The credentials were sent to another host")
+	}))
+	defer other.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"data": [{"attributes": {"slug": "core"}}], "links": {"next": "%s/resources?page[cursor]=abc"}}`, other.URL)
+	}))
+	defer ts.Close()
+
+	transifexAPI := NewTransifexAPI("project", "user", "secret")
+	transifexAPI.ApiUrl = ts.URL
+
+	if _, err := transifexAPI.ListResources(); err == nil {
+		t.Errorf("Expected an error for a next page on another host")
+	}
+}
@@ -3,6 +3,7 @@ package transifex
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -19,6 +20,8 @@ type TransifexAPI struct {
 	ApiUrl, Project, username, password string
 	client                              *http.Client
 	Debug                               bool
+	// the number of resources requested per page when listing resources
+	PageSize int
 }
 
 type BaseResource struct {
@@ -44,25 +47,41 @@ type Language struct {
 	Reviewers    []string `json:"reviewers"`
 }
 
-func NewTransifexAPI(project, username, password string) TransifexAPI {
-	return TransifexAPI{"https://www.transifex.com/api/2", project, username, password, &http.Client{}, false}
+// An Option customizes a TransifexAPI when it is created.  See WithHTTPClient and WithTransport
+type Option func(*TransifexAPI)
+
+// Use the provided client for all requests (for example a client configured with a proxy or custom CA)
+func WithHTTPClient(client *http.Client) Option {
+	return func(t *TransifexAPI) {
+		t.client = client
+	}
 }
 
-func (t TransifexAPI) ListResources() ([]Resource, error) {
-	resp, err := t.execRequest("GET", t.resourcesUrl(true), nil)
-	if err != nil {
-		return nil, err
+// Send all requests through the provided RoundTripper (for example a recording transport in tests)
+func WithTransport(transport http.RoundTripper) Option {
+	return func(t *TransifexAPI) {
+		t.client = &http.Client{Transport: transport}
 	}
-	defer resp.Body.Close()
+}
 
-	data, readErr := ioutil.ReadAll(resp.Body)
-	if readErr != nil {
-		return nil, err
+func NewTransifexAPI(project, username, password string, options ...Option) TransifexAPI {
+	api := TransifexAPI{"https://www.transifex.com/api/2", project, username, password, &http.Client{}, false, DefaultPageSize}
+	for _, option := range options {
+		option(&api)
 	}
-	var resources []Resource
-	jsonErr := json.Unmarshal(data, &resources)
-	if jsonErr != nil {
-		return nil, jsonErr
+	return api
+}
+
+// List all resources of the project.  For projects with many resources prefer EachResource which does not
+// keep all resources in memory
+func (t TransifexAPI) ListResources() ([]Resource, error) {
+	resources := []Resource{}
+	err := t.EachResource(func(r Resource) error {
+		resources = append(resources, r)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return resources, nil
@@ -120,7 +139,7 @@ Strings deleted: %v
 func (t TransifexAPI) ValidateConfiguration() error {
 	msg := "Error occurred when checking credentials. Please check credentials and network connection"
 	if _, err := t.SourceLanguage(); err != nil {
-		return fmt.Errorf(msg)
+		return errors.New(msg)
 	}
 	return nil
 }
@@ -208,17 +227,31 @@ func (t TransifexAPI) DownloadTranslations(slug string) (map[string]string, erro
 
 	translations := make(map[string]string, len(langs))
 	for _, lang := range langs {
-		url := fmt.Sprintf("%s/project/%s/resource/%s/translation/%s", t.ApiUrl, t.Project, slug, lang)
-		data, err2 := t.getJson(url, "Error downloing translations file")
+		translation, err2 := t.DownloadTranslation(slug, lang)
 		if err2 != nil {
 			return nil, err2
 		}
 
-		translations[lang] = data.(map[string]interface{})["content"].(string)
+		translations[lang] = translation
 	}
 	return translations, nil
 }
 
+// Download the translation file content of a single language of a resource
+func (t TransifexAPI) DownloadTranslation(slug, lang string) (string, error) {
+	url := fmt.Sprintf("%s/project/%s/resource/%s/translation/%s", t.ApiUrl, t.Project, slug, lang)
+	data, err := t.getJson(url, "Error downloing translations file")
+	if err != nil {
+		return "", err
+	}
+
+	content, ok := data.(map[string]interface{})["content"].(string)
+	if !ok {
+		return "", fmt.Errorf("An error occurred while reading response. Expected a 'content' json field:\n%s", data)
+	}
+	return content, nil
+}
+
 func (t TransifexAPI) getJson(url string, errMsg string) (interface{}, error) {
 	resp, err := t.execRequest("GET", url, nil)
 	if err != nil {
@@ -249,6 +282,9 @@ func (t TransifexAPI) execRequest(method string, url string, requestData io.Read
 	}
 
 	resp, finalErr := t.client.Do(request)
+	if finalErr != nil {
+		return nil, finalErr
+	}
 
 	if t.Debug {
 		dump, _ := httputil.DumpResponse(resp, true)
@@ -260,7 +296,7 @@ func (t TransifexAPI) execRequest(method string, url string, requestData io.Read
 		return nil, fmt.Errorf("Response Code: %v\nResponse Status: %s", resp.StatusCode, resp.Status)
 	}
 
-	return resp, finalErr
+	return resp, nil
 }
 
 func (t TransifexAPI) resourcesUrl(endSlash bool) string {
@@ -288,7 +324,7 @@ func (t TransifexAPI) checkValidJsonResponse(resp *http.Response, errorMsg strin
 
 	var jsonData interface{}
 	if err := json.Unmarshal(responseData, &jsonData); err != nil {
-		return nil, fmt.Errorf(errorMsg + "\n\nError:\n" + string(responseData))
+		return nil, fmt.Errorf("%s\n\nError:\n%s", errorMsg, responseData)
 	}
 
 	if t.Debug {
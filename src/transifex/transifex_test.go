@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	tu "testutil"
 )
 
 func Test_ListResources(t *testing.T) {
@@ -41,6 +42,19 @@ func Test_ListResources(t *testing.T) {
 	t.Log("ListResources passed", resources)
 }
 
+func Test_ListResources_Replay(t *testing.T) {
+	recorder := tu.RecordOrReplay("testdata/list-resources.json", t)
+	transifexAPI := NewTransifexAPI("project", "user", "password", WithTransport(recorder))
+
+	resources, err := transifexAPI.ListResources()
+	if err != nil {
+		t.Fatal("Failed to list sources", err)
+	}
+
+	tu.AssertEqualsInt("number of resources", 2, len(resources), t)
+	tu.AssertEquals("slug", "iso19139-strings-xml", resources[1].Slug, t)
+}
+
 func Test_CreateResource(t *testing.T) {
 	var requestJson map[string]interface{}
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -64,3 +78,23 @@ func Test_CreateResource(t *testing.T) {
 		t.Error("incorrect content: " + c.(string))
 	}
 }
+
+func Test_DownloadTranslation(t *testing.T) {
+	var requestedPath string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, `{"content": "{\"key\": \"valeur\"}", "mimetype": "application/json"}`)
+	}))
+	defer ts.Close()
+
+	var transifexAPI = NewTransifexAPI("project", "", "")
+	transifexAPI.ApiUrl = ts.URL
+	content, err := transifexAPI.DownloadTranslation("core", "fr")
+	if err != nil {
+		t.Fatal("Failed to download translation", err)
+	}
+
+	tu.AssertEquals("path", "/project/project/resource/core/translation/fr", requestedPath, t)
+	tu.AssertEquals("content", `{"key": "valeur"}`, content, t)
+}
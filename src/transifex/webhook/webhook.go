@@ -0,0 +1,196 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"transifex/config"
+)
+
+const (
+	SignatureHeader   = "X-TX-Signature"
+	SignatureV2Header = "X-TX-Signature-V2"
+	UrlHeader         = "X-TX-Url"
+)
+
+// The events that trigger a download.  Other events are acknowledged and ignored
+var DownloadEvents = map[string]bool{
+	"translation_completed":         true,
+	"translation_completed_updated": true,
+	"review_completed":              true,
+	"proofread_completed":           true}
+
+// The payload sent by transifex
+type Event struct {
+	Project  string `json:"project"`
+	Resource string `json:"resource"`
+	Language string `json:"language"`
+	Event    string `json:"event"`
+	// only sent by the legacy (form encoded) webhooks
+	Percent string `json:"percent"`
+}
+
+// Function that downloads the translation of the language and writes it to the translation file
+type DownloadFunc func(file config.LocalizationFile, lang string) error
+
+// An http.Handler that verifies transifex webhook requests and downloads the translation
+// of the resource and language that the webhook reports as complete
+type Handler struct {
+	// the shared secret configured for the webhook in transifex
+	Secret string
+	// the transifex project slug.  Events of other projects are rejected
+	Project string
+	// the source language of the project.  Its events are ignored since a download would overwrite the source files
+	SourceLang string
+	Files      []config.LocalizationFile
+	Download   DownloadFunc
+}
+
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Unable to read request", http.StatusBadRequest)
+		return
+	}
+
+	if !VerifySignature(h.Secret, r, body) {
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	event, err := parseEvent(r, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !isDownloadEvent(event) {
+		log.Printf("Ignoring webhook event %q for %s/%s", event.Event, event.Resource, event.Language)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if h.Project != "" && event.Project != h.Project {
+		http.Error(w, fmt.Sprintf("Unknown project %q", event.Project), http.StatusNotFound)
+		return
+	}
+
+	if event.Language == h.SourceLang {
+		log.Printf("Ignoring webhook event %q for the source language %s of %s", event.Event, event.Language, event.Resource)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	file, found := h.findFile(event.Resource)
+	if !found {
+		http.Error(w, fmt.Sprintf("Unknown resource %q", event.Resource), http.StatusNotFound)
+		return
+	}
+
+	log.Printf("Webhook %q: downloading %s translation of %s", event.Event, event.Language, event.Resource)
+	if err = h.Download(file, event.Language); err != nil {
+		log.Printf("Failed to download %s translation of %s: %s", event.Language, event.Resource, err)
+		http.Error(w, "Failed to download translation", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h Handler) findFile(slug string) (config.LocalizationFile, bool) {
+	for _, f := range h.Files {
+		if f.Slug == slug {
+			return f, true
+		}
+	}
+	return config.LocalizationFile{}, false
+}
+
+// Check the signature of the webhook request.  The V2 signature is used if present, otherwise the legacy signature.
+//
+// V2: base64(hmac-sha256(secret, "POST\n<url>\n<date>\n<md5 hex of body>"))
+// Legacy: base64(hmac-sha1(secret, body))
+func VerifySignature(secret string, r *http.Request, body []byte) bool {
+	if signature := r.Header.Get(SignatureV2Header); signature != "" {
+		return hmac.Equal([]byte(signature), []byte(SignV2(secret, r.Method, requestUrl(r), r.Header.Get("Date"), body)))
+	}
+	if signature := r.Header.Get(SignatureHeader); signature != "" {
+		return hmac.Equal([]byte(signature), []byte(Sign(secret, body)))
+	}
+	return false
+}
+
+// Compute the legacy signature of a webhook request body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write(body)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Compute the V2 signature of a webhook request
+func SignV2(secret, method, url, date string, body []byte) string {
+	contentMd5 := md5.Sum(body)
+	message := strings.Join([]string{method, url, date, hex.EncodeToString(contentMd5[:])}, "\n")
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// transifex signs the url the webhook was configured with, which is sent in the X-TX-Url header
+// since it can differ from the url seen by the server when behind a proxy
+func requestUrl(r *http.Request) string {
+	if u := r.Header.Get(UrlHeader); u != "" {
+		return u
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+func parseEvent(r *http.Request, body []byte) (Event, error) {
+	var event Event
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(body, &event); err != nil {
+			return event, fmt.Errorf("Invalid json payload: %s", err)
+		}
+	} else {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return event, fmt.Errorf("Invalid form payload: %s", err)
+		}
+		event = Event{
+			Project:  values.Get("project"),
+			Resource: values.Get("resource"),
+			Language: values.Get("language"),
+			Event:    values.Get("event"),
+			Percent:  values.Get("percent")}
+	}
+
+	if event.Resource == "" || event.Language == "" {
+		return event, fmt.Errorf("The payload requires both a resource and a language")
+	}
+	return event, nil
+}
+
+func isDownloadEvent(event Event) bool {
+	if event.Event == "" {
+		return event.Percent == "100"
+	}
+	return DownloadEvents[event.Event]
+}
@@ -0,0 +1,118 @@
+package webhook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	tu "testutil"
+	"transifex"
+	"transifex/config"
+)
+
+const secret = "shared-secret"
+
+type downloadCall struct {
+	slug, lang string
+}
+
+func newServer(calls *[]downloadCall) *httptest.Server {
+	files := []config.LocalizationFile{
+		{BaseResource: transifex.BaseResource{Slug: "core"}},
+		{BaseResource: transifex.BaseResource{Slug: "admin"}}}
+	return httptest.NewServer(Handler{
+		Secret:     secret,
+		Project:    "project",
+		SourceLang: "en",
+		Files:      files,
+		Download: func(file config.LocalizationFile, lang string) error {
+			*calls = append(*calls, downloadCall{file.Slug, lang})
+			return nil
+		}})
+}
+
+func post(t *testing.T, serverUrl, contentType string, body []byte, sign func(*http.Request)) int {
+	req, _ := http.NewRequest("POST", serverUrl+"/hook", bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	sign(req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %s", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func Test_Handler_V2Signature(t *testing.T) {
+	var calls []downloadCall
+	ts := newServer(&calls)
+	defer ts.Close()
+
+	body := []byte(`{"project": "project", "resource": "admin", "language": "fr", "event": "translation_completed"}`)
+	status := post(t, ts.URL, "application/json", body, func(r *http.Request) {
+		date := "Mon, 19 Oct 2026 10:00:00 GMT"
+		r.Header.Set("Date", date)
+		r.Header.Set(UrlHeader, "https://example.com/hook")
+		r.Header.Set(SignatureV2Header, SignV2(secret, "POST", "https://example.com/hook", date, body))
+	})
+
+	tu.AssertEqualsInt("status", http.StatusOK, status, t)
+	if len(calls) != 1 || calls[0] != (downloadCall{"admin", "fr"}) {
+		t.Errorf("Expected a download of the fr admin translation: %v", calls)
+	}
+}
+
+func Test_Handler_LegacyFormPayload(t *testing.T) {
+	var calls []downloadCall
+	ts := newServer(&calls)
+	defer ts.Close()
+
+	body := []byte(url.Values{"project": {"project"}, "resource": {"core"}, "language": {"de"}, "percent": {"100"}}.Encode())
+	status := post(t, ts.URL, "application/x-www-form-urlencoded", body, func(r *http.Request) {
+		r.Header.Set(SignatureHeader, Sign(secret, body))
+	})
+
+	tu.AssertEqualsInt("status", http.StatusOK, status, t)
+	if len(calls) != 1 || calls[0] != (downloadCall{"core", "de"}) {
+		t.Errorf("Expected a download of the de core translation: %v", calls)
+	}
+}
+
+func Test_Handler_Rejected(t *testing.T) {
+	var calls []downloadCall
+	ts := newServer(&calls)
+	defer ts.Close()
+
+	body := []byte(`{"project": "project", "resource": "core", "language": "fr", "event": "review_completed"}`)
+	status := post(t, ts.URL, "application/json", body, func(r *http.Request) {
+		r.Header.Set(SignatureHeader, Sign("wrong-secret", body))
+	})
+	tu.AssertEqualsInt("bad signature", http.StatusUnauthorized, status, t)
+
+	status = post(t, ts.URL, "application/json", body, func(r *http.Request) {})
+	tu.AssertEqualsInt("missing signature", http.StatusUnauthorized, status, t)
+
+	unknown := []byte(`{"project": "project", "resource": "other", "language": "fr", "event": "review_completed"}`)
+	status = post(t, ts.URL, "application/json", unknown, func(r *http.Request) {
+		r.Header.Set(SignatureHeader, Sign(secret, unknown))
+	})
+	tu.AssertEqualsInt("unknown resource", http.StatusNotFound, status, t)
+
+	ignored := []byte(`{"project": "project", "resource": "core", "language": "fr", "event": "translation_fillup_completed"}`)
+	status = post(t, ts.URL, "application/json", ignored, func(r *http.Request) {
+		r.Header.Set(SignatureHeader, Sign(secret, ignored))
+	})
+	tu.AssertEqualsInt("ignored event", http.StatusOK, status, t)
+
+	// the source files are never overwritten
+	source := []byte(`{"project": "project", "resource": "core", "language": "en", "event": "translation_completed"}`)
+	status = post(t, ts.URL, "application/json", source, func(r *http.Request) {
+		r.Header.Set(SignatureHeader, Sign(secret, source))
+	})
+	tu.AssertEqualsInt("source language", http.StatusOK, status, t)
+
+	if len(calls) != 0 {
+		t.Errorf("Expected no downloads: %v", calls)
+	}
+}
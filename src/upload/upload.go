@@ -5,53 +5,73 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strings"
 	"transifex"
 	"transifex/cli"
 	"transifex/config"
 )
 
-var sourceLang string
-var rootDir string
-var transifexApi transifex.TransifexAPI
-var existingResources = make(map[string]bool)
+// the state of the upload of a single project
+type projectUpload struct {
+	slug              string
+	sourceLang        string
+	transifexApi      transifex.TransifexAPI
+	existingResources map[string]bool
+}
 
 func main() {
 	transifexCLI := cli.NewCLI()
-	transifexApi = transifex.NewTransifexAPI(transifexCLI.ProjectSlug(), transifexCLI.Username(), transifexCLI.Password())
-	rootDir = transifexCLI.RootDir()
-	transifexApi.Debug = transifexCLI.Debug()
-	var err error
+	rootDir := transifexCLI.RootDir()
 
-	if sourceLang, err = transifexApi.SourceLanguage(); err != nil {
-		log.Fatalf("\n\nError loading the transifext project data: \n%s", err)
+	conf, readConfErr := transifexCLI.ReadConfig()
+	if readConfErr != nil {
+		fmt.Println(rootDir)
+		log.Fatalf("\n\nError reading reading language files: \n\n%s", readConfErr)
 	}
 
-	files, readFilesErr := config.ReadConfig(transifexCLI.ConfigFile(), rootDir, sourceLang)
+	client, err := transifex.NewHTTPClient(conf.HTTP.Merge(transifexCLI.HTTPSettings()))
+	if err != nil {
+		log.Fatalf("\n\nError configuring the http client: \n%s", err)
+	}
 
-	if readFilesErr != nil {
-		fmt.Println(rootDir)
-		log.Fatalf("\n\nError reading reading language files: \n\n%s", readFilesErr)
+	summaries := []string{}
+	for _, project := range conf.Projects {
+		p := projectUpload{slug: project.Slug, transifexApi: transifexCLI.TransifexAPI(project, client)}
+		summaries = append(summaries, p.run(project.Files))
+	}
+
+	fmt.Printf("\nUpload Summary:\n  * %s\n", strings.Join(summaries, "\n  * "))
+}
+
+func (p *projectUpload) run(files []config.LocalizationFile) string {
+	var err error
+	if p.sourceLang, err = p.transifexApi.SourceLanguage(); err != nil {
+		log.Fatalf("\n\nError loading the transifext project data of %s: \n%s", p.slug, err)
 	}
 
-	readExistingResources()
+	p.readExistingResources()
 
-	doneChannel := make(chan string, len(files))
+	doneChannel := make(chan bool, len(files))
 	defer close(doneChannel)
 
 	for _, file := range files {
-		go upload(doneChannel, file)
+		go p.upload(doneChannel, file)
 	}
 
-	for done := 0; done < len(files); {
-		slug := <-doneChannel
-		fmt.Printf("\nFINISHED %s\n", slug)
-		done++
+	created := 0
+	for done := 0; done < len(files); done++ {
+		if <-doneChannel {
+			created++
+		}
 	}
+
+	return fmt.Sprintf("%s: %d resources created, %d resources updated", p.slug, created, len(files)-created)
 }
 
-func upload(doneChannel chan string, file config.LocalizationFile) {
-	uploadFile(&file)
-	doneChannel <- file.Slug
+func (p *projectUpload) upload(doneChannel chan bool, file config.LocalizationFile) {
+	created := p.uploadFile(&file)
+	fmt.Printf("\nFINISHED %s\n", file.Slug)
+	doneChannel <- created
 }
 
 func readBody(resp http.Response) []byte {
@@ -68,10 +88,8 @@ func loadContent(lang string, file *config.LocalizationFile) string {
 	if err != nil {
 		log.Fatalf("Unable to load file: %s", err)
 	}
-	format := file.Format
-
 	var cleanedContent []byte
-	cleanedContent, file.I18nType, err = format.Clean(content)
+	cleanedContent, file.I18nType, err = file.Clean(lang, content)
 	if err != nil {
 		log.Fatalf("Unable to clean and read content of %s.\nError:\n%v\nContent:\n%s", filename, err, string(content))
 
@@ -81,52 +99,56 @@ func loadContent(lang string, file *config.LocalizationFile) string {
 	return string(cleanedContent)
 }
 
-func uploadFile(file *config.LocalizationFile) {
+// returns true if the resource was created, false if it was updated
+func (p *projectUpload) uploadFile(file *config.LocalizationFile) bool {
 	slug := file.Slug
-	filename := file.Translations[sourceLang]
+	filename := file.Translations[p.sourceLang]
 
 	fmt.Printf("\nLoading data from translations data for %q from %s\n", file.Name, filename)
 
-	content := loadContent(sourceLang, file)
+	content := loadContent(p.sourceLang, file)
 
-	if _, has := existingResources[slug]; !has {
+	if _, has := p.existingResources[slug]; !has {
 		fmt.Printf("Creating new resource: %q (%s)\n", file.Name, slug)
 
-		req := transifex.UploadResourceRequest{file.BaseResource, string(content), "true"}
-		err := transifexApi.CreateResource(req)
+		req := transifex.UploadResourceRequest{BaseResource: file.BaseResource, Content: string(content), Accept_translations: "true"}
+		err := p.transifexApi.CreateResource(req)
 		if err != nil {
 			log.Fatalf("Error encountered sending the request to transifex: \n%s\n", err)
 		}
 
-		addTranslations(file)
+		p.addTranslations(file)
 
 		fmt.Printf("Finished Adding '%s'\n", slug)
-	} else {
-		fmt.Printf("Updating main language content of %q (%s)\n", file.Name, slug)
-		if err := transifexApi.UpdateResourceContent(slug, string(content)); err != nil {
-			log.Fatalf("Error updating content")
-		}
+		return true
+	}
 
-		fmt.Printf("Finished Updating '%s'\n", slug)
+	fmt.Printf("Updating main language content of %q (%s)\n", file.Name, slug)
+	if err := p.transifexApi.UpdateResourceContent(slug, string(content)); err != nil {
+		log.Fatalf("Error updating content")
 	}
+
+	fmt.Printf("Finished Updating '%s'\n", slug)
+	return false
 }
 
-func readExistingResources() {
-	resources, err := transifexApi.ListResources()
+func (p *projectUpload) readExistingResources() {
+	p.existingResources = make(map[string]bool)
+	err := p.transifexApi.EachResource(func(res transifex.Resource) error {
+		p.existingResources[res.Slug] = true
+		return nil
+	})
 	if err != nil {
 		log.Fatalf("Unable to load resources: %s", err)
 	}
-	for _, res := range resources {
-		existingResources[res.Slug] = true
-	}
 }
 
-func addTranslations(file *config.LocalizationFile) {
+func (p *projectUpload) addTranslations(file *config.LocalizationFile) {
 	for lang, _ := range file.Translations {
-		if lang != sourceLang {
+		if lang != p.sourceLang {
 			content := loadContent(lang, file)
 
-			transifexApi.UploadTranslationFile(file.Slug, lang, content)
+			p.transifexApi.UploadTranslationFile(file.Slug, lang, content)
 		}
 	}
 }